@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kleyson/groceries/backend/internal/api"
 	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/jobs"
+	"github.com/kleyson/groceries/backend/internal/realtime"
 	"github.com/kleyson/groceries/backend/internal/repository"
+	"github.com/kleyson/groceries/backend/internal/webhook"
 )
 
 //go:embed static/*
@@ -22,6 +28,13 @@ func main() {
 	dbPath := getEnv("DATABASE_PATH", "./data/groceries.db")
 	secureCookie := getEnv("SECURE_COOKIE", "false") == "true"
 	allowOrigins := strings.Split(getEnv("ALLOW_ORIGINS", "http://localhost:5173"), ",")
+	// At least a day so trashed items can still be restored; at most a
+	// century so the retention fits a time.Duration
+	trashRetentionDays := getEnvIntInRange("TRASH_RETENTION_DAYS", 30, 1, 36500)
+	expiryWebhookURL := getEnv("EXPIRY_WEBHOOK_URL", "")
+	expiryWebhookDays := getEnvInt("EXPIRY_WEBHOOK_DAYS", 3)
+	expiryWebhookHour := getEnvIntInRange("EXPIRY_WEBHOOK_HOUR", 8, 0, 23)
+	budgetWebhookURL := getEnv("BUDGET_WEBHOOK_URL", "")
 
 	// Initialize database
 	database, err := db.New(dbPath)
@@ -40,22 +53,68 @@ func main() {
 		log.Fatalf("Failed to seed database: %v", err)
 	}
 
+	// Point items at Other when their category went missing before deletes
+	// did that themselves
+	if repaired, err := database.RepairOrphanedCategories(); err != nil {
+		log.Fatalf("Failed to repair item categories: %v", err)
+	} else if repaired > 0 {
+		log.Printf("Moved %d items with unknown categories to Other", repaired)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(database)
 	sessionRepo := repository.NewSessionRepository(database)
 	listRepo := repository.NewListRepository(database)
+	listMemberRepo := repository.NewListMemberRepository(database)
 	itemRepo := repository.NewItemRepository(database)
 	categoryRepo := repository.NewCategoryRepository(database)
 	priceHistoryRepo := repository.NewPriceHistoryRepository(database)
+	syncRepo := repository.NewSyncRepository(database)
+	tombstoneRepo := repository.NewTombstoneRepository(database)
+	activityRepo := repository.NewActivityRepository(database)
+	templateRepo := repository.NewTemplateRepository(database)
+	searchRepo := repository.NewSearchRepository(database)
+	pantryRepo := repository.NewPantryRepository(database)
+	recipeRepo := repository.NewRecipeRepository(database)
+	mealPlanRepo := repository.NewMealPlanRepository(database)
+	reportRepo := repository.NewReportRepository(database)
+	budgetRepo := repository.NewBudgetRepository(database)
+	jobStateRepo := repository.NewJobStateRepository(database)
+
+	// Real-time event hub for list subscribers
+	hub := realtime.NewHub()
+
+	// Background jobs
+	trashRetention := time.Duration(trashRetentionDays) * 24 * time.Hour
+	go jobs.RunEvery(context.Background(), "trash purge", time.Hour, jobs.PurgeTrash(listRepo, itemRepo, trashRetention))
+	go jobs.RunEvery(context.Background(), "scheduled templates", time.Minute, jobs.RunScheduledTemplates(templateRepo, listMemberRepo, userRepo, activityRepo, hub))
+	if hook := webhook.New(expiryWebhookURL); hook != nil {
+		go jobs.RunEvery(context.Background(), "expiry reminders", time.Hour, jobs.NotifyExpiring(pantryRepo, itemRepo, jobStateRepo, hook, expiryWebhookDays, expiryWebhookHour))
+	}
+	if hook := webhook.New(budgetWebhookURL); hook != nil {
+		go jobs.RunEvery(context.Background(), "budget alerts", time.Minute, jobs.NotifyBudgets(budgetRepo, hook))
+	}
 
 	// Create router
 	router := api.NewRouter(
 		userRepo,
 		sessionRepo,
 		listRepo,
+		listMemberRepo,
 		itemRepo,
 		categoryRepo,
 		priceHistoryRepo,
+		syncRepo,
+		tombstoneRepo,
+		activityRepo,
+		templateRepo,
+		searchRepo,
+		pantryRepo,
+		recipeRepo,
+		mealPlanRepo,
+		reportRepo,
+		budgetRepo,
+		hub,
 		api.Config{
 			SecureCookie: secureCookie,
 			AllowOrigins: allowOrigins,
@@ -78,3 +137,26 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return parsed
+}
+
+// getEnvIntInRange is getEnvInt for settings that only make sense from min
+// to max; other values are logged and replaced by the default
+func getEnvIntInRange(key string, defaultValue, min, max int) int {
+	value := getEnvInt(key, defaultValue)
+	if value < min || value > max {
+		log.Printf("%s must be from %d to %d, using %d", key, min, max, defaultValue)
+		return defaultValue
+	}
+	return value
+}
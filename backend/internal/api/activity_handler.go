@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+type ActivityHandler struct {
+	activityRepo *repository.ActivityRepository
+	memberRepo   *repository.ListMemberRepository
+	syncRepo     *repository.SyncRepository
+	hub          *realtime.Hub
+}
+
+func NewActivityHandler(activityRepo *repository.ActivityRepository, memberRepo *repository.ListMemberRepository, syncRepo *repository.SyncRepository, hub *realtime.Hub) *ActivityHandler {
+	return &ActivityHandler{
+		activityRepo: activityRepo,
+		memberRepo:   memberRepo,
+		syncRepo:     syncRepo,
+		hub:          hub,
+	}
+}
+
+// GetByListID returns a page of a list's activity, newest first
+func (h *ActivityHandler) GetByListID(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleViewer); !ok {
+		return
+	}
+
+	limit := defaultActivityLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			BadRequest(w, "limit must be a positive number")
+			return
+		}
+		limit = min(parsed, maxActivityLimit)
+	}
+
+	var before int64
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 {
+			BadRequest(w, "before must be an activity ID")
+			return
+		}
+		before = parsed
+	}
+
+	activities, err := h.activityRepo.GetByListID(listID, before, limit)
+	if err != nil {
+		InternalError(w, "Failed to get activity")
+		return
+	}
+
+	page := models.ActivityPage{Activities: activities}
+	if len(activities) == limit {
+		page.NextCursor = activities[len(activities)-1].ID
+	}
+
+	JSON(w, http.StatusOK, page)
+}
+
+// Undo reverts the current user's most recent change to a list that has not
+// been undone yet, together with the rest of its group when one action
+// changed several items. It refuses with 409 when someone changed the same
+// thing afterwards, since undoing would discard their work. The undo is
+// recorded in the list's history too.
+func (h *ActivityHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	activity, err := h.activityRepo.GetLatestUndoable(listID, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNothingToUndo) {
+			NotFound(w, "Nothing to undo")
+			return
+		}
+		InternalError(w, "Failed to get activity")
+		return
+	}
+
+	var events []realtime.Event
+	err = h.syncRepo.Transaction(func(tx *repository.TxRepositories) error {
+		group, err := tx.Activities.GetUndoGroup(activity)
+		if err != nil {
+			return err
+		}
+		for i := range group {
+			changed, err := tx.Activities.HasLaterChanges(&group[i])
+			if err != nil {
+				return err
+			}
+			if changed {
+				return errChangedAgain
+			}
+		}
+
+		now := auth.GetCurrentTimestamp()
+		undone := make([]int64, len(group))
+		for i := range group {
+			event, err := revert(tx, &group[i])
+			if err != nil {
+				return err
+			}
+			if err := tx.Activities.MarkUndone(group[i].ID, now); err != nil {
+				return err
+			}
+			events = append(events, event)
+			undone[i] = group[i].ID
+		}
+		activity.UndoneAt = &now
+
+		if err := tx.Activities.Create(models.NewActivity(user, listID, models.ActivityUndone, nil, nil, undone, now)); err != nil {
+			return err
+		}
+		return tx.Lists.TouchUpdatedAt(listID, now)
+	})
+	if err != nil {
+		if errors.Is(err, errChangedAgain) {
+			Error(w, http.StatusConflict, "CONFLICT", "This was changed again after your action")
+			return
+		}
+		if errors.Is(err, repository.ErrItemNotFound) || errors.Is(err, repository.ErrListNotFound) {
+			Error(w, http.StatusConflict, "CONFLICT", "This no longer exists")
+			return
+		}
+		if errors.Is(err, errItemMoved) {
+			Error(w, http.StatusConflict, "CONFLICT", "This item has moved to another list")
+			return
+		}
+		if errors.Is(err, errUnknownActivity) {
+			BadRequest(w, "This action cannot be undone")
+			return
+		}
+		InternalError(w, "Failed to undo")
+		return
+	}
+
+	for _, event := range events {
+		h.hub.Publish(event)
+	}
+
+	JSON(w, http.StatusOK, activity)
+}
+
+var errUnknownActivity = errors.New("unknown activity action")
+
+// errChangedAgain means something an undo would revert was changed after it
+var errChangedAgain = errors.New("changed again since")
+
+// errItemMoved means the item an activity changed is now on another list,
+// where undoing from this list must not reach
+var errItemMoved = errors.New("item moved to another list")
+
+// revert applies the inverse of an activity within tx and returns the event
+// describing it. Items that have since moved to another list are left alone.
+func revert(tx *repository.TxRepositories, activity *models.Activity) (realtime.Event, error) {
+	switch activity.Action {
+	case models.ActivityItemCreated, models.ActivityItemRestored:
+		item, err := tx.Items.GetByID(*activity.ItemID)
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		if item.ListID != activity.ListID {
+			return realtime.Event{}, errItemMoved
+		}
+		if err := tx.Items.Delete(item.ID); err != nil {
+			return realtime.Event{}, err
+		}
+		return realtime.Event{Type: realtime.ItemDeleted, ListID: item.ListID, Version: item.Version, ItemID: item.ID}, nil
+
+	case models.ActivityItemDeleted:
+		trashed, err := tx.Items.GetTrashedByID(*activity.ItemID)
+		if errors.Is(err, repository.ErrItemNotFound) {
+			// Items moved to another list leave a deleted entry behind too
+			if item, getErr := tx.Items.GetByID(*activity.ItemID); getErr == nil && item.ListID != activity.ListID {
+				return realtime.Event{}, errItemMoved
+			}
+		}
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		if trashed.ListID != activity.ListID {
+			return realtime.Event{}, errItemMoved
+		}
+		item, err := tx.Items.Restore(trashed.ID)
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		return realtime.Event{Type: realtime.ItemCreated, ListID: item.ListID, Version: item.Version, Item: item}, nil
+
+	case models.ActivityItemUpdated, models.ActivityItemChecked, models.ActivityItemUnchecked:
+		var before models.Item
+		if err := json.Unmarshal(activity.Before, &before); err != nil {
+			return realtime.Event{}, err
+		}
+		if before.ID != *activity.ItemID || before.ListID != activity.ListID {
+			return realtime.Event{}, repository.ErrItemNotFound
+		}
+		current, err := tx.Items.GetByID(before.ID)
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		if current.ListID != activity.ListID {
+			return realtime.Event{}, errItemMoved
+		}
+		item, err := tx.Items.RestoreSnapshot(&before)
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		eventType := realtime.ItemUpdated
+		if activity.Action != models.ActivityItemUpdated {
+			eventType = realtime.ItemToggled
+		}
+		return realtime.Event{Type: eventType, ListID: item.ListID, Version: item.Version, Item: item}, nil
+
+	case models.ActivityItemsReordered:
+		var itemIDs []string
+		if err := json.Unmarshal(activity.Before, &itemIDs); err != nil {
+			return realtime.Event{}, err
+		}
+		if err := tx.Items.Reorder(activity.ListID, itemIDs); err != nil {
+			return realtime.Event{}, err
+		}
+		list, err := tx.Lists.GetByID(activity.ListID)
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		return realtime.Event{Type: realtime.ItemsReordered, ListID: activity.ListID, Version: list.Version, ItemIDs: itemIDs}, nil
+
+	case models.ActivityListRenamed:
+		var before listNameSnapshot
+		if err := json.Unmarshal(activity.Before, &before); err != nil {
+			return realtime.Event{}, err
+		}
+		if err := tx.Lists.Update(activity.ListID, before.Name, auth.GetCurrentTimestamp()); err != nil {
+			return realtime.Event{}, err
+		}
+		list, err := tx.Lists.GetByID(activity.ListID)
+		if err != nil {
+			return realtime.Event{}, err
+		}
+		return realtime.Event{Type: realtime.ListUpdated, ListID: list.ID, Version: list.Version, List: &list.List}, nil
+	}
+
+	return realtime.Event{}, errUnknownActivity
+}
+
+// listNameSnapshot is what list.renamed activities store before and after
+type listNameSnapshot struct {
+	Name string `json:"name"`
+}
+
+// activityGroupContextKey holds the group of the activity a request records
+const activityGroupContextKey contextKey = "activityGroup"
+
+// withActivityGroup returns r with a group for the activity it records, for
+// actions that change several items, so undo reverts them as one. A request
+// that already has a group keeps it.
+func withActivityGroup(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(activityGroupContextKey).(string); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), activityGroupContextKey, auth.GenerateID()))
+}
+
+// recordActivity adds an entry to a list's history for the request's user.
+// History is best effort and never fails the change it describes.
+func recordActivity(repo *repository.ActivityRepository, r *http.Request, listID, action string, item *models.Item, before, after interface{}) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		return
+	}
+	activity := newActivity(user, listID, action, item, before, after)
+	if groupID, ok := r.Context().Value(activityGroupContextKey).(string); ok {
+		activity.GroupID = &groupID
+	}
+	_ = repo.Create(activity)
+}
+
+// publishMerge announces the items a merge created on a list and the ones it
+// added to, and records them in the list's history to be undone together
+func publishMerge(hub *realtime.Hub, activityRepo *repository.ActivityRepository, r *http.Request, listID string, created []models.Item, merged []repository.MergedItem) {
+	r = withActivityGroup(r)
+	for i := range created {
+		item := &created[i]
+		hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: listID, Version: item.Version, Item: item})
+		recordActivity(activityRepo, r, listID, models.ActivityItemCreated, item, nil, item)
+	}
+	for i := range merged {
+		item := &merged[i].After
+		hub.Publish(realtime.Event{Type: realtime.ItemUpdated, ListID: listID, Version: item.Version, Item: item})
+		recordActivity(activityRepo, r, listID, models.ActivityItemUpdated, item, merged[i].Before, item)
+	}
+}
+
+// recordMove records items moved off fromListID as leaving that list and
+// arriving on the list they are now on. Only the arrival can be undone, which
+// trashes the items together.
+func recordMove(repo *repository.ActivityRepository, r *http.Request, fromListID string, moved []models.Item) {
+	r = withActivityGroup(r)
+	for i := range moved {
+		item := &moved[i]
+		before := *item
+		before.ListID = fromListID
+		recordActivity(repo, r, fromListID, models.ActivityItemDeleted, item, &before, nil)
+		recordActivity(repo, r, item.ListID, models.ActivityItemCreated, item, nil, item)
+	}
+}
+
+// newActivity builds an activity entry for a change made by user just now
+func newActivity(user *models.User, listID, action string, item *models.Item, before, after interface{}) *models.Activity {
+	return models.NewActivity(user, listID, action, item, before, after, auth.GetCurrentTimestamp())
+}
+
+// toggleActivity names the action for an item whose checked state became checked
+func toggleActivity(checked bool) string {
+	if checked {
+		return models.ActivityItemChecked
+	}
+	return models.ActivityItemUnchecked
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// maxBudgetAmount keeps budgets to amounts a household could spend in a month
+const maxBudgetAmount = 1000000
+
+type BudgetHandler struct {
+	budgetRepo   *repository.BudgetRepository
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewBudgetHandler(budgetRepo *repository.BudgetRepository, categoryRepo *repository.CategoryRepository) *BudgetHandler {
+	return &BudgetHandler{budgetRepo: budgetRepo, categoryRepo: categoryRepo}
+}
+
+// GetAll returns every budget with what has been spent against it this month
+func (h *BudgetHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.budgetRepo.Status(time.Now())
+	if err != nil {
+		InternalError(w, "Failed to get budgets")
+		return
+	}
+	JSON(w, http.StatusOK, statuses)
+}
+
+// Create sets a monthly budget, overall or for one category
+func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.BudgetRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if msg := validateBudgetAmount(req.Amount); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+	if req.CategoryID != nil && *req.CategoryID == "" {
+		req.CategoryID = nil
+	}
+	if req.CategoryID != nil && !checkCategory(w, h.categoryRepo, *req.CategoryID) {
+		return
+	}
+
+	budget := &models.Budget{
+		ID:         auth.GenerateID(),
+		CategoryID: req.CategoryID,
+		Amount:     req.Amount,
+	}
+	if err := h.budgetRepo.Create(budget); err != nil {
+		if errors.Is(err, repository.ErrBudgetExists) {
+			Error(w, http.StatusConflict, "CONFLICT", "A budget already exists for this category")
+			return
+		}
+		InternalError(w, "Failed to create budget")
+		return
+	}
+
+	JSON(w, http.StatusCreated, budget)
+}
+
+// Update changes a budget's amount; its category can't be changed
+func (h *BudgetHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.BudgetRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if msg := validateBudgetAmount(req.Amount); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	if err := h.budgetRepo.UpdateAmount(id, req.Amount); err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			NotFound(w, "Budget not found")
+			return
+		}
+		InternalError(w, "Failed to update budget")
+		return
+	}
+
+	budget, err := h.budgetRepo.GetByID(id)
+	if err != nil {
+		InternalError(w, "Failed to get updated budget")
+		return
+	}
+	JSON(w, http.StatusOK, budget)
+}
+
+// Delete removes a budget
+func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.budgetRepo.Delete(chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			NotFound(w, "Budget not found")
+			return
+		}
+		InternalError(w, "Failed to delete budget")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+func validateBudgetAmount(amount float64) string {
+	if amount <= 0 {
+		return "Amount must be greater than zero"
+	}
+	if amount > maxBudgetAmount {
+		return "Amount is too large"
+	}
+	return ""
+}
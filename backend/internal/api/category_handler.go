@@ -4,9 +4,11 @@ import (
 	"errors"
 	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/categorize"
 	"github.com/kleyson/groceries/backend/internal/models"
 	"github.com/kleyson/groceries/backend/internal/repository"
 )
@@ -15,10 +17,14 @@ var hexColorRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
 
 type CategoryHandler struct {
 	categoryRepo *repository.CategoryRepository
+	itemRepo     *repository.ItemRepository
 }
 
-func NewCategoryHandler(categoryRepo *repository.CategoryRepository) *CategoryHandler {
-	return &CategoryHandler{categoryRepo: categoryRepo}
+func NewCategoryHandler(categoryRepo *repository.CategoryRepository, itemRepo *repository.ItemRepository) *CategoryHandler {
+	return &CategoryHandler{
+		categoryRepo: categoryRepo,
+		itemRepo:     itemRepo,
+	}
 }
 
 // GetAll returns all categories
@@ -31,6 +37,22 @@ func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusOK, categories)
 }
 
+// Suggest returns the category an item name most likely belongs to
+func (h *CategoryHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		BadRequest(w, "name query parameter is required")
+		return
+	}
+
+	suggestion, err := categorize.Suggest(name, h.itemRepo.MostUsedCategory)
+	if err != nil {
+		InternalError(w, "Failed to suggest category")
+		return
+	}
+	JSON(w, http.StatusOK, suggestion)
+}
+
 // Create creates a new category
 func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateCategoryRequest
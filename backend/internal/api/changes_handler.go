@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// changesCursorOverlap moves the returned cursor back so that writes stamped
+// just before a read but committed after it are picked up by the next
+// request. Clients upsert by ID, so seeing a change twice is harmless.
+const changesCursorOverlap = 2000
+
+type ChangesHandler struct {
+	listRepo      *repository.ListRepository
+	itemRepo      *repository.ItemRepository
+	categoryRepo  *repository.CategoryRepository
+	tombstoneRepo *repository.TombstoneRepository
+}
+
+func NewChangesHandler(
+	listRepo *repository.ListRepository,
+	itemRepo *repository.ItemRepository,
+	categoryRepo *repository.CategoryRepository,
+	tombstoneRepo *repository.TombstoneRepository,
+) *ChangesHandler {
+	return &ChangesHandler{
+		listRepo:      listRepo,
+		itemRepo:      itemRepo,
+		categoryRepo:  categoryRepo,
+		tombstoneRepo: tombstoneRepo,
+	}
+}
+
+// GetSince returns lists, items and categories changed since a cursor, plus
+// tombstones for deletions. Without a cursor everything is returned.
+func (h *ChangesHandler) GetSince(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var since int64
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			BadRequest(w, "since must be a cursor returned by a previous request")
+			return
+		}
+		since = parsed
+	}
+
+	cursor := auth.GetCurrentTimestamp() - changesCursorOverlap
+
+	lists, err := h.listRepo.GetChangedSinceForUser(user.ID, since)
+	if err != nil {
+		InternalError(w, "Failed to get changed lists")
+		return
+	}
+
+	items, err := h.itemRepo.GetChangedSinceForUser(user.ID, since)
+	if err != nil {
+		InternalError(w, "Failed to get changed items")
+		return
+	}
+
+	categories, err := h.categoryRepo.GetChangedSince(since)
+	if err != nil {
+		InternalError(w, "Failed to get changed categories")
+		return
+	}
+
+	deleted, err := h.tombstoneRepo.GetSinceForUser(user.ID, since)
+	if err != nil {
+		InternalError(w, "Failed to get deletions")
+		return
+	}
+
+	// Never hand back a cursor that moves the client backwards
+	if cursor < since {
+		cursor = since
+	}
+
+	JSON(w, http.StatusOK, models.ChangesResponse{
+		Cursor:     cursor,
+		Lists:      lists,
+		Items:      items,
+		Categories: categories,
+		Deleted:    deleted,
+	})
+}
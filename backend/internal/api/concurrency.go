@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// expectedVersion returns the version a client based its write on, taken
+// from the If-Match header or, failing that, the request body. The boolean
+// is false when the client sent neither and the write should not be checked.
+func expectedVersion(r *http.Request, bodyVersion *int) (int, bool, error) {
+	if header := strings.TrimSpace(r.Header.Get("If-Match")); header != "" {
+		// Accept 3, "3" and W/"3" since clients echo back the ETag
+		value := strings.TrimPrefix(header, "W/")
+		value = strings.Trim(value, `"`)
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false, errInvalidIfMatch
+		}
+		return version, true, nil
+	}
+
+	if bodyVersion != nil {
+		return *bodyVersion, true, nil
+	}
+
+	return 0, false, nil
+}
+
+// setETag exposes an entity version so clients can send it back as If-Match
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
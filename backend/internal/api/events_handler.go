@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// heartbeatInterval keeps idle streams alive through proxies that close
+// silent connections. Access is checked again on every heartbeat.
+const heartbeatInterval = 25 * time.Second
+
+type EventsHandler struct {
+	sessionRepo *repository.SessionRepository
+	memberRepo  *repository.ListMemberRepository
+	hub         *realtime.Hub
+}
+
+func NewEventsHandler(sessionRepo *repository.SessionRepository, memberRepo *repository.ListMemberRepository, hub *realtime.Hub) *EventsHandler {
+	return &EventsHandler{
+		sessionRepo: sessionRepo,
+		memberRepo:  memberRepo,
+		hub:         hub,
+	}
+}
+
+// Stream pushes list change events to the client as Server-Sent Events. The
+// stream closes once the session ends or the user loses access to the list.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleViewer); !ok {
+		return
+	}
+	user := GetUserFromContext(r)
+	session := GetSessionFromContext(r)
+	if session == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		InternalError(w, "Streaming not supported")
+		return
+	}
+
+	events, unsubscribe := h.hub.Subscribe(listID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Tell the client the stream is open so it can refetch once and then
+	// rely on events
+	_, _ = fmt.Fprint(w, "event: ready\ndata: {}\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !h.stillAllowed(session.ID, listID, user.ID) {
+				return
+			}
+			_, _ = fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// stillAllowed reports whether the session is still valid and its user can
+// still view the list. Errors count as no, so the client reconnects and is
+// checked afresh.
+func (h *EventsHandler) stillAllowed(sessionID, listID, userID string) bool {
+	if _, err := h.sessionRepo.GetByID(sessionID); err != nil {
+		return false
+	}
+	role, err := h.memberRepo.GetRole(listID, userID)
+	return err == nil && role.AtLeast(models.ListRoleViewer)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+const (
+	defaultExpiringDays = 7
+	maxExpiringDays     = 365
+)
+
+type ExpiryHandler struct {
+	pantryRepo *repository.PantryRepository
+	itemRepo   *repository.ItemRepository
+}
+
+func NewExpiryHandler(pantryRepo *repository.PantryRepository, itemRepo *repository.ItemRepository) *ExpiryHandler {
+	return &ExpiryHandler{
+		pantryRepo: pantryRepo,
+		itemRepo:   itemRepo,
+	}
+}
+
+// GetExpiring returns pantry items and checked items on the user's lists that
+// expire within the next days days, including anything already past its date
+func (h *ExpiryHandler) GetExpiring(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	days := defaultExpiringDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > maxExpiringDays {
+			BadRequest(w, "days must be between 0 and 365")
+			return
+		}
+		days = parsed
+	}
+
+	report := models.ExpiringReport{
+		Until: time.Now().AddDate(0, 0, days).UnixMilli(),
+	}
+
+	var err error
+	if report.Pantry, err = h.pantryRepo.GetExpiring(report.Until); err != nil {
+		InternalError(w, "Failed to get expiring pantry items")
+		return
+	}
+	if report.Items, err = h.itemRepo.GetExpiringForUser(user.ID, report.Until); err != nil {
+		InternalError(w, "Failed to get expiring items")
+		return
+	}
+
+	JSON(w, http.StatusOK, report)
+}
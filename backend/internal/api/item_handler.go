@@ -2,23 +2,48 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/categorize"
 	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/quickadd"
+	"github.com/kleyson/groceries/backend/internal/realtime"
 	"github.com/kleyson/groceries/backend/internal/repository"
 )
 
+// maxBulkItems bounds how many item IDs one bulk request may name
+const maxBulkItems = 500
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
 type ItemHandler struct {
-	itemRepo *repository.ItemRepository
-	listRepo *repository.ListRepository
+	itemRepo     *repository.ItemRepository
+	listRepo     *repository.ListRepository
+	memberRepo   *repository.ListMemberRepository
+	categoryRepo *repository.CategoryRepository
+	pantryRepo   *repository.PantryRepository
+	activityRepo *repository.ActivityRepository
+	hub          *realtime.Hub
 }
 
-func NewItemHandler(itemRepo *repository.ItemRepository, listRepo *repository.ListRepository) *ItemHandler {
+func NewItemHandler(itemRepo *repository.ItemRepository, listRepo *repository.ListRepository, memberRepo *repository.ListMemberRepository, categoryRepo *repository.CategoryRepository, pantryRepo *repository.PantryRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) *ItemHandler {
 	return &ItemHandler{
-		itemRepo: itemRepo,
-		listRepo: listRepo,
+		itemRepo:     itemRepo,
+		listRepo:     listRepo,
+		memberRepo:   memberRepo,
+		categoryRepo: categoryRepo,
+		pantryRepo:   pantryRepo,
+		activityRepo: activityRepo,
+		hub:          hub,
 	}
 }
 
@@ -26,42 +51,83 @@ func NewItemHandler(itemRepo *repository.ItemRepository, listRepo *repository.Li
 func (h *ItemHandler) GetByListID(w http.ResponseWriter, r *http.Request) {
 	listID := chi.URLParam(r, "listId")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleViewer); !ok {
+		return
+	}
+
 	items, err := h.itemRepo.GetByListID(listID)
 	if err != nil {
 		InternalError(w, "Failed to get items")
 		return
 	}
+	for i := range items {
+		items[i].SetUnitPrice()
+	}
 
 	JSON(w, http.StatusOK, items)
 }
 
+// Suggest completes an item name from everything added to the user's lists
+// before, with the category, unit, store and price it was last added with
+func (h *ItemHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			BadRequest(w, "limit must be a positive number")
+			return
+		}
+		limit = min(parsed, maxSuggestLimit)
+	}
+
+	suggestions, err := h.itemRepo.Suggest(user.ID, r.URL.Query().Get("q"), auth.GetCurrentTimestamp(), limit)
+	if err != nil {
+		InternalError(w, "Failed to get suggestions")
+		return
+	}
+
+	JSON(w, http.StatusOK, suggestions)
+}
+
 // Create creates a new item
 func (h *ItemHandler) Create(w http.ResponseWriter, r *http.Request) {
 	listID := chi.URLParam(r, "listId")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
 	var req models.CreateItemRequest
 	if err := DecodeJSON(r, &req); err != nil {
 		BadRequest(w, "Invalid request body")
 		return
 	}
 
+	// Quick-add text fills in what the request leaves empty
+	if req.Text != "" {
+		if msg := applyQuickAdd(&req); msg != "" {
+			BadRequest(w, msg)
+			return
+		}
+	}
+
 	// Validate
-	if len(req.Name) == 0 {
-		BadRequest(w, "Name is required")
+	if msg := validateCreateItem(&req); msg != "" {
+		BadRequest(w, msg)
 		return
 	}
-	if len(req.Name) > 200 {
-		BadRequest(w, "Name must be at most 200 characters")
+	if req.CategoryID != "" && !checkCategory(w, h.categoryRepo, req.CategoryID) {
 		return
 	}
-	if req.Quantity < 1 {
-		req.Quantity = 1
-	}
-	if req.CategoryID == "" {
-		req.CategoryID = "other"
-	}
-	if req.Price != nil && *req.Price < 0 {
-		BadRequest(w, "Price must be non-negative")
+
+	if err := fillCategory(h.itemRepo, &req); err != nil {
+		InternalError(w, "Failed to suggest category")
 		return
 	}
 
@@ -73,26 +139,33 @@ func (h *ItemHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	item := &models.Item{
-		ID:         auth.GenerateID(),
-		ListID:     listID,
-		Name:       req.Name,
-		Quantity:   req.Quantity,
-		Unit:       req.Unit,
-		CategoryID: req.CategoryID,
-		Checked:    false,
-		Price:      req.Price,
-		Store:      req.Store,
-		SortOrder:  maxOrder + 1,
+		ID:          auth.GenerateID(),
+		ListID:      listID,
+		Name:        req.Name,
+		Quantity:    req.Quantity,
+		Unit:        req.Unit,
+		CategoryID:  req.CategoryID,
+		Checked:     false,
+		Price:       req.Price,
+		PackageSize: req.PackageSize,
+		PackageUnit: req.PackageUnit,
+		Store:       req.Store,
+		SortOrder:   maxOrder + 1,
 	}
 
 	if err := h.itemRepo.Create(item); err != nil {
 		InternalError(w, "Failed to create item")
 		return
 	}
+	item.SetUnitPrice()
 
 	// Update list's updatedAt
 	_ = h.listRepo.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
 
+	recordActivity(h.activityRepo, r, listID, models.ActivityItemCreated, item, nil, item)
+
+	h.hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: listID, Version: item.Version, Item: item})
+
 	JSON(w, http.StatusCreated, item)
 }
 
@@ -101,6 +174,10 @@ func (h *ItemHandler) Update(w http.ResponseWriter, r *http.Request) {
 	listID := chi.URLParam(r, "listId")
 	id := chi.URLParam(r, "id")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
 	// Get existing item
 	item, err := h.itemRepo.GetByID(id)
 	if err != nil {
@@ -124,22 +201,256 @@ func (h *ItemHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, checkVersion, err := expectedVersion(r, req.Version)
+	if err != nil {
+		BadRequest(w, "If-Match must be an item version")
+		return
+	}
+	if checkVersion && version != item.Version {
+		VersionConflict(w, "Item was modified by someone else", item)
+		return
+	}
+
 	// Apply updates
+	before := *item
+	if msg := applyItemUpdate(item, &req); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+	if req.CategoryID != nil && !checkCategory(w, h.categoryRepo, *req.CategoryID) {
+		return
+	}
+
+	if checkVersion {
+		err = h.itemRepo.UpdateWithVersion(item, version)
+	} else {
+		err = h.itemRepo.Update(item)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrItemVersionConflict) {
+			h.writeItemConflict(w, id)
+			return
+		}
+		if errors.Is(err, repository.ErrItemNotFound) {
+			NotFound(w, "Item not found")
+			return
+		}
+		InternalError(w, "Failed to update item")
+		return
+	}
+	item.SetUnitPrice()
+
+	// Update list's updatedAt
+	_ = h.listRepo.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
+
+	recordActivity(h.activityRepo, r, listID, models.ActivityItemUpdated, item, before, item)
+
+	h.hub.Publish(realtime.Event{Type: realtime.ItemUpdated, ListID: listID, Version: item.Version, Item: item})
+
+	setETag(w, item.Version)
+	JSON(w, http.StatusOK, item)
+}
+
+// checkCategory responds with 422 when the category does not exist
+func checkCategory(w http.ResponseWriter, categoryRepo *repository.CategoryRepository, categoryID string) bool {
+	exists, err := categoryRepo.Exists(categoryID)
+	if err != nil {
+		InternalError(w, "Failed to check category")
+		return false
+	}
+	if !exists {
+		InvalidReference(w, "Category not found")
+		return false
+	}
+	return true
+}
+
+// writeItemConflict responds with 409 and the item as it is now stored
+func (h *ItemHandler) writeItemConflict(w http.ResponseWriter, id string) {
+	current, err := h.itemRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			NotFound(w, "Item not found")
+			return
+		}
+		InternalError(w, "Failed to get item")
+		return
+	}
+	VersionConflict(w, "Item was modified by someone else", current)
+}
+
+// applyQuickAdd parses req.Text as a single quick-add entry and copies the
+// parsed fields into req where it has none. It returns a message describing
+// why the text cannot be used, or "" on success.
+func applyQuickAdd(req *models.CreateItemRequest) string {
+	parsed := quickadd.Parse(req.Text)
+	if len(parsed) == 0 {
+		return "Text does not name an item"
+	}
+	if len(parsed) > 1 {
+		return "Text has several items; use the parse endpoint to add them"
+	}
+
+	item := parsed[0]
+	if req.Name == "" {
+		req.Name = item.Name
+	}
+	if req.Quantity == 0 {
+		req.Quantity = item.Quantity
+	}
+	if req.Unit == nil {
+		req.Unit = item.Unit
+	}
+	if req.Price == nil {
+		req.Price = item.Price
+	}
+	if req.Store == nil {
+		req.Store = item.Store
+	}
+	req.Text = ""
+	return ""
+}
+
+// Parse turns quick-add text into item fields, one item per line. When asked
+// to, it also adds the parsed items to the list in one transaction.
+func (h *ItemHandler) Parse(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "listId")
+
+	var req models.ParseItemsRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	required := models.ListRoleViewer
+	if req.Create {
+		required = models.ListRoleEditor
+	}
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, required); !ok {
+		return
+	}
+
+	parsed := quickadd.Parse(req.Text)
+	if len(parsed) > maxBulkItems {
+		BadRequest(w, "Too many items in one request")
+		return
+	}
+	for i := range parsed {
+		if msg := validateCreateItem(&parsed[i]); msg != "" {
+			BadRequest(w, fmt.Sprintf("Line %d: %s", i+1, msg))
+			return
+		}
+		if err := fillCategory(h.itemRepo, &parsed[i]); err != nil {
+			InternalError(w, "Failed to suggest category")
+			return
+		}
+	}
+
+	response := models.ParseItemsResponse{Items: parsed}
+	if !req.Create || len(parsed) == 0 {
+		JSON(w, http.StatusOK, response)
+		return
+	}
+
+	items := make([]models.Item, len(parsed))
+	for i, p := range parsed {
+		items[i] = models.Item{
+			ID:         auth.GenerateID(),
+			Name:       p.Name,
+			Quantity:   p.Quantity,
+			Unit:       p.Unit,
+			CategoryID: p.CategoryID,
+			Price:      p.Price,
+			Store:      p.Store,
+		}
+	}
+	if err := h.itemRepo.CreateMany(listID, items); err != nil {
+		InternalError(w, "Failed to create items")
+		return
+	}
+
+	r = withActivityGroup(r)
+	for i := range items {
+		item := &items[i]
+		recordActivity(h.activityRepo, r, listID, models.ActivityItemCreated, item, nil, item)
+		h.hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: listID, Version: item.Version, Item: item})
+	}
+
+	response.Created = items
+	JSON(w, http.StatusCreated, response)
+}
+
+// fillCategory suggests a category from the item's name when the request
+// leaves it out
+func fillCategory(itemRepo *repository.ItemRepository, req *models.CreateItemRequest) error {
+	if req.CategoryID != "" {
+		return nil
+	}
+	suggestion, err := categorize.Suggest(req.Name, itemRepo.MostUsedCategory)
+	if err != nil {
+		return err
+	}
+	req.CategoryID = suggestion.CategoryID
+	return nil
+}
+
+// validateCreateItem checks a create request and fills in defaults. It
+// returns a message describing the first problem, or "" if the request is valid.
+func validateCreateItem(req *models.CreateItemRequest) string {
+	if len(req.Name) == 0 {
+		return "Name is required"
+	}
+	if len(req.Name) > 200 {
+		return "Name must be at most 200 characters"
+	}
+	if req.Quantity < 1 {
+		req.Quantity = 1
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return "Price must be non-negative"
+	}
+	if req.PackageSize == nil {
+		req.PackageUnit = nil
+		return ""
+	}
+	return normalizePackage(*req.PackageSize, &req.PackageUnit)
+}
+
+// normalizePackage checks a package size and normalizes the spelling of its
+// unit. It returns a message describing the problem, or "" if they are valid.
+func normalizePackage(size float64, unit **string) string {
+	if size <= 0 {
+		return "Package size must be positive"
+	}
+	if *unit == nil {
+		return ""
+	}
+	normalized := strings.TrimSpace(**unit)
+	if spelled := quickadd.NormalizeUnit(normalized); spelled != "" {
+		normalized = spelled
+	}
+	if len(normalized) > 50 {
+		return "Package unit must be at most 50 characters"
+	}
+	*unit = emptyToNil(normalized)
+	return ""
+}
+
+// applyItemUpdate copies the fields set in req onto item. It returns a
+// message describing the first invalid field, or "" on success.
+func applyItemUpdate(item *models.Item, req *models.UpdateItemRequest) string {
 	if req.Name != nil {
 		if len(*req.Name) == 0 {
-			BadRequest(w, "Name cannot be empty")
-			return
+			return "Name cannot be empty"
 		}
 		if len(*req.Name) > 200 {
-			BadRequest(w, "Name must be at most 200 characters")
-			return
+			return "Name must be at most 200 characters"
 		}
 		item.Name = *req.Name
 	}
 	if req.Quantity != nil {
 		if *req.Quantity < 1 {
-			BadRequest(w, "Quantity must be positive")
-			return
+			return "Quantity must be positive"
 		}
 		item.Quantity = *req.Quantity
 	}
@@ -151,24 +462,31 @@ func (h *ItemHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	if req.Price != nil {
 		if *req.Price < 0 {
-			BadRequest(w, "Price must be non-negative")
-			return
+			return "Price must be non-negative"
 		}
 		item.Price = req.Price
 	}
+	if req.PackageSize != nil {
+		if *req.PackageSize == 0 {
+			item.PackageSize = nil
+			item.PackageUnit = nil
+		} else {
+			if msg := normalizePackage(*req.PackageSize, &req.PackageUnit); msg != "" {
+				return msg
+			}
+			item.PackageSize = req.PackageSize
+			item.PackageUnit = req.PackageUnit
+		}
+	} else if req.PackageUnit != nil && item.PackageSize != nil {
+		if msg := normalizePackage(*item.PackageSize, &req.PackageUnit); msg != "" {
+			return msg
+		}
+		item.PackageUnit = req.PackageUnit
+	}
 	if req.Store != nil {
 		item.Store = req.Store
 	}
-
-	if err := h.itemRepo.Update(item); err != nil {
-		InternalError(w, "Failed to update item")
-		return
-	}
-
-	// Update list's updatedAt
-	_ = h.listRepo.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
-
-	JSON(w, http.StatusOK, item)
+	return ""
 }
 
 // ToggleChecked toggles an item's checked state
@@ -176,6 +494,10 @@ func (h *ItemHandler) ToggleChecked(w http.ResponseWriter, r *http.Request) {
 	listID := chi.URLParam(r, "listId")
 	id := chi.URLParam(r, "id")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
 	// Get current user from context
 	user := GetUserFromContext(r)
 	if user == nil {
@@ -199,21 +521,41 @@ func (h *ItemHandler) ToggleChecked(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Toggle with user info
-	updatedItem, err := h.itemRepo.ToggleChecked(id, user.ID, user.Name)
+	// The body is optional
+	var req models.ToggleItemRequest
+	if err := DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Toggle with user info, stocking the pantry when asked to
+	var updatedItem *models.Item
+	if req.AddToPantry && !item.Checked {
+		updatedItem, _, err = h.pantryRepo.CheckIn(id, user.ID, user.Name, req.Location, req.ExpiresAt)
+	} else {
+		updatedItem, err = h.itemRepo.ToggleChecked(id, user.ID, user.Name, req.ExpiresAt)
+	}
 	if err != nil {
 		InternalError(w, "Failed to toggle item")
 		return
 	}
 
+	recordActivity(h.activityRepo, r, listID, toggleActivity(updatedItem.Checked), updatedItem, item, updatedItem)
+
+	h.hub.Publish(realtime.Event{Type: realtime.ItemToggled, ListID: listID, Version: updatedItem.Version, Item: updatedItem})
+
 	JSON(w, http.StatusOK, updatedItem)
 }
 
-// Delete deletes an item
+// Delete moves an item to the trash
 func (h *ItemHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	listID := chi.URLParam(r, "listId")
 	id := chi.URLParam(r, "id")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
 	// Verify item exists and belongs to list
 	item, err := h.itemRepo.GetByID(id)
 	if err != nil {
@@ -238,6 +580,10 @@ func (h *ItemHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Update list's updatedAt
 	_ = h.listRepo.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
 
+	recordActivity(h.activityRepo, r, listID, models.ActivityItemDeleted, item, item, nil)
+
+	h.hub.Publish(realtime.Event{Type: realtime.ItemDeleted, ListID: listID, Version: item.Version, ItemID: id})
+
 	JSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
@@ -245,6 +591,10 @@ func (h *ItemHandler) Delete(w http.ResponseWriter, r *http.Request) {
 func (h *ItemHandler) Reorder(w http.ResponseWriter, r *http.Request) {
 	listID := chi.URLParam(r, "listId")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
 	var req models.ReorderItemsRequest
 	if err := DecodeJSON(r, &req); err != nil {
 		BadRequest(w, "Invalid request body")
@@ -256,7 +606,14 @@ func (h *ItemHandler) Reorder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.itemRepo.Reorder(req.ItemIDs); err != nil {
+	// Remember the previous order so the reorder can be undone
+	previous, err := h.itemRepo.GetByListID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get items")
+		return
+	}
+
+	if err := h.itemRepo.Reorder(listID, req.ItemIDs); err != nil {
 		InternalError(w, "Failed to reorder items")
 		return
 	}
@@ -264,5 +621,143 @@ func (h *ItemHandler) Reorder(w http.ResponseWriter, r *http.Request) {
 	// Update list's updatedAt
 	_ = h.listRepo.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
 
+	recordActivity(h.activityRepo, r, listID, models.ActivityItemsReordered, nil, itemIDs(previous), req.ItemIDs)
+
+	// Reorder events carry the list version since no single item describes them
+	if list, err := h.listRepo.GetByID(listID); err == nil {
+		h.hub.Publish(realtime.Event{Type: realtime.ItemsReordered, ListID: listID, Version: list.Version, ItemIDs: req.ItemIDs})
+	}
+
 	JSON(w, http.StatusOK, map[string]bool{"success": true})
 }
+
+// Bulk applies one action to many items of a list in a single transaction
+// that bumps the list version once
+func (h *ItemHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "listId")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor); !ok {
+		return
+	}
+
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.BulkItemsRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Validate
+	switch req.Filter {
+	case "", models.ItemFilterAll, models.ItemFilterChecked, models.ItemFilterUnchecked:
+	default:
+		BadRequest(w, "Filter must be all, checked or unchecked")
+		return
+	}
+	if len(req.ItemIDs) == 0 && req.Filter == "" {
+		BadRequest(w, "Item IDs or a filter are required")
+		return
+	}
+	if len(req.ItemIDs) > maxBulkItems {
+		BadRequest(w, "Too many item IDs in one request")
+		return
+	}
+
+	// Remember the items as they were for the activity history
+	previous, err := h.itemRepo.GetByListID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get items")
+		return
+	}
+
+	var changed []models.Item
+	switch req.Action {
+	case models.BulkActionCheck, models.BulkActionUncheck:
+		changed, err = h.itemRepo.BulkSetChecked(listID, req.ItemSelection, req.Action == models.BulkActionCheck, user.ID, user.Name)
+	case models.BulkActionDelete:
+		changed, err = h.itemRepo.BulkDelete(listID, req.ItemSelection)
+	case models.BulkActionMove:
+		if req.ListID == "" {
+			BadRequest(w, "List ID is required to move items")
+			return
+		}
+		if req.ListID == listID {
+			BadRequest(w, "Items are already in this list")
+			return
+		}
+		if _, ok := authorizeList(w, r, h.memberRepo, req.ListID, models.ListRoleEditor); !ok {
+			return
+		}
+		changed, err = h.itemRepo.BulkMove(listID, req.ItemSelection, req.ListID)
+	case models.BulkActionSetCategory:
+		if req.CategoryID == "" {
+			BadRequest(w, "Category ID is required")
+			return
+		}
+		changed, err = h.itemRepo.BulkSetCategory(listID, req.ItemSelection, req.CategoryID)
+	default:
+		BadRequest(w, "Action must be check, uncheck, delete, move or set_category")
+		return
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		if errors.Is(err, repository.ErrCategoryNotFound) {
+			InvalidReference(w, "Category not found")
+			return
+		}
+		InternalError(w, "Failed to update items")
+		return
+	}
+
+	// The whole call is undone as one
+	r = withActivityGroup(r)
+	before := make(map[string]models.Item, len(previous))
+	for _, item := range previous {
+		before[item.ID] = item
+	}
+	for i := range changed {
+		item := &changed[i]
+		old := before[item.ID]
+		switch req.Action {
+		case models.BulkActionCheck, models.BulkActionUncheck:
+			recordActivity(h.activityRepo, r, listID, toggleActivity(item.Checked), item, old, item)
+			h.hub.Publish(realtime.Event{Type: realtime.ItemToggled, ListID: listID, Version: item.Version, Item: item})
+		case models.BulkActionDelete:
+			recordActivity(h.activityRepo, r, listID, models.ActivityItemDeleted, item, old, nil)
+			h.hub.Publish(realtime.Event{Type: realtime.ItemDeleted, ListID: listID, Version: item.Version, ItemID: item.ID})
+		case models.BulkActionMove:
+			recordActivity(h.activityRepo, r, listID, models.ActivityItemDeleted, item, old, nil)
+			recordActivity(h.activityRepo, r, item.ListID, models.ActivityItemCreated, item, nil, item)
+			h.hub.Publish(realtime.Event{Type: realtime.ItemDeleted, ListID: listID, Version: item.Version, ItemID: item.ID})
+			h.hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: item.ListID, Version: item.Version, Item: item})
+		case models.BulkActionSetCategory:
+			recordActivity(h.activityRepo, r, listID, models.ActivityItemUpdated, item, old, item)
+			h.hub.Publish(realtime.Event{Type: realtime.ItemUpdated, ListID: listID, Version: item.Version, Item: item})
+		}
+	}
+
+	list, err := h.listRepo.GetByID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get list")
+		return
+	}
+
+	JSON(w, http.StatusOK, models.BulkItemsResponse{Items: changed, Version: list.Version})
+}
+
+// itemIDs returns the IDs of items in order
+func itemIDs(items []models.Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// authorizeList checks that the current user holds at least the required
+// role on a list, writing the error response and returning false otherwise.
+// Users without any access get a 404 so private lists are not revealed.
+func authorizeList(w http.ResponseWriter, r *http.Request, memberRepo *repository.ListMemberRepository, listID string, required models.ListRole) (models.ListRole, bool) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return "", false
+	}
+
+	role, err := memberRepo.GetRole(listID, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) || errors.Is(err, repository.ErrMemberNotFound) {
+			NotFound(w, "List not found")
+			return "", false
+		}
+		InternalError(w, "Failed to check list access")
+		return "", false
+	}
+
+	if !role.AtLeast(required) {
+		Forbidden(w, "You need "+string(required)+" access to this list")
+		return "", false
+	}
+
+	return role, true
+}
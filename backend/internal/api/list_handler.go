@@ -2,29 +2,67 @@ package api
 
 import (
 	"errors"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
 	"github.com/kleyson/groceries/backend/internal/repository"
 )
 
 type ListHandler struct {
-	listRepo *repository.ListRepository
+	listRepo     *repository.ListRepository
+	itemRepo     *repository.ItemRepository
+	memberRepo   *repository.ListMemberRepository
+	activityRepo *repository.ActivityRepository
+	budgetRepo   *repository.BudgetRepository
+	hub          *realtime.Hub
 }
 
-func NewListHandler(listRepo *repository.ListRepository) *ListHandler {
-	return &ListHandler{listRepo: listRepo}
+func NewListHandler(listRepo *repository.ListRepository, itemRepo *repository.ItemRepository, memberRepo *repository.ListMemberRepository, activityRepo *repository.ActivityRepository, budgetRepo *repository.BudgetRepository, hub *realtime.Hub) *ListHandler {
+	return &ListHandler{
+		listRepo:     listRepo,
+		itemRepo:     itemRepo,
+		memberRepo:   memberRepo,
+		activityRepo: activityRepo,
+		budgetRepo:   budgetRepo,
+		hub:          hub,
+	}
+}
+
+// budgets returns how the household budgets stand this month, to send
+// along with lists. Budgets are extra: when they can't be read the error is
+// logged and lists go out without them.
+func (h *ListHandler) budgets() []models.BudgetStatus {
+	statuses, err := h.budgetRepo.Status(time.Now())
+	if err != nil {
+		log.Printf("Failed to get budgets: %v", err)
+		return nil
+	}
+	return statuses
 }
 
-// GetAll returns all lists
+// GetAll returns the lists visible to the current user
 func (h *ListHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	lists, err := h.listRepo.GetAll()
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	lists, err := h.listRepo.GetAllForUser(user.ID)
 	if err != nil {
 		InternalError(w, "Failed to get lists")
 		return
 	}
+	budgets := h.budgets()
+	for i := range lists {
+		lists[i].Budgets = budgets
+	}
 	JSON(w, http.StatusOK, lists)
 }
 
@@ -32,6 +70,11 @@ func (h *ListHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 func (h *ListHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	role, ok := authorizeList(w, r, h.memberRepo, id, models.ListRoleViewer)
+	if !ok {
+		return
+	}
+
 	list, err := h.listRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, repository.ErrListNotFound) {
@@ -41,12 +84,21 @@ func (h *ListHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		InternalError(w, "Failed to get list")
 		return
 	}
+	list.Role = role
+	list.Budgets = h.budgets()
 
+	setETag(w, list.Version)
 	JSON(w, http.StatusOK, list)
 }
 
-// Create creates a new list
+// Create creates a new list owned by the current user
 func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
 	var req models.CreateListRequest
 	if err := DecodeJSON(r, &req); err != nil {
 		BadRequest(w, "Invalid request body")
@@ -54,12 +106,8 @@ func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate
-	if len(req.Name) == 0 {
-		BadRequest(w, "Name is required")
-		return
-	}
-	if len(req.Name) > 100 {
-		BadRequest(w, "Name must be at most 100 characters")
+	if msg := validateListName(req.Name); msg != "" {
+		BadRequest(w, msg)
 		return
 	}
 
@@ -67,6 +115,7 @@ func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
 	list := &models.List{
 		ID:        auth.GenerateID(),
 		Name:      req.Name,
+		OwnerID:   &user.ID,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -82,7 +131,9 @@ func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
 		TotalItems:   0,
 		CheckedItems: 0,
 		TotalPrice:   0,
+		Role:         models.ListRoleOwner,
 	}
+	result.Budgets = h.budgets()
 
 	JSON(w, http.StatusCreated, result)
 }
@@ -91,6 +142,11 @@ func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *ListHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	role, ok := authorizeList(w, r, h.memberRepo, id, models.ListRoleEditor)
+	if !ok {
+		return
+	}
+
 	var req models.UpdateListRequest
 	if err := DecodeJSON(r, &req); err != nil {
 		BadRequest(w, "Invalid request body")
@@ -98,16 +154,44 @@ func (h *ListHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate
-	if len(req.Name) == 0 {
-		BadRequest(w, "Name is required")
+	if msg := validateListName(req.Name); msg != "" {
+		BadRequest(w, msg)
 		return
 	}
-	if len(req.Name) > 100 {
-		BadRequest(w, "Name must be at most 100 characters")
+
+	version, checkVersion, err := expectedVersion(r, req.Version)
+	if err != nil {
+		BadRequest(w, "If-Match must be a list version")
 		return
 	}
 
-	if err := h.listRepo.Update(id, req.Name, auth.GetCurrentTimestamp()); err != nil {
+	previous, err := h.listRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to get list")
+		return
+	}
+
+	now := auth.GetCurrentTimestamp()
+	if checkVersion {
+		err = h.listRepo.UpdateWithVersion(id, req.Name, version, now)
+	} else {
+		err = h.listRepo.Update(id, req.Name, now)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			current, getErr := h.listRepo.GetByID(id)
+			if getErr != nil {
+				InternalError(w, "Failed to get list")
+				return
+			}
+			current.Role = role
+			VersionConflict(w, "List was modified by someone else", current)
+			return
+		}
 		if errors.Is(err, repository.ErrListNotFound) {
 			NotFound(w, "List not found")
 			return
@@ -122,14 +206,27 @@ func (h *ListHandler) Update(w http.ResponseWriter, r *http.Request) {
 		InternalError(w, "Failed to get updated list")
 		return
 	}
+	list.Role = role
+	list.Budgets = h.budgets()
 
+	if previous.Name != list.Name {
+		recordActivity(h.activityRepo, r, id, models.ActivityListRenamed, nil, listNameSnapshot{Name: previous.Name}, listNameSnapshot{Name: list.Name})
+	}
+
+	h.hub.Publish(realtime.Event{Type: realtime.ListUpdated, ListID: id, Version: list.Version, List: &list.List})
+
+	setETag(w, list.Version)
 	JSON(w, http.StatusOK, list)
 }
 
-// Delete deletes a list
+// Delete moves a list to the trash
 func (h *ListHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	if _, ok := authorizeList(w, r, h.memberRepo, id, models.ListRoleOwner); !ok {
+		return
+	}
+
 	if err := h.listRepo.Delete(id); err != nil {
 		if errors.Is(err, repository.ErrListNotFound) {
 			NotFound(w, "List not found")
@@ -139,5 +236,178 @@ func (h *ListHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.hub.Publish(realtime.Event{Type: realtime.ListDeleted, ListID: id})
+
 	JSON(w, http.StatusOK, map[string]bool{"success": true})
 }
+
+// Duplicate copies a list and its items into a new list owned by the
+// current user. The copies start unchecked.
+func (h *ListHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	if _, ok := authorizeList(w, r, h.memberRepo, id, models.ListRoleViewer); !ok {
+		return
+	}
+
+	// An empty body duplicates under the source list's name
+	var req models.DuplicateListRequest
+	if err := DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	list, ok := h.newListFrom(w, id, req.Name, user)
+	if !ok {
+		return
+	}
+
+	copies, err := h.listRepo.Duplicate(id, list)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to duplicate list")
+		return
+	}
+	r = withActivityGroup(r)
+	for i := range copies {
+		recordActivity(h.activityRepo, r, list.ID, models.ActivityItemCreated, &copies[i], nil, &copies[i])
+	}
+
+	result, err := h.listRepo.GetByID(list.ID)
+	if err != nil {
+		InternalError(w, "Failed to get created list")
+		return
+	}
+	result.Role = models.ListRoleOwner
+
+	JSON(w, http.StatusCreated, result)
+}
+
+// CarryOver moves a list's unchecked items into an existing list, or into a
+// new list owned by the current user
+func (h *ListHandler) CarryOver(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	if _, ok := authorizeList(w, r, h.memberRepo, id, models.ListRoleEditor); !ok {
+		return
+	}
+
+	// An empty body carries over into a new list named after the source
+	var req models.CarryOverRequest
+	if err := DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	var targetID string
+	var role models.ListRole
+	var moved []models.Item
+	var err error
+	status := http.StatusOK
+
+	if req.ListID != nil {
+		if *req.ListID == id {
+			BadRequest(w, "Cannot carry items over into the same list")
+			return
+		}
+		var ok bool
+		role, ok = authorizeList(w, r, h.memberRepo, *req.ListID, models.ListRoleEditor)
+		if !ok {
+			return
+		}
+		targetID = *req.ListID
+		moved, err = h.itemRepo.MoveUnchecked(id, targetID)
+	} else {
+		list, ok := h.newListFrom(w, id, req.Name, user)
+		if !ok {
+			return
+		}
+		targetID = list.ID
+		role = models.ListRoleOwner
+		status = http.StatusCreated
+		moved, err = h.listRepo.CarryOverToNew(id, list)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to carry over items")
+		return
+	}
+
+	target, err := h.listRepo.GetByID(targetID)
+	if err != nil {
+		InternalError(w, "Failed to get list")
+		return
+	}
+	target.Role = role
+
+	for i := range moved {
+		h.hub.Publish(realtime.Event{Type: realtime.ItemDeleted, ListID: id, Version: moved[i].Version, ItemID: moved[i].ID})
+		h.hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: targetID, Version: moved[i].Version, Item: &moved[i]})
+	}
+	recordMove(h.activityRepo, r, id, moved)
+
+	if moved == nil {
+		moved = []models.Item{}
+	}
+	JSON(w, status, models.CarryOverResponse{List: *target, Items: moved})
+}
+
+// newListFrom builds a new list for the user named name, or after the source
+// list when name is empty
+func (h *ListHandler) newListFrom(w http.ResponseWriter, sourceID, name string, user *models.User) (*models.List, bool) {
+	if name == "" {
+		source, err := h.listRepo.GetByID(sourceID)
+		if err != nil {
+			if errors.Is(err, repository.ErrListNotFound) {
+				NotFound(w, "List not found")
+				return nil, false
+			}
+			InternalError(w, "Failed to get list")
+			return nil, false
+		}
+		name = source.Name
+	}
+	if msg := validateListName(name); msg != "" {
+		BadRequest(w, msg)
+		return nil, false
+	}
+
+	now := auth.GetCurrentTimestamp()
+	return &models.List{
+		ID:        auth.GenerateID(),
+		Name:      name,
+		OwnerID:   &user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, true
+}
+
+// validateListName returns a message describing why a list name is invalid,
+// or "" if it is acceptable
+func validateListName(name string) string {
+	if len(name) == 0 {
+		return "Name is required"
+	}
+	if len(name) > 100 {
+		return "Name must be at most 100 characters"
+	}
+	return ""
+}
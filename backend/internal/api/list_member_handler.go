@@ -0,0 +1,187 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+type ListMemberHandler struct {
+	listRepo   *repository.ListRepository
+	memberRepo *repository.ListMemberRepository
+	userRepo   *repository.UserRepository
+}
+
+func NewListMemberHandler(listRepo *repository.ListRepository, memberRepo *repository.ListMemberRepository, userRepo *repository.UserRepository) *ListMemberHandler {
+	return &ListMemberHandler{
+		listRepo:   listRepo,
+		memberRepo: memberRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// GetAll returns the members of a list
+func (h *ListMemberHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleViewer); !ok {
+		return
+	}
+
+	members, err := h.memberRepo.GetByListID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get members")
+		return
+	}
+
+	JSON(w, http.StatusOK, members)
+}
+
+// Create shares a list with another user
+func (h *ListMemberHandler) Create(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleOwner); !ok {
+		return
+	}
+
+	var req models.AddListMemberRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Validate
+	if req.Username == "" {
+		BadRequest(w, "Username is required")
+		return
+	}
+	if !req.Role.Valid() {
+		BadRequest(w, "Role must be viewer, editor or owner")
+		return
+	}
+
+	user, err := h.userRepo.GetByUsername(req.Username)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			NotFound(w, "User not found")
+			return
+		}
+		InternalError(w, "Failed to find user")
+		return
+	}
+
+	member := &models.ListMember{
+		ID:        auth.GenerateID(),
+		ListID:    listID,
+		UserID:    user.ID,
+		Role:      req.Role,
+		CreatedAt: auth.GetCurrentTimestamp(),
+	}
+
+	if err := h.memberRepo.Create(member); err != nil {
+		if errors.Is(err, repository.ErrAlreadyMember) {
+			BadRequest(w, "User is already a member of this list")
+			return
+		}
+		InternalError(w, "Failed to add member")
+		return
+	}
+
+	JSON(w, http.StatusCreated, models.ListMemberWithUser{
+		ListMember: *member,
+		Username:   user.Username,
+		Name:       user.Name,
+	})
+}
+
+// Update changes a member's role
+func (h *ListMemberHandler) Update(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+	userID := chi.URLParam(r, "userId")
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleOwner); !ok {
+		return
+	}
+
+	var req models.UpdateListMemberRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if !req.Role.Valid() {
+		BadRequest(w, "Role must be viewer, editor or owner")
+		return
+	}
+
+	if !h.checkNotCreator(w, listID, userID, "Cannot change the role of the list's creator") {
+		return
+	}
+
+	if err := h.memberRepo.UpdateRole(listID, userID, req.Role); err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			NotFound(w, "Member not found")
+			return
+		}
+		InternalError(w, "Failed to update member")
+		return
+	}
+
+	member, err := h.memberRepo.Get(listID, userID)
+	if err != nil {
+		InternalError(w, "Failed to get updated member")
+		return
+	}
+
+	JSON(w, http.StatusOK, member)
+}
+
+// Delete removes a member from a list. Members may always remove themselves.
+func (h *ListMemberHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "id")
+	userID := chi.URLParam(r, "userId")
+
+	required := models.ListRoleOwner
+	if user := GetUserFromContext(r); user != nil && user.ID == userID {
+		required = models.ListRoleViewer
+	}
+
+	if _, ok := authorizeList(w, r, h.memberRepo, listID, required); !ok {
+		return
+	}
+
+	if !h.checkNotCreator(w, listID, userID, "Cannot remove the list's creator") {
+		return
+	}
+
+	if err := h.memberRepo.Delete(listID, userID); err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			NotFound(w, "Member not found")
+			return
+		}
+		InternalError(w, "Failed to remove member")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// checkNotCreator rejects changes to the membership of the user who owns the
+// list, so a list can never be left without an owner
+func (h *ListMemberHandler) checkNotCreator(w http.ResponseWriter, listID, userID, message string) bool {
+	list, err := h.listRepo.GetByID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get list")
+		return false
+	}
+	if list.OwnerID != nil && *list.OwnerID == userID {
+		Forbidden(w, message)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+const (
+	mealPlanDateLayout = "2006-01-02"
+	maxMealPlanDays    = 92
+)
+
+var mealSlots = map[string]bool{
+	models.MealSlotBreakfast: true,
+	models.MealSlotLunch:     true,
+	models.MealSlotDinner:    true,
+	models.MealSlotSnack:     true,
+}
+
+type MealPlanHandler struct {
+	mealPlanRepo *repository.MealPlanRepository
+	recipeRepo   *repository.RecipeRepository
+	listRepo     *repository.ListRepository
+	memberRepo   *repository.ListMemberRepository
+	activityRepo *repository.ActivityRepository
+	hub          *realtime.Hub
+}
+
+func NewMealPlanHandler(mealPlanRepo *repository.MealPlanRepository, recipeRepo *repository.RecipeRepository, listRepo *repository.ListRepository, memberRepo *repository.ListMemberRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) *MealPlanHandler {
+	return &MealPlanHandler{
+		mealPlanRepo: mealPlanRepo,
+		recipeRepo:   recipeRepo,
+		listRepo:     listRepo,
+		memberRepo:   memberRepo,
+		activityRepo: activityRepo,
+		hub:          hub,
+	}
+}
+
+// GetRange returns the meals planned from from to to, inclusive. Without
+// dates it returns the coming week.
+func (h *MealPlanHandler) GetRange(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" {
+		from = time.Now().Format(mealPlanDateLayout)
+	}
+	if to == "" {
+		start, err := time.Parse(mealPlanDateLayout, from)
+		if err != nil {
+			BadRequest(w, "from must be a date like 2024-01-31")
+			return
+		}
+		to = start.AddDate(0, 0, 6).Format(mealPlanDateLayout)
+	}
+	if msg := validateMealPlanRange(from, to); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	entries, err := h.mealPlanRepo.GetRange(from, to)
+	if err != nil {
+		InternalError(w, "Failed to get meal plan")
+		return
+	}
+	JSON(w, http.StatusOK, entries)
+}
+
+// Create plans a meal
+func (h *MealPlanHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.MealPlanEntryRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	entry := &models.MealPlanEntry{ID: auth.GenerateID(), CreatedBy: &user.ID}
+	if !h.apply(w, entry, &req) {
+		return
+	}
+
+	if err := h.mealPlanRepo.Create(entry); err != nil {
+		InternalError(w, "Failed to plan meal")
+		return
+	}
+
+	h.respond(w, http.StatusCreated, entry.ID)
+}
+
+// Update replaces a planned meal
+func (h *MealPlanHandler) Update(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.mealPlanRepo.GetByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrMealPlanEntryNotFound) {
+			NotFound(w, "Meal plan entry not found")
+			return
+		}
+		InternalError(w, "Failed to get meal plan entry")
+		return
+	}
+
+	var req models.MealPlanEntryRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if !h.apply(w, entry, &req) {
+		return
+	}
+
+	if err := h.mealPlanRepo.Update(entry); err != nil {
+		if errors.Is(err, repository.ErrMealPlanEntryNotFound) {
+			NotFound(w, "Meal plan entry not found")
+			return
+		}
+		InternalError(w, "Failed to update meal plan entry")
+		return
+	}
+
+	h.respond(w, http.StatusOK, entry.ID)
+}
+
+// Delete removes a planned meal
+func (h *MealPlanHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.mealPlanRepo.Delete(chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, repository.ErrMealPlanEntryNotFound) {
+			NotFound(w, "Meal plan entry not found")
+			return
+		}
+		InternalError(w, "Failed to delete meal plan entry")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// GenerateList turns the recipes planned for a date range into shopping:
+// ingredients are added up, pantry stock is subtracted and the rest goes onto
+// a new list, or is merged into an existing one, grouped by category
+func (h *MealPlanHandler) GenerateList(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.GenerateMealPlanListRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if msg := validateMealPlanRange(req.From, req.To); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	if req.ListID != nil {
+		h.generateInto(w, r, &req)
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("Meals %s to %s", req.From, req.To)
+	}
+	if msg := validateListName(req.Name); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	now := auth.GetCurrentTimestamp()
+	list := &models.List{
+		ID:        auth.GenerateID(),
+		Name:      req.Name,
+		OwnerID:   &user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	created, err := h.mealPlanRepo.GenerateNew(req.From, req.To, !req.IgnorePantry, list)
+	if err != nil {
+		InternalError(w, "Failed to create list from meal plan")
+		return
+	}
+	publishMerge(h.hub, h.activityRepo, r, list.ID, created, nil)
+
+	result, err := h.listRepo.GetByID(list.ID)
+	if err != nil {
+		InternalError(w, "Failed to get created list")
+		return
+	}
+	result.Role = models.ListRoleOwner
+
+	JSON(w, http.StatusCreated, result)
+}
+
+func (h *MealPlanHandler) generateInto(w http.ResponseWriter, r *http.Request, req *models.GenerateMealPlanListRequest) {
+	listID := *req.ListID
+	role, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor)
+	if !ok {
+		return
+	}
+
+	created, merged, err := h.mealPlanRepo.GenerateInto(req.From, req.To, !req.IgnorePantry, listID)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to add meal plan to list")
+		return
+	}
+
+	publishMerge(h.hub, h.activityRepo, r, listID, created, merged)
+
+	list, err := h.listRepo.GetByID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get updated list")
+		return
+	}
+	list.Role = role
+
+	JSON(w, http.StatusOK, list)
+}
+
+// apply validates req and copies it onto entry, writing the error response
+// and returning false when something is wrong
+func (h *MealPlanHandler) apply(w http.ResponseWriter, entry *models.MealPlanEntry, req *models.MealPlanEntryRequest) bool {
+	if _, err := time.Parse(mealPlanDateLayout, req.Date); err != nil {
+		BadRequest(w, "date must be a date like 2024-01-31")
+		return false
+	}
+	if !mealSlots[req.Slot] {
+		BadRequest(w, "slot must be breakfast, lunch, dinner or snack")
+		return false
+	}
+	if req.Servings < 0 || req.Servings > maxRecipeServings {
+		BadRequest(w, "Servings must be between 1 and 1000")
+		return false
+	}
+
+	var text *string
+	if req.Text != nil {
+		if trimmed := strings.TrimSpace(*req.Text); trimmed != "" {
+			text = &trimmed
+		}
+	}
+	if (req.RecipeID == nil) == (text == nil) {
+		BadRequest(w, "Either a recipe or text is required")
+		return false
+	}
+	if text != nil && len(*text) > 200 {
+		BadRequest(w, "Text must be at most 200 characters")
+		return false
+	}
+
+	if req.RecipeID != nil {
+		if _, err := h.recipeRepo.GetByID(*req.RecipeID); err != nil {
+			if errors.Is(err, repository.ErrRecipeNotFound) {
+				InvalidReference(w, "Recipe does not exist")
+				return false
+			}
+			InternalError(w, "Failed to get recipe")
+			return false
+		}
+	}
+
+	entry.Date = req.Date
+	entry.Slot = req.Slot
+	entry.RecipeID = req.RecipeID
+	entry.Text = text
+	entry.Servings = req.Servings
+	return true
+}
+
+// respond writes the entry with its recipe
+func (h *MealPlanHandler) respond(w http.ResponseWriter, status int, id string) {
+	entry, err := h.mealPlanRepo.GetByID(id)
+	if err != nil {
+		InternalError(w, "Failed to get meal plan entry")
+		return
+	}
+	JSON(w, status, entry)
+}
+
+// validateMealPlanRange checks a from/to pair of dates, returning the error
+// message or "" when they are fine
+func validateMealPlanRange(from, to string) string {
+	start, err := time.Parse(mealPlanDateLayout, from)
+	if err != nil {
+		return "from must be a date like 2024-01-31"
+	}
+	end, err := time.Parse(mealPlanDateLayout, to)
+	if err != nil {
+		return "to must be a date like 2024-01-31"
+	}
+	if end.Before(start) {
+		return "to must not be before from"
+	}
+	if end.Sub(start) >= maxMealPlanDays*24*time.Hour {
+		return "A meal plan range can cover at most 92 days"
+	}
+	return ""
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/categorize"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+type PantryHandler struct {
+	pantryRepo   *repository.PantryRepository
+	itemRepo     *repository.ItemRepository
+	categoryRepo *repository.CategoryRepository
+	memberRepo   *repository.ListMemberRepository
+	activityRepo *repository.ActivityRepository
+	hub          *realtime.Hub
+}
+
+func NewPantryHandler(pantryRepo *repository.PantryRepository, itemRepo *repository.ItemRepository, categoryRepo *repository.CategoryRepository, memberRepo *repository.ListMemberRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) *PantryHandler {
+	return &PantryHandler{
+		pantryRepo:   pantryRepo,
+		itemRepo:     itemRepo,
+		categoryRepo: categoryRepo,
+		memberRepo:   memberRepo,
+		activityRepo: activityRepo,
+		hub:          hub,
+	}
+}
+
+// GetAll returns the pantry, optionally only one location
+func (h *PantryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	items, err := h.pantryRepo.GetAll(r.URL.Query().Get("location"))
+	if err != nil {
+		InternalError(w, "Failed to get pantry")
+		return
+	}
+	JSON(w, http.StatusOK, items)
+}
+
+// GetByID returns a single pantry item
+func (h *PantryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	item, err := h.pantryRepo.GetByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrPantryItemNotFound) {
+			NotFound(w, "Pantry item not found")
+			return
+		}
+		InternalError(w, "Failed to get pantry item")
+		return
+	}
+	JSON(w, http.StatusOK, item)
+}
+
+// Create adds an item to the pantry
+func (h *PantryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.CreatePantryItemRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	item := &models.PantryItem{ID: auth.GenerateID()}
+	update := models.UpdatePantryItemRequest{
+		Name:              &req.Name,
+		Quantity:          &req.Quantity,
+		Unit:              req.Unit,
+		Location:          req.Location,
+		ExpiresAt:         req.ExpiresAt,
+		LowStockThreshold: req.LowStockThreshold,
+		RestockListID:     req.RestockListID,
+	}
+	if req.CategoryID != "" {
+		update.CategoryID = &req.CategoryID
+	}
+	if !h.apply(w, r, item, &update) {
+		return
+	}
+
+	// Suggest a category from the name when none was given
+	if item.CategoryID == "" {
+		suggestion, err := categorize.Suggest(item.Name, h.itemRepo.MostUsedCategory)
+		if err != nil {
+			InternalError(w, "Failed to suggest category")
+			return
+		}
+		item.CategoryID = suggestion.CategoryID
+	}
+
+	restocked, err := h.pantryRepo.Create(item, user.ID)
+	if err != nil {
+		InternalError(w, "Failed to create pantry item")
+		return
+	}
+	h.publishRestock(r, restocked)
+	item.Restocked = restocked
+
+	JSON(w, http.StatusCreated, item)
+}
+
+// Update changes a pantry item. Taking it down to its low-stock threshold
+// adds it to its restock list, when the user can edit that list.
+func (h *PantryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	item, err := h.pantryRepo.GetByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrPantryItemNotFound) {
+			NotFound(w, "Pantry item not found")
+			return
+		}
+		InternalError(w, "Failed to get pantry item")
+		return
+	}
+
+	var req models.UpdatePantryItemRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if !h.apply(w, r, item, &req) {
+		return
+	}
+
+	restocked, err := h.pantryRepo.Update(item, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPantryItemNotFound) {
+			NotFound(w, "Pantry item not found")
+			return
+		}
+		InternalError(w, "Failed to update pantry item")
+		return
+	}
+	h.publishRestock(r, restocked)
+	item.Restocked = restocked
+
+	JSON(w, http.StatusOK, item)
+}
+
+// Delete removes an item from the pantry
+func (h *PantryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.pantryRepo.Delete(chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, repository.ErrPantryItemNotFound) {
+			NotFound(w, "Pantry item not found")
+			return
+		}
+		InternalError(w, "Failed to delete pantry item")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// apply validates req and copies the fields it sets onto item, writing the
+// error response and returning false when something is wrong
+func (h *PantryHandler) apply(w http.ResponseWriter, r *http.Request, item *models.PantryItem, req *models.UpdatePantryItemRequest) bool {
+	if req.Name != nil {
+		if len(*req.Name) == 0 {
+			BadRequest(w, "Name is required")
+			return false
+		}
+		if len(*req.Name) > 200 {
+			BadRequest(w, "Name must be at most 200 characters")
+			return false
+		}
+		item.Name = *req.Name
+	}
+	if req.Quantity != nil {
+		if *req.Quantity < 0 {
+			BadRequest(w, "Quantity must not be negative")
+			return false
+		}
+		item.Quantity = *req.Quantity
+	}
+	if req.Unit != nil {
+		item.Unit = emptyToNil(*req.Unit)
+	}
+	if req.Location != nil {
+		if len(*req.Location) > 100 {
+			BadRequest(w, "Location must be at most 100 characters")
+			return false
+		}
+		item.Location = emptyToNil(*req.Location)
+	}
+	if req.CategoryID != nil {
+		if !checkCategory(w, h.categoryRepo, *req.CategoryID) {
+			return false
+		}
+		item.CategoryID = *req.CategoryID
+	}
+	if req.ExpiresAt != nil {
+		if *req.ExpiresAt == 0 {
+			item.ExpiresAt = nil
+		} else {
+			item.ExpiresAt = req.ExpiresAt
+		}
+	}
+	if req.LowStockThreshold != nil {
+		if *req.LowStockThreshold < 0 {
+			item.LowStockThreshold = nil
+		} else {
+			item.LowStockThreshold = req.LowStockThreshold
+		}
+	}
+	if req.RestockListID != nil {
+		if *req.RestockListID == "" {
+			item.RestockListID = nil
+		} else {
+			if _, ok := authorizeList(w, r, h.memberRepo, *req.RestockListID, models.ListRoleEditor); !ok {
+				return false
+			}
+			item.RestockListID = req.RestockListID
+		}
+	}
+	return true
+}
+
+func (h *PantryHandler) publishRestock(r *http.Request, item *models.Item) {
+	if item != nil {
+		h.hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: item.ListID, Version: item.Version, Item: item})
+		recordActivity(h.activityRepo, r, item.ListID, models.ActivityItemCreated, item, nil, item)
+	}
+}
+
+func emptyToNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
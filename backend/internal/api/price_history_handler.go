@@ -2,12 +2,20 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/quickadd"
 	"github.com/kleyson/groceries/backend/internal/repository"
 )
 
+const (
+	defaultPriceStatsDays = 90
+	maxPriceStatsDays     = 3650
+)
+
 type PriceHistoryHandler struct {
 	priceHistoryRepo *repository.PriceHistoryRepository
 }
@@ -29,10 +37,46 @@ func (h *PriceHistoryHandler) GetByItemName(w http.ResponseWriter, r *http.Reque
 		InternalError(w, "Failed to get price history")
 		return
 	}
+	for i := range history {
+		history[i].SetUnitPrice()
+	}
 
 	JSON(w, http.StatusOK, history)
 }
 
+// GetStats returns min, max, average and last price for an item over the last
+// days days, the percent change across them and a per-store comparison
+func (h *PriceHistoryHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	itemName := r.URL.Query().Get("itemName")
+	if itemName == "" {
+		BadRequest(w, "itemName query parameter is required")
+		return
+	}
+
+	days := defaultPriceStatsDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxPriceStatsDays {
+			BadRequest(w, "days must be between 1 and 3650")
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().AddDate(0, 0, -days).UnixMilli()
+	stats, err := h.priceHistoryRepo.GetStats(itemName, since)
+	if err != nil {
+		InternalError(w, "Failed to get price statistics")
+		return
+	}
+	if stats == nil {
+		NotFound(w, "No prices recorded for this item")
+		return
+	}
+
+	JSON(w, http.StatusOK, stats)
+}
+
 // Create records a new price
 func (h *PriceHistoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatePriceHistoryRequest
@@ -50,19 +94,34 @@ func (h *PriceHistoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 		BadRequest(w, "Price must be non-negative")
 		return
 	}
+	if req.PackageSize == nil {
+		req.PackageUnit = nil
+	} else if msg := normalizePackage(*req.PackageSize, &req.PackageUnit); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
 
 	priceHistory := &models.PriceHistory{
-		ID:         auth.GenerateID(),
-		ItemName:   req.ItemName,
-		Price:      req.Price,
-		Store:      req.Store,
-		RecordedAt: auth.GetCurrentTimestamp(),
+		ID:          auth.GenerateID(),
+		ItemName:    req.ItemName,
+		Price:       req.Price,
+		Unit:        req.Unit,
+		PackageSize: req.PackageSize,
+		PackageUnit: req.PackageUnit,
+		Store:       req.Store,
+		RecordedAt:  auth.GetCurrentTimestamp(),
+	}
+	if req.Unit != nil {
+		if unit := quickadd.NormalizeUnit(*req.Unit); unit != "" {
+			priceHistory.Unit = &unit
+		}
 	}
 
 	if err := h.priceHistoryRepo.Create(priceHistory); err != nil {
 		InternalError(w, "Failed to create price history")
 		return
 	}
+	priceHistory.SetUnitPrice()
 
 	JSON(w, http.StatusCreated, priceHistory)
 }
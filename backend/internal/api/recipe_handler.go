@@ -0,0 +1,345 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/categorize"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/quickadd"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/recipeimport"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+const (
+	maxRecipeIngredients = 200
+	maxRecipeSteps       = 100
+	maxRecipeServings    = 1000
+	maxRecipeImportSize  = 2 << 20
+)
+
+type RecipeHandler struct {
+	recipeRepo   *repository.RecipeRepository
+	itemRepo     *repository.ItemRepository
+	categoryRepo *repository.CategoryRepository
+	listRepo     *repository.ListRepository
+	memberRepo   *repository.ListMemberRepository
+	activityRepo *repository.ActivityRepository
+	hub          *realtime.Hub
+}
+
+func NewRecipeHandler(recipeRepo *repository.RecipeRepository, itemRepo *repository.ItemRepository, categoryRepo *repository.CategoryRepository, listRepo *repository.ListRepository, memberRepo *repository.ListMemberRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) *RecipeHandler {
+	return &RecipeHandler{
+		recipeRepo:   recipeRepo,
+		itemRepo:     itemRepo,
+		categoryRepo: categoryRepo,
+		listRepo:     listRepo,
+		memberRepo:   memberRepo,
+		activityRepo: activityRepo,
+		hub:          hub,
+	}
+}
+
+// GetAll returns the household's recipes
+func (h *RecipeHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	recipes, err := h.recipeRepo.GetAll()
+	if err != nil {
+		InternalError(w, "Failed to get recipes")
+		return
+	}
+	JSON(w, http.StatusOK, recipes)
+}
+
+// GetByID returns a recipe with its ingredients and steps
+func (h *RecipeHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.loadRecipe(w, r)
+	if !ok {
+		return
+	}
+	JSON(w, http.StatusOK, recipe)
+}
+
+// Create saves a new recipe
+func (h *RecipeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.RecipeRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	recipe := &models.Recipe{ID: auth.GenerateID(), CreatedBy: &user.ID}
+	if !h.apply(w, recipe, &req) {
+		return
+	}
+
+	if err := h.recipeRepo.Create(recipe); err != nil {
+		InternalError(w, "Failed to create recipe")
+		return
+	}
+
+	JSON(w, http.StatusCreated, recipe)
+}
+
+// Update replaces a recipe, including all of its ingredients and steps
+func (h *RecipeHandler) Update(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.loadRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RecipeRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if !h.apply(w, recipe, &req) {
+		return
+	}
+
+	if err := h.recipeRepo.Replace(recipe); err != nil {
+		if errors.Is(err, repository.ErrRecipeNotFound) {
+			NotFound(w, "Recipe not found")
+			return
+		}
+		InternalError(w, "Failed to update recipe")
+		return
+	}
+
+	JSON(w, http.StatusOK, recipe)
+}
+
+// Delete deletes a recipe
+func (h *RecipeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.recipeRepo.Delete(chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, repository.ErrRecipeNotFound) {
+			NotFound(w, "Recipe not found")
+			return
+		}
+		InternalError(w, "Failed to delete recipe")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// AddToList adds a recipe's ingredients to a list, scaled to the requested
+// servings and merged into matching items already on the list
+func (h *RecipeHandler) AddToList(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.loadRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddRecipeToListRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.ListID == "" {
+		BadRequest(w, "List ID is required")
+		return
+	}
+	h.addToList(w, r, recipe, req.ListID, req.Servings)
+}
+
+// Import reads a recipe from a pasted page or JSON-LD document. Nothing is
+// fetched: the client sends the content. The recipe is saved unless a list
+// ID is given, in which case its ingredients are added to that list instead.
+func (h *RecipeHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.ImportRecipeRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		BadRequest(w, "Content is required")
+		return
+	}
+	if len(req.Content) > maxRecipeImportSize {
+		BadRequest(w, "Content must be at most 2 MB")
+		return
+	}
+
+	parsed, err := recipeimport.Parse(req.Content)
+	if err != nil {
+		BadRequest(w, "No recipe found in content")
+		return
+	}
+
+	recipe := &models.Recipe{ID: auth.GenerateID(), CreatedBy: &user.ID}
+	if !h.apply(w, recipe, parsed) {
+		return
+	}
+
+	if req.ListID != "" {
+		h.addToList(w, r, recipe, req.ListID, req.Servings)
+		return
+	}
+
+	if err := h.recipeRepo.Create(recipe); err != nil {
+		InternalError(w, "Failed to create recipe")
+		return
+	}
+
+	JSON(w, http.StatusCreated, recipe)
+}
+
+// addToList adds recipe's ingredients to a list for servings, which defaults
+// to the recipe's own, and responds with the updated list
+func (h *RecipeHandler) addToList(w http.ResponseWriter, r *http.Request, recipe *models.Recipe, listID string, servings int) {
+	if servings < 0 || servings > maxRecipeServings {
+		BadRequest(w, "Servings must be between 1 and 1000")
+		return
+	}
+	if servings == 0 {
+		servings = recipe.Servings
+	}
+
+	role, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor)
+	if !ok {
+		return
+	}
+
+	created, merged, err := h.recipeRepo.AddToList(recipe, listID, servings)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to add recipe to list")
+		return
+	}
+
+	publishMerge(h.hub, h.activityRepo, r, listID, created, merged)
+
+	list, err := h.listRepo.GetByID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get updated list")
+		return
+	}
+	list.Role = role
+
+	JSON(w, http.StatusOK, list)
+}
+
+// apply validates req and copies it onto recipe, writing the error response
+// and returning false when something is wrong. Ingredient units are
+// normalized and missing categories suggested from the name.
+func (h *RecipeHandler) apply(w http.ResponseWriter, recipe *models.Recipe, req *models.RecipeRequest) bool {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		BadRequest(w, "Name is required")
+		return false
+	}
+	if len(req.Name) > 200 {
+		BadRequest(w, "Name must be at most 200 characters")
+		return false
+	}
+	if req.Servings == 0 {
+		req.Servings = 1
+	}
+	if req.Servings < 1 || req.Servings > maxRecipeServings {
+		BadRequest(w, "Servings must be between 1 and 1000")
+		return false
+	}
+	if len(req.Ingredients) > maxRecipeIngredients {
+		BadRequest(w, "A recipe can have at most 200 ingredients")
+		return false
+	}
+	if len(req.Steps) > maxRecipeSteps {
+		BadRequest(w, "A recipe can have at most 100 steps")
+		return false
+	}
+
+	ingredients := make([]models.RecipeIngredient, 0, len(req.Ingredients))
+	for _, in := range req.Ingredients {
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			BadRequest(w, "Ingredient name is required")
+			return false
+		}
+		if len(name) > 200 {
+			BadRequest(w, "Ingredient name must be at most 200 characters")
+			return false
+		}
+		if in.Quantity < 0 {
+			BadRequest(w, "Ingredient quantity must not be negative")
+			return false
+		}
+
+		ingredient := models.RecipeIngredient{Name: name, Quantity: in.Quantity, CategoryID: in.CategoryID}
+		if in.Unit != nil {
+			unit := strings.TrimSpace(*in.Unit)
+			if normalized := quickadd.NormalizeUnit(unit); normalized != "" {
+				unit = normalized
+			}
+			if len(unit) > 50 {
+				BadRequest(w, "Ingredient unit must be at most 50 characters")
+				return false
+			}
+			if unit != "" {
+				ingredient.Unit = &unit
+			}
+		}
+
+		if ingredient.CategoryID != "" {
+			if !checkCategory(w, h.categoryRepo, ingredient.CategoryID) {
+				return false
+			}
+		} else {
+			suggestion, err := categorize.Suggest(name, h.itemRepo.MostUsedCategory)
+			if err != nil {
+				InternalError(w, "Failed to suggest category")
+				return false
+			}
+			ingredient.CategoryID = suggestion.CategoryID
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+
+	steps := make([]models.RecipeStep, 0, len(req.Steps))
+	for _, text := range req.Steps {
+		if text = strings.TrimSpace(text); text != "" {
+			steps = append(steps, models.RecipeStep{Text: text})
+		}
+	}
+
+	recipe.Name = req.Name
+	recipe.Description = req.Description
+	recipe.Servings = req.Servings
+	recipe.Ingredients = ingredients
+	recipe.Steps = steps
+	return true
+}
+
+// loadRecipe fetches the recipe named in the URL, responding with 404 when it
+// does not exist
+func (h *RecipeHandler) loadRecipe(w http.ResponseWriter, r *http.Request) (*models.Recipe, bool) {
+	recipe, err := h.recipeRepo.GetByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecipeNotFound) {
+			NotFound(w, "Recipe not found")
+			return nil, false
+		}
+		InternalError(w, "Failed to get recipe")
+		return nil, false
+	}
+	return recipe, true
+}
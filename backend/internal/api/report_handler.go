@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+const reportDateLayout = "2006-01-02"
+
+var spendingDimensions = map[string]bool{
+	models.SpendingByMonth:    true,
+	models.SpendingByCategory: true,
+	models.SpendingByStore:    true,
+	models.SpendingByUser:     true,
+}
+
+type ReportHandler struct {
+	reportRepo *repository.ReportRepository
+}
+
+func NewReportHandler(reportRepo *repository.ReportRepository) *ReportHandler {
+	return &ReportHandler{reportRepo: reportRepo}
+}
+
+// Spending reports what was spent on checked items from from to to,
+// inclusive dates that default to the current month so far. groupBy is a
+// comma-separated list of month, category, store and user, month by default.
+// With format=csv the groups are sent as a CSV file.
+func (h *ReportHandler) Spending(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	var err error
+	if raw := query.Get("from"); raw != "" {
+		if start, err = time.ParseInLocation(reportDateLayout, raw, time.Local); err != nil {
+			BadRequest(w, "from must be a date like 2024-01-31")
+			return
+		}
+	}
+	if raw := query.Get("to"); raw != "" {
+		if end, err = time.ParseInLocation(reportDateLayout, raw, time.Local); err != nil {
+			BadRequest(w, "to must be a date like 2024-01-31")
+			return
+		}
+	}
+	if end.Before(start) {
+		BadRequest(w, "to must not be before from")
+		return
+	}
+
+	groupBy := []string{models.SpendingByMonth}
+	if raw := query.Get("groupBy"); raw != "" {
+		groupBy = nil
+		seen := make(map[string]bool)
+		for _, dimension := range strings.Split(raw, ",") {
+			dimension = strings.TrimSpace(dimension)
+			if !spendingDimensions[dimension] {
+				BadRequest(w, "groupBy must list month, category, store or user")
+				return
+			}
+			if !seen[dimension] {
+				seen[dimension] = true
+				groupBy = append(groupBy, dimension)
+			}
+		}
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		BadRequest(w, "format must be json or csv")
+		return
+	}
+
+	// to is inclusive, so the range runs until the end of that day
+	until := end.AddDate(0, 0, 1).UnixMilli() - 1
+	report, err := h.reportRepo.Spending(user.ID, start.UnixMilli(), until, groupBy, time.Local)
+	if err != nil {
+		InternalError(w, "Failed to get spending report")
+		return
+	}
+
+	if format == "csv" {
+		filename := fmt.Sprintf("spending-%s-to-%s.csv", start.Format(reportDateLayout), end.Format(reportDateLayout))
+		writeSpendingCSV(w, report, filename)
+		return
+	}
+	JSON(w, http.StatusOK, report)
+}
+
+// writeSpendingCSV sends the report's groups as CSV, a column for each
+// grouped dimension followed by the item count and total
+func writeSpendingCSV(w http.ResponseWriter, report *models.SpendingReport, filename string) {
+	header := append(append([]string{}, report.GroupBy...), "items", "total")
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	_ = out.Write(header)
+	for _, group := range report.Groups {
+		var record []string
+		for _, dimension := range report.GroupBy {
+			switch dimension {
+			case models.SpendingByMonth:
+				record = append(record, group.Month)
+			case models.SpendingByCategory:
+				record = append(record, csvText(group.Category))
+			case models.SpendingByStore:
+				record = append(record, csvText(group.Store))
+			case models.SpendingByUser:
+				record = append(record, csvText(group.UserName))
+			}
+		}
+		record = append(record, strconv.Itoa(group.Items), strconv.FormatFloat(group.Total, 'f', 2, 64))
+		_ = out.Write(record)
+	}
+	out.Flush()
+}
+
+// csvText keeps user-written text from being read as a formula when the CSV
+// is opened in a spreadsheet, by prefixing text that starts like one with '
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -26,6 +26,20 @@ func Error(w http.ResponseWriter, status int, code, message string) {
 	})
 }
 
+// ErrorWithData writes an error response that also carries data, such as the
+// current server copy of an entity
+func ErrorWithData(w http.ResponseWriter, status int, code, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.APIResponse{
+		Data: data,
+		Error: &models.APIError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
 // Common error responses
 func BadRequest(w http.ResponseWriter, message string) {
 	Error(w, http.StatusBadRequest, "BAD_REQUEST", message)
@@ -43,6 +57,17 @@ func Forbidden(w http.ResponseWriter, message string) {
 	Error(w, http.StatusForbidden, "FORBIDDEN", message)
 }
 
+// InvalidReference reports a request body that points at something that
+// does not exist, such as an unknown category
+func InvalidReference(w http.ResponseWriter, message string) {
+	Error(w, http.StatusUnprocessableEntity, "INVALID_REFERENCE", message)
+}
+
+// VersionConflict reports a stale write along with the current server copy
+func VersionConflict(w http.ResponseWriter, message string, current interface{}) {
+	ErrorWithData(w, http.StatusConflict, "VERSION_CONFLICT", message, current)
+}
+
 func InternalError(w http.ResponseWriter, message string) {
 	Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", message)
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/kleyson/groceries/backend/internal/realtime"
 	"github.com/kleyson/groceries/backend/internal/repository"
 )
 
@@ -52,9 +53,21 @@ func NewRouter(
 	userRepo *repository.UserRepository,
 	sessionRepo *repository.SessionRepository,
 	listRepo *repository.ListRepository,
+	listMemberRepo *repository.ListMemberRepository,
 	itemRepo *repository.ItemRepository,
 	categoryRepo *repository.CategoryRepository,
 	priceHistoryRepo *repository.PriceHistoryRepository,
+	syncRepo *repository.SyncRepository,
+	tombstoneRepo *repository.TombstoneRepository,
+	activityRepo *repository.ActivityRepository,
+	templateRepo *repository.TemplateRepository,
+	searchRepo *repository.SearchRepository,
+	pantryRepo *repository.PantryRepository,
+	recipeRepo *repository.RecipeRepository,
+	mealPlanRepo *repository.MealPlanRepository,
+	reportRepo *repository.ReportRepository,
+	budgetRepo *repository.BudgetRepository,
+	hub *realtime.Hub,
 	config Config,
 ) *chi.Mux {
 	r := chi.NewRouter()
@@ -69,18 +82,32 @@ func NewRouter(
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   config.AllowOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With", "If-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	// Handlers
 	authHandler := NewAuthHandler(userRepo, sessionRepo, config.SecureCookie)
-	listHandler := NewListHandler(listRepo)
-	itemHandler := NewItemHandler(itemRepo, listRepo)
-	categoryHandler := NewCategoryHandler(categoryRepo)
+	listHandler := NewListHandler(listRepo, itemRepo, listMemberRepo, activityRepo, budgetRepo, hub)
+	listMemberHandler := NewListMemberHandler(listRepo, listMemberRepo, userRepo)
+	itemHandler := NewItemHandler(itemRepo, listRepo, listMemberRepo, categoryRepo, pantryRepo, activityRepo, hub)
+	eventsHandler := NewEventsHandler(sessionRepo, listMemberRepo, hub)
+	categoryHandler := NewCategoryHandler(categoryRepo, itemRepo)
 	priceHistoryHandler := NewPriceHistoryHandler(priceHistoryRepo)
+	syncHandler := NewSyncHandler(syncRepo, hub)
+	changesHandler := NewChangesHandler(listRepo, itemRepo, categoryRepo, tombstoneRepo)
+	trashHandler := NewTrashHandler(listRepo, itemRepo, listMemberRepo, activityRepo, hub)
+	activityHandler := NewActivityHandler(activityRepo, listMemberRepo, syncRepo, hub)
+	templateHandler := NewTemplateHandler(templateRepo, listRepo, listMemberRepo, activityRepo, hub)
+	searchHandler := NewSearchHandler(searchRepo)
+	pantryHandler := NewPantryHandler(pantryRepo, itemRepo, categoryRepo, listMemberRepo, activityRepo, hub)
+	expiryHandler := NewExpiryHandler(pantryRepo, itemRepo)
+	recipeHandler := NewRecipeHandler(recipeRepo, itemRepo, categoryRepo, listRepo, listMemberRepo, activityRepo, hub)
+	mealPlanHandler := NewMealPlanHandler(mealPlanRepo, recipeRepo, listRepo, listMemberRepo, activityRepo, hub)
+	reportHandler := NewReportHandler(reportRepo)
+	budgetHandler := NewBudgetHandler(budgetRepo, categoryRepo)
 
 	// Auth middleware
 	authMiddleware := AuthMiddleware(userRepo, sessionRepo)
@@ -128,21 +155,87 @@ func NewRouter(
 				r.Get("/{id}", listHandler.GetByID)
 				r.Put("/{id}", listHandler.Update)
 				r.Delete("/{id}", listHandler.Delete)
+				r.Post("/{id}/duplicate", listHandler.Duplicate)
+				r.Post("/{id}/carry-over", listHandler.CarryOver)
+				r.Get("/{id}/events", eventsHandler.Stream)
+
+				// Activity history
+				r.Get("/{id}/activity", activityHandler.GetByListID)
+				r.Post("/{id}/activity/undo", activityHandler.Undo)
+
+				// Members (sharing)
+				r.Get("/{id}/members", listMemberHandler.GetAll)
+				r.Post("/{id}/members", listMemberHandler.Create)
+				r.Put("/{id}/members/{userId}", listMemberHandler.Update)
+				r.Delete("/{id}/members/{userId}", listMemberHandler.Delete)
 
 				// Items (nested under lists)
 				r.Route("/{listId}/items", func(r chi.Router) {
 					r.Get("/", itemHandler.GetByListID)
 					r.Post("/", itemHandler.Create)
 					r.Put("/reorder", itemHandler.Reorder)
+					r.Post("/bulk", itemHandler.Bulk)
+					r.Post("/parse", itemHandler.Parse)
 					r.Put("/{id}", itemHandler.Update)
 					r.Patch("/{id}/toggle", itemHandler.ToggleChecked)
 					r.Delete("/{id}", itemHandler.Delete)
 				})
 			})
 
+			// Item name autocomplete across all lists
+			r.Get("/items/suggest", itemHandler.Suggest)
+
+			// Search lists and items by name
+			r.Get("/search", searchHandler.Search)
+
+			// Templates
+			r.Route("/templates", func(r chi.Router) {
+				r.Get("/", templateHandler.GetAll)
+				r.Post("/", templateHandler.Create)
+				r.Get("/{id}", templateHandler.GetByID)
+				r.Put("/{id}", templateHandler.Update)
+				r.Delete("/{id}", templateHandler.Delete)
+				r.Put("/{id}/schedule", templateHandler.SetSchedule)
+				r.Delete("/{id}/schedule", templateHandler.ClearSchedule)
+				r.Post("/{id}/instantiate", templateHandler.Instantiate)
+			})
+
+			// Pantry (shared by the household)
+			r.Route("/pantry", func(r chi.Router) {
+				r.Get("/", pantryHandler.GetAll)
+				r.Post("/", pantryHandler.Create)
+				r.Get("/{id}", pantryHandler.GetByID)
+				r.Put("/{id}", pantryHandler.Update)
+				r.Delete("/{id}", pantryHandler.Delete)
+			})
+
+			// Recipes (shared by the household)
+			r.Route("/recipes", func(r chi.Router) {
+				r.Get("/", recipeHandler.GetAll)
+				r.Post("/", recipeHandler.Create)
+				r.Post("/import", recipeHandler.Import)
+				r.Get("/{id}", recipeHandler.GetByID)
+				r.Put("/{id}", recipeHandler.Update)
+				r.Delete("/{id}", recipeHandler.Delete)
+				r.Post("/{id}/add-to-list", recipeHandler.AddToList)
+			})
+
+			// Meal plan (shared by the household)
+			r.Route("/meal-plan", func(r chi.Router) {
+				r.Get("/", mealPlanHandler.GetRange)
+				r.Post("/", mealPlanHandler.Create)
+				r.Put("/{id}", mealPlanHandler.Update)
+				r.Delete("/{id}", mealPlanHandler.Delete)
+				r.Post("/generate-list", mealPlanHandler.GenerateList)
+			})
+
+			// What to use soon
+			r.Get("/expiring", expiryHandler.GetExpiring)
+
 			// Categories
 			r.Route("/categories", func(r chi.Router) {
 				r.Get("/", categoryHandler.GetAll)
+				r.Get("/suggest", categoryHandler.Suggest)
 				r.Post("/", categoryHandler.Create)
 				r.Put("/{id}", categoryHandler.Update)
 				r.Delete("/{id}", categoryHandler.Delete)
@@ -151,8 +244,31 @@ func NewRouter(
 			// Price history
 			r.Route("/price-history", func(r chi.Router) {
 				r.Get("/", priceHistoryHandler.GetByItemName)
+				r.Get("/stats", priceHistoryHandler.GetStats)
 				r.Post("/", priceHistoryHandler.Create)
 			})
+
+			// Reports
+			r.Get("/reports/spending", reportHandler.Spending)
+
+			// Monthly budgets (shared by the household)
+			r.Route("/budgets", func(r chi.Router) {
+				r.Get("/", budgetHandler.GetAll)
+				r.Post("/", budgetHandler.Create)
+				r.Put("/{id}", budgetHandler.Update)
+				r.Delete("/{id}", budgetHandler.Delete)
+			})
+
+			// Offline sync
+			r.Post("/sync", syncHandler.Sync)
+			r.Get("/changes", changesHandler.GetSince)
+
+			// Trash
+			r.Route("/trash", func(r chi.Router) {
+				r.Get("/", trashHandler.GetAll)
+				r.Post("/lists/{id}/restore", trashHandler.RestoreList)
+				r.Post("/items/{id}/restore", trashHandler.RestoreItem)
+			})
 		})
 	})
 
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	searchRepo *repository.SearchRepository
+}
+
+func NewSearchHandler(searchRepo *repository.SearchRepository) *SearchHandler {
+	return &SearchHandler{searchRepo: searchRepo}
+}
+
+// Search finds lists and items by name across the user's lists. Results are
+// grouped by list and paged by list with limit and offset; includeChecked and
+// includeTrashed widen the search.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+	opts := models.SearchOptions{Limit: defaultSearchLimit}
+
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			BadRequest(w, "limit must be a positive number")
+			return
+		}
+		opts.Limit = min(parsed, maxSearchLimit)
+	}
+	if raw := query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			BadRequest(w, "offset must be zero or a positive number")
+			return
+		}
+		opts.Offset = parsed
+	}
+
+	var err error
+	if opts.IncludeChecked, err = queryBool(r, "includeChecked"); err != nil {
+		BadRequest(w, "includeChecked must be true or false")
+		return
+	}
+	if opts.IncludeTrashed, err = queryBool(r, "includeTrashed"); err != nil {
+		BadRequest(w, "includeTrashed must be true or false")
+		return
+	}
+
+	page, err := h.searchRepo.Search(user.ID, query.Get("q"), opts)
+	if err != nil {
+		InternalError(w, "Failed to search")
+		return
+	}
+
+	JSON(w, http.StatusOK, page)
+}
+
+// queryBool reads an optional boolean query parameter, false when absent
+func queryBool(r *http.Request, name string) (bool, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
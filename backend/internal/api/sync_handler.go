@@ -0,0 +1,640 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// maxSyncOperations bounds the size of a single sync batch
+const maxSyncOperations = 500
+
+type SyncHandler struct {
+	syncRepo *repository.SyncRepository
+	hub      *realtime.Hub
+}
+
+func NewSyncHandler(syncRepo *repository.SyncRepository, hub *realtime.Hub) *SyncHandler {
+	return &SyncHandler{
+		syncRepo: syncRepo,
+		hub:      hub,
+	}
+}
+
+// Sync applies a batch of queued offline operations in order within one
+// transaction. Operations already applied are not applied again; their
+// original result is returned instead. Conflicting or invalid operations are
+// reported per operation and do not stop the rest of the batch.
+func (h *SyncHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.SyncRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Validate
+	if len(req.Operations) == 0 {
+		BadRequest(w, "Operations are required")
+		return
+	}
+	if len(req.Operations) > maxSyncOperations {
+		BadRequest(w, "Too many operations in one batch")
+		return
+	}
+	seen := make(map[string]bool, len(req.Operations))
+	for _, op := range req.Operations {
+		if op.ClientOpID == "" || len(op.ClientOpID) > 64 {
+			BadRequest(w, "Each operation needs a clientOpId of at most 64 characters")
+			return
+		}
+		if seen[op.ClientOpID] {
+			BadRequest(w, "Duplicate clientOpId in batch: "+op.ClientOpID)
+			return
+		}
+		seen[op.ClientOpID] = true
+	}
+
+	var results []models.SyncOperationResult
+	var events []realtime.Event
+
+	err := h.syncRepo.Transaction(func(tx *repository.TxRepositories) error {
+		results = make([]models.SyncOperationResult, 0, len(req.Operations))
+		batch := &syncBatch{tx: tx, user: user}
+
+		for _, op := range req.Operations {
+			previous, err := tx.Sync.GetOperation(user.ID, op.ClientOpID)
+			if err == nil {
+				var result models.SyncOperationResult
+				if err := json.Unmarshal([]byte(previous.Result), &result); err != nil {
+					return err
+				}
+				result.Replayed = true
+				results = append(results, result)
+				continue
+			}
+			if !errors.Is(err, repository.ErrSyncOperationNotFound) {
+				return err
+			}
+
+			batch.now = auth.GetCurrentTimestamp()
+			result, err := batch.apply(op)
+			if err != nil {
+				return err
+			}
+
+			encoded, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			if err := tx.Sync.CreateOperation(&models.SyncOperation{
+				ID:         auth.GenerateID(),
+				UserID:     user.ID,
+				ClientOpID: op.ClientOpID,
+				Result:     string(encoded),
+				CreatedAt:  batch.now,
+			}); err != nil {
+				return err
+			}
+
+			results = append(results, result)
+		}
+
+		events = batch.events
+		return nil
+	})
+	if err != nil {
+		InternalError(w, "Failed to apply sync operations")
+		return
+	}
+
+	// Only announce changes once they are committed
+	for _, event := range events {
+		h.hub.Publish(event)
+	}
+
+	JSON(w, http.StatusOK, models.SyncResponse{Results: results})
+}
+
+// syncBatch applies operations of one sync request inside its transaction.
+// Returned errors are unexpected failures that abort the whole batch; problems
+// with a single operation are reported in its result instead.
+type syncBatch struct {
+	tx     *repository.TxRepositories
+	user   *models.User
+	now    int64
+	events []realtime.Event
+}
+
+func (b *syncBatch) apply(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	switch op.Type {
+	case "list.create":
+		return b.createList(op)
+	case "list.update":
+		return b.updateList(op)
+	case "list.delete":
+		return b.deleteList(op)
+	case "item.create":
+		return b.createItem(op)
+	case "item.update":
+		return b.updateItem(op)
+	case "item.toggle":
+		return b.toggleItem(op)
+	case "item.delete":
+		return b.deleteItem(op)
+	case "item.reorder":
+		return b.reorderItems(op)
+	default:
+		return rejectSync(op, "BAD_REQUEST", "Unknown operation type"), nil
+	}
+}
+
+func (b *syncBatch) createList(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	var req models.CreateListRequest
+	if err := decodeSyncPayload(op, &req); err != nil {
+		return rejectSync(op, "BAD_REQUEST", "Invalid payload"), nil
+	}
+	if msg := validateListName(req.Name); msg != "" {
+		return rejectSync(op, "BAD_REQUEST", msg), nil
+	}
+
+	// Clients create entities offline with their own IDs so later queued
+	// operations can refer to them
+	id := op.EntityID
+	if id == "" {
+		id = auth.GenerateID()
+	}
+	if len(id) > 26 {
+		return rejectSync(op, "BAD_REQUEST", "Entity ID must be at most 26 characters"), nil
+	}
+
+	list := &models.List{
+		ID:        id,
+		Name:      req.Name,
+		OwnerID:   &b.user.ID,
+		CreatedAt: b.now,
+		UpdatedAt: b.now,
+	}
+	rejected, err := b.checkNewID(op, b.tx.Sync.CheckNewListID(id, b.user.ID), "List already exists")
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+	if err := b.tx.Lists.Create(list); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	return appliedSync(op, list.ID, list.Version), nil
+}
+
+func (b *syncBatch) updateList(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	rejected, err := b.authorize(op, op.EntityID, models.ListRoleEditor)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+
+	var req models.UpdateListRequest
+	if err := decodeSyncPayload(op, &req); err != nil {
+		return rejectSync(op, "BAD_REQUEST", "Invalid payload"), nil
+	}
+	if msg := validateListName(req.Name); msg != "" {
+		return rejectSync(op, "BAD_REQUEST", msg), nil
+	}
+
+	previous, err := b.tx.Lists.GetByID(op.EntityID)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	if op.BaseVersion != nil {
+		err = b.tx.Lists.UpdateWithVersion(op.EntityID, req.Name, *op.BaseVersion, b.now)
+	} else {
+		err = b.tx.Lists.Update(op.EntityID, req.Name, b.now)
+	}
+	if err != nil && !errors.Is(err, repository.ErrVersionConflict) {
+		return models.SyncOperationResult{}, err
+	}
+
+	list, getErr := b.tx.Lists.GetByID(op.EntityID)
+	if getErr != nil {
+		return models.SyncOperationResult{}, getErr
+	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return conflictSync(op, list.Version, list), nil
+	}
+	if previous.Name != list.Name {
+		if err := b.record(list.ID, models.ActivityListRenamed, nil, listNameSnapshot{Name: previous.Name}, listNameSnapshot{Name: list.Name}); err != nil {
+			return models.SyncOperationResult{}, err
+		}
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ListUpdated, ListID: list.ID, Version: list.Version, List: &list.List})
+	return appliedSync(op, list.ID, list.Version), nil
+}
+
+func (b *syncBatch) deleteList(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	rejected, err := b.authorize(op, op.EntityID, models.ListRoleOwner)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		// Deleting something already gone is what the client wanted
+		if rejected.Error.Code == "NOT_FOUND" {
+			return appliedSync(op, op.EntityID, 0), nil
+		}
+		return *rejected, nil
+	}
+
+	list, err := b.tx.Lists.GetByID(op.EntityID)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if op.BaseVersion != nil && *op.BaseVersion != list.Version {
+		return conflictSync(op, list.Version, list), nil
+	}
+
+	if err := b.tx.Lists.Delete(op.EntityID); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ListDeleted, ListID: op.EntityID})
+	return appliedSync(op, op.EntityID, 0), nil
+}
+
+func (b *syncBatch) createItem(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	rejected, err := b.authorize(op, op.ListID, models.ListRoleEditor)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+
+	var req models.CreateItemRequest
+	if err := decodeSyncPayload(op, &req); err != nil {
+		return rejectSync(op, "BAD_REQUEST", "Invalid payload"), nil
+	}
+	if msg := validateCreateItem(&req); msg != "" {
+		return rejectSync(op, "BAD_REQUEST", msg), nil
+	}
+	if req.CategoryID != "" {
+		rejected, err := b.checkCategory(op, req.CategoryID)
+		if err != nil {
+			return models.SyncOperationResult{}, err
+		}
+		if rejected != nil {
+			return *rejected, nil
+		}
+	}
+	if err := fillCategory(b.tx.Items, &req); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	id := op.EntityID
+	if id == "" {
+		id = auth.GenerateID()
+	}
+	if len(id) > 26 {
+		return rejectSync(op, "BAD_REQUEST", "Entity ID must be at most 26 characters"), nil
+	}
+	rejected, err = b.checkNewID(op, b.tx.Sync.CheckNewItemID(id, b.user.ID), "Item already exists")
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+
+	maxOrder, err := b.tx.Items.GetMaxSortOrder(op.ListID)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	item := &models.Item{
+		ID:          id,
+		ListID:      op.ListID,
+		Name:        req.Name,
+		Quantity:    req.Quantity,
+		Unit:        req.Unit,
+		CategoryID:  req.CategoryID,
+		Price:       req.Price,
+		PackageSize: req.PackageSize,
+		PackageUnit: req.PackageUnit,
+		Store:       req.Store,
+		SortOrder:   maxOrder + 1,
+	}
+	if err := b.tx.Items.Create(item); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.tx.Lists.TouchUpdatedAt(op.ListID, b.now); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.record(item.ListID, models.ActivityItemCreated, item, nil, item); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ItemCreated, ListID: item.ListID, Version: item.Version, Item: item})
+	return appliedSync(op, item.ID, item.Version), nil
+}
+
+func (b *syncBatch) updateItem(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	item, rejected, err := b.authorizeItem(op)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+
+	if op.BaseVersion != nil && *op.BaseVersion != item.Version {
+		return conflictSync(op, item.Version, item), nil
+	}
+
+	var req models.UpdateItemRequest
+	if err := decodeSyncPayload(op, &req); err != nil {
+		return rejectSync(op, "BAD_REQUEST", "Invalid payload"), nil
+	}
+	before := *item
+	if msg := applyItemUpdate(item, &req); msg != "" {
+		return rejectSync(op, "BAD_REQUEST", msg), nil
+	}
+	if req.CategoryID != nil {
+		rejected, err := b.checkCategory(op, *req.CategoryID)
+		if err != nil {
+			return models.SyncOperationResult{}, err
+		}
+		if rejected != nil {
+			return *rejected, nil
+		}
+	}
+
+	if err := b.tx.Items.UpdateWithVersion(item, item.Version); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.tx.Lists.TouchUpdatedAt(item.ListID, b.now); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.record(item.ListID, models.ActivityItemUpdated, item, before, item); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ItemUpdated, ListID: item.ListID, Version: item.Version, Item: item})
+	return appliedSync(op, item.ID, item.Version), nil
+}
+
+// syncTogglePayload lets a client state the checked value it wants, which is
+// safer to replay than a bare toggle
+type syncTogglePayload struct {
+	Checked *bool `json:"checked"`
+	models.ToggleItemRequest
+}
+
+func (b *syncBatch) toggleItem(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	item, rejected, err := b.authorizeItem(op)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+
+	if op.BaseVersion != nil && *op.BaseVersion != item.Version {
+		return conflictSync(op, item.Version, item), nil
+	}
+
+	var req syncTogglePayload
+	if err := decodeSyncPayload(op, &req); err != nil {
+		return rejectSync(op, "BAD_REQUEST", "Invalid payload"), nil
+	}
+	if req.Checked != nil && *req.Checked == item.Checked {
+		return appliedSync(op, item.ID, item.Version), nil
+	}
+
+	// With the pantry stocked, the best-before date belongs to the pantry item
+	expiresAt := req.ExpiresAt
+	if req.AddToPantry {
+		expiresAt = nil
+	}
+	updated, err := b.tx.Items.ToggleChecked(item.ID, b.user.ID, b.user.Name, expiresAt)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if updated.Checked && req.AddToPantry {
+		if _, err := b.tx.Pantry.AddStock(repository.StockFromItem(updated, req.Location, req.ExpiresAt)); err != nil {
+			return models.SyncOperationResult{}, err
+		}
+	}
+	if err := b.record(updated.ListID, toggleActivity(updated.Checked), updated, item, updated); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ItemToggled, ListID: updated.ListID, Version: updated.Version, Item: updated})
+	return appliedSync(op, updated.ID, updated.Version), nil
+}
+
+func (b *syncBatch) deleteItem(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	item, rejected, err := b.authorizeItem(op)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		// Deleting something already gone is what the client wanted
+		if rejected.Error.Code == "NOT_FOUND" {
+			return appliedSync(op, op.EntityID, 0), nil
+		}
+		return *rejected, nil
+	}
+
+	if op.BaseVersion != nil && *op.BaseVersion != item.Version {
+		return conflictSync(op, item.Version, item), nil
+	}
+
+	if err := b.tx.Items.Delete(item.ID); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.tx.Lists.TouchUpdatedAt(item.ListID, b.now); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.record(item.ListID, models.ActivityItemDeleted, item, item, nil); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ItemDeleted, ListID: item.ListID, Version: item.Version, ItemID: item.ID})
+	return appliedSync(op, item.ID, 0), nil
+}
+
+func (b *syncBatch) reorderItems(op models.SyncOperationRequest) (models.SyncOperationResult, error) {
+	rejected, err := b.authorize(op, op.ListID, models.ListRoleEditor)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if rejected != nil {
+		return *rejected, nil
+	}
+
+	var req models.ReorderItemsRequest
+	if err := decodeSyncPayload(op, &req); err != nil {
+		return rejectSync(op, "BAD_REQUEST", "Invalid payload"), nil
+	}
+	if len(req.ItemIDs) == 0 {
+		return rejectSync(op, "BAD_REQUEST", "Item IDs are required"), nil
+	}
+
+	previous, err := b.tx.Items.GetByListID(op.ListID)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.tx.Items.Reorder(op.ListID, req.ItemIDs); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.tx.Lists.TouchUpdatedAt(op.ListID, b.now); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+	if err := b.record(op.ListID, models.ActivityItemsReordered, nil, itemIDs(previous), req.ItemIDs); err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	list, err := b.tx.Lists.GetByID(op.ListID)
+	if err != nil {
+		return models.SyncOperationResult{}, err
+	}
+
+	b.events = append(b.events, realtime.Event{Type: realtime.ItemsReordered, ListID: op.ListID, Version: list.Version, ItemIDs: req.ItemIDs})
+	return appliedSync(op, op.ListID, list.Version), nil
+}
+
+// record adds an activity entry for the syncing user within the batch
+func (b *syncBatch) record(listID, action string, item *models.Item, before, after interface{}) error {
+	return b.tx.Activities.Create(newActivity(b.user, listID, action, item, before, after))
+}
+
+// authorize checks the user's role on a list, returning a rejected result
+// when access is missing
+func (b *syncBatch) authorize(op models.SyncOperationRequest, listID string, required models.ListRole) (*models.SyncOperationResult, error) {
+	if listID == "" {
+		result := rejectSync(op, "BAD_REQUEST", "List ID is required")
+		return &result, nil
+	}
+
+	role, err := b.tx.Members.GetRole(listID, b.user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) || errors.Is(err, repository.ErrMemberNotFound) {
+			result := rejectSync(op, "NOT_FOUND", "List not found")
+			return &result, nil
+		}
+		return nil, err
+	}
+
+	if !role.AtLeast(required) {
+		result := rejectSync(op, "FORBIDDEN", "You need "+string(required)+" access to this list")
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+// checkCategory returns a rejected result when the category does not exist
+func (b *syncBatch) checkCategory(op models.SyncOperationRequest, categoryID string) (*models.SyncOperationResult, error) {
+	exists, err := b.tx.Categories.Exists(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		result := rejectSync(op, "INVALID_REFERENCE", "Category not found")
+		return &result, nil
+	}
+	return nil, nil
+}
+
+// checkNewID turns the result of checking a client-chosen ID into a rejected
+// result when the ID is taken. Only IDs in lists the user can see are
+// reported as existing; others are a bare conflict so they give nothing away.
+func (b *syncBatch) checkNewID(op models.SyncOperationRequest, err error, exists string) (*models.SyncOperationResult, error) {
+	switch {
+	case err == nil:
+		return nil, nil
+	case errors.Is(err, repository.ErrAlreadyExists):
+		result := rejectSync(op, "ALREADY_EXISTS", exists)
+		return &result, nil
+	case errors.Is(err, repository.ErrIDConflict):
+		result := rejectSync(op, "ID_CONFLICT", "Entity ID is already in use")
+		return &result, nil
+	default:
+		return nil, err
+	}
+}
+
+// authorizeItem loads the operation's item and checks editor access to its list
+func (b *syncBatch) authorizeItem(op models.SyncOperationRequest) (*models.Item, *models.SyncOperationResult, error) {
+	item, err := b.tx.Items.GetByID(op.EntityID)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			result := rejectSync(op, "NOT_FOUND", "Item not found")
+			return nil, &result, nil
+		}
+		return nil, nil, err
+	}
+
+	if op.ListID != "" && op.ListID != item.ListID {
+		result := rejectSync(op, "NOT_FOUND", "Item not found in this list")
+		return nil, &result, nil
+	}
+
+	rejected, err := b.authorize(op, item.ListID, models.ListRoleEditor)
+	if err != nil || rejected != nil {
+		return nil, rejected, err
+	}
+
+	return item, nil, nil
+}
+
+func decodeSyncPayload(op models.SyncOperationRequest, v interface{}) error {
+	if len(op.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(op.Payload, v)
+}
+
+func appliedSync(op models.SyncOperationRequest, entityID string, version int) models.SyncOperationResult {
+	return models.SyncOperationResult{
+		ClientOpID: op.ClientOpID,
+		Status:     models.SyncStatusApplied,
+		EntityID:   entityID,
+		Version:    version,
+	}
+}
+
+func conflictSync(op models.SyncOperationRequest, version int, server interface{}) models.SyncOperationResult {
+	return models.SyncOperationResult{
+		ClientOpID: op.ClientOpID,
+		Status:     models.SyncStatusConflict,
+		EntityID:   op.EntityID,
+		Version:    version,
+		Error:      &models.APIError{Code: "VERSION_CONFLICT", Message: "Entity was modified by someone else"},
+		Server:     server,
+	}
+}
+
+func rejectSync(op models.SyncOperationRequest, code, message string) models.SyncOperationResult {
+	return models.SyncOperationResult{
+		ClientOpID: op.ClientOpID,
+		Status:     models.SyncStatusRejected,
+		EntityID:   op.EntityID,
+		Error:      &models.APIError{Code: code, Message: message},
+	}
+}
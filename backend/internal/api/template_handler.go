@@ -0,0 +1,325 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+type TemplateHandler struct {
+	templateRepo *repository.TemplateRepository
+	listRepo     *repository.ListRepository
+	memberRepo   *repository.ListMemberRepository
+	activityRepo *repository.ActivityRepository
+	hub          *realtime.Hub
+}
+
+func NewTemplateHandler(templateRepo *repository.TemplateRepository, listRepo *repository.ListRepository, memberRepo *repository.ListMemberRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) *TemplateHandler {
+	return &TemplateHandler{
+		templateRepo: templateRepo,
+		listRepo:     listRepo,
+		memberRepo:   memberRepo,
+		activityRepo: activityRepo,
+		hub:          hub,
+	}
+}
+
+// GetAll returns the current user's templates
+func (h *TemplateHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	templates, err := h.templateRepo.GetAllForUser(user.ID)
+	if err != nil {
+		InternalError(w, "Failed to get templates")
+		return
+	}
+	JSON(w, http.StatusOK, templates)
+}
+
+// GetByID returns a single template with its items
+func (h *TemplateHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+	JSON(w, http.StatusOK, template)
+}
+
+// Create saves a list and its items as a template
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req models.CreateTemplateRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.ListID == "" {
+		BadRequest(w, "List ID is required")
+		return
+	}
+	if _, ok := authorizeList(w, r, h.memberRepo, req.ListID, models.ListRoleViewer); !ok {
+		return
+	}
+
+	// Default to the list's name
+	if req.Name == "" {
+		list, err := h.listRepo.GetByID(req.ListID)
+		if err != nil {
+			InternalError(w, "Failed to get list")
+			return
+		}
+		req.Name = list.Name
+	}
+	if msg := validateListName(req.Name); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	now := auth.GetCurrentTimestamp()
+	template := &models.Template{
+		ID:        auth.GenerateID(),
+		Name:      req.Name,
+		OwnerID:   user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := h.templateRepo.CreateFromList(template, req.ListID); err != nil {
+		InternalError(w, "Failed to create template")
+		return
+	}
+
+	JSON(w, http.StatusCreated, template)
+}
+
+// Update renames a template
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTemplateRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if msg := validateListName(req.Name); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	now := auth.GetCurrentTimestamp()
+	if err := h.templateRepo.Rename(template.ID, req.Name, now); err != nil {
+		InternalError(w, "Failed to update template")
+		return
+	}
+	template.Name = req.Name
+	template.UpdatedAt = now
+
+	JSON(w, http.StatusOK, template)
+}
+
+// Delete deletes a template
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.templateRepo.Delete(template.ID); err != nil {
+		InternalError(w, "Failed to delete template")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// SetSchedule makes a template run every week at the given day and hour
+func (h *TemplateHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.TemplateScheduleRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Validate
+	if req.Weekday < 0 || req.Weekday > 6 {
+		BadRequest(w, "Weekday must be between 0 (Sunday) and 6 (Saturday)")
+		return
+	}
+	if req.Hour < 0 || req.Hour > 23 {
+		BadRequest(w, "Hour must be between 0 and 23")
+		return
+	}
+	if req.ListID != nil {
+		if _, ok := authorizeList(w, r, h.memberRepo, *req.ListID, models.ListRoleEditor); !ok {
+			return
+		}
+	}
+
+	template.ScheduleWeekday = &req.Weekday
+	template.ScheduleHour = req.Hour
+	template.ScheduleListID = req.ListID
+	template.NextRunAt = template.NextRunAfter(time.Now())
+
+	h.saveSchedule(w, template)
+}
+
+// ClearSchedule stops a template from running on a schedule
+func (h *TemplateHandler) ClearSchedule(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	template.ScheduleWeekday = nil
+	template.ScheduleHour = 0
+	template.ScheduleListID = nil
+	template.NextRunAt = nil
+
+	h.saveSchedule(w, template)
+}
+
+func (h *TemplateHandler) saveSchedule(w http.ResponseWriter, template *models.Template) {
+	now := auth.GetCurrentTimestamp()
+	if err := h.templateRepo.SetSchedule(template, now); err != nil {
+		InternalError(w, "Failed to update schedule")
+		return
+	}
+	template.UpdatedAt = now
+
+	JSON(w, http.StatusOK, template)
+}
+
+// Instantiate turns a template into a new list, or merges its items into an
+// existing list when a list ID is given
+func (h *TemplateHandler) Instantiate(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional; without one the list is named after the template
+	var req models.InstantiateTemplateRequest
+	if err := DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.ListID != nil {
+		h.instantiateInto(w, r, template, *req.ListID)
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = template.Name
+	}
+	if msg := validateListName(req.Name); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	now := auth.GetCurrentTimestamp()
+	list := &models.List{
+		ID:        auth.GenerateID(),
+		Name:      req.Name,
+		OwnerID:   &template.OwnerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	created, err := h.templateRepo.InstantiateNew(template, list)
+	if err != nil {
+		InternalError(w, "Failed to create list from template")
+		return
+	}
+	publishMerge(h.hub, h.activityRepo, r, list.ID, created, nil)
+
+	result, err := h.listRepo.GetByID(list.ID)
+	if err != nil {
+		InternalError(w, "Failed to get created list")
+		return
+	}
+	result.Role = models.ListRoleOwner
+
+	JSON(w, http.StatusCreated, result)
+}
+
+func (h *TemplateHandler) instantiateInto(w http.ResponseWriter, r *http.Request, template *models.Template, listID string) {
+	role, ok := authorizeList(w, r, h.memberRepo, listID, models.ListRoleEditor)
+	if !ok {
+		return
+	}
+
+	created, merged, err := h.templateRepo.InstantiateInto(template, listID)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to add template to list")
+		return
+	}
+
+	publishMerge(h.hub, h.activityRepo, r, listID, created, merged)
+
+	list, err := h.listRepo.GetByID(listID)
+	if err != nil {
+		InternalError(w, "Failed to get updated list")
+		return
+	}
+	list.Role = role
+
+	JSON(w, http.StatusOK, list)
+}
+
+// loadTemplate fetches the template named in the URL, responding with 404
+// when it does not exist or belongs to someone else
+func (h *TemplateHandler) loadTemplate(w http.ResponseWriter, r *http.Request) (*models.Template, bool) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return nil, false
+	}
+
+	template, err := h.templateRepo.GetByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			NotFound(w, "Template not found")
+			return nil, false
+		}
+		InternalError(w, "Failed to get template")
+		return nil, false
+	}
+
+	if template.OwnerID != user.ID {
+		NotFound(w, "Template not found")
+		return nil, false
+	}
+
+	return template, true
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+type TrashHandler struct {
+	listRepo     *repository.ListRepository
+	itemRepo     *repository.ItemRepository
+	memberRepo   *repository.ListMemberRepository
+	activityRepo *repository.ActivityRepository
+	hub          *realtime.Hub
+}
+
+func NewTrashHandler(listRepo *repository.ListRepository, itemRepo *repository.ItemRepository, memberRepo *repository.ListMemberRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) *TrashHandler {
+	return &TrashHandler{
+		listRepo:     listRepo,
+		itemRepo:     itemRepo,
+		memberRepo:   memberRepo,
+		activityRepo: activityRepo,
+		hub:          hub,
+	}
+}
+
+// GetAll returns the trashed lists and items the current user can restore
+func (h *TrashHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	lists, err := h.listRepo.GetTrashedForUser(user.ID)
+	if err != nil {
+		InternalError(w, "Failed to get trashed lists")
+		return
+	}
+
+	items, err := h.itemRepo.GetTrashedForUser(user.ID)
+	if err != nil {
+		InternalError(w, "Failed to get trashed items")
+		return
+	}
+
+	JSON(w, http.StatusOK, models.TrashResponse{Lists: lists, Items: items})
+}
+
+// RestoreList takes a list out of the trash
+func (h *TrashHandler) RestoreList(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	user := GetUserFromContext(r)
+	if user == nil {
+		Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	role, err := h.memberRepo.GetRoleIncludingTrashed(id, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrListNotFound) || errors.Is(err, repository.ErrMemberNotFound) {
+			NotFound(w, "List not found")
+			return
+		}
+		InternalError(w, "Failed to check list access")
+		return
+	}
+	if !role.AtLeast(models.ListRoleOwner) {
+		Forbidden(w, "You need owner access to this list")
+		return
+	}
+
+	if err := h.listRepo.Restore(id); err != nil {
+		if errors.Is(err, repository.ErrListNotFound) {
+			NotFound(w, "List not found in trash")
+			return
+		}
+		InternalError(w, "Failed to restore list")
+		return
+	}
+
+	list, err := h.listRepo.GetByID(id)
+	if err != nil {
+		InternalError(w, "Failed to get restored list")
+		return
+	}
+	list.Role = role
+
+	h.hub.Publish(realtime.Event{Type: realtime.ListUpdated, ListID: id, Version: list.Version, List: &list.List})
+
+	JSON(w, http.StatusOK, list)
+}
+
+// RestoreItem takes an item out of the trash. Its list must not be trashed.
+func (h *TrashHandler) RestoreItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	trashed, err := h.itemRepo.GetTrashedByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			NotFound(w, "Item not found in trash")
+			return
+		}
+		InternalError(w, "Failed to get item")
+		return
+	}
+
+	if _, ok := authorizeList(w, r, h.memberRepo, trashed.ListID, models.ListRoleEditor); !ok {
+		return
+	}
+
+	item, err := h.itemRepo.Restore(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			NotFound(w, "Item not found in trash")
+			return
+		}
+		InternalError(w, "Failed to restore item")
+		return
+	}
+
+	recordActivity(h.activityRepo, r, item.ListID, models.ActivityItemRestored, item, nil, item)
+
+	h.hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: item.ListID, Version: item.Version, Item: item})
+
+	JSON(w, http.StatusOK, item)
+}
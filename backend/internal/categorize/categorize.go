@@ -0,0 +1,163 @@
+// Package categorize suggests a category for an item from its name.
+package categorize
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+// Suggestion sources
+const (
+	SourceHistory = "history"
+	SourceKeyword = "keyword"
+	SourceDefault = "default"
+)
+
+// keywords lists words and phrases that place an item in one of the seeded
+// default categories, keyed by category name
+var keywords = map[string][]string{
+	"Produce": {
+		"apple", "avocado", "banana", "basil", "bean sprout", "berry", "blueberry", "broccoli",
+		"cabbage", "carrot", "cauliflower", "celery", "cherry", "cilantro", "corn", "cucumber",
+		"eggplant", "garlic", "ginger", "grape", "grapefruit", "herb", "kale", "kiwi", "leek",
+		"lemon", "lettuce", "lime", "mango", "melon", "mint", "mushroom", "onion", "orange",
+		"parsley", "peach", "pear", "pepper", "pineapple", "plum", "potato", "pumpkin",
+		"radish", "raspberry", "salad", "scallion", "spinach", "squash", "strawberry",
+		"sweet potato", "tomato", "watermelon", "zucchini", "fruit", "vegetable", "veggie",
+	},
+	"Dairy": {
+		"butter", "buttermilk", "cheddar", "cheese", "cottage cheese", "cream", "cream cheese",
+		"egg", "feta", "ghee", "half and half", "kefir", "milk", "mozzarella", "parmesan",
+		"ricotta", "sour cream", "whipped cream", "yogurt", "yoghurt",
+	},
+	"Meat": {
+		"bacon", "beef", "chicken", "chorizo", "cod", "fish", "ground beef", "ham", "lamb",
+		"meat", "meatball", "pork", "prawn", "salami", "salmon", "sausage", "seafood",
+		"shrimp", "steak", "tilapia", "tuna steak", "turkey", "veal",
+	},
+	"Bakery": {
+		"bagel", "baguette", "bread", "brioche", "bun", "cake", "croissant", "donut",
+		"doughnut", "english muffin", "loaf", "muffin", "pastry", "pita", "roll", "sourdough",
+		"tortilla", "wrap",
+	},
+	"Frozen": {
+		"frozen", "ice", "ice cream", "ice pop", "popsicle", "sorbet", "frozen pizza",
+		"fish stick", "frozen pea", "frozen vegetable",
+	},
+	"Beverages": {
+		"beer", "cider", "coffee", "cola", "energy drink", "espresso", "juice", "kombucha",
+		"lemonade", "soda", "sparkling water", "tea", "water", "wine", "drink", "smoothie",
+	},
+	"Snacks": {
+		"biscuit", "candy", "chip", "chocolate", "cookie", "cracker", "granola bar", "gum",
+		"nut", "peanut", "popcorn", "pretzel", "snack", "trail mix", "almond", "cashew",
+	},
+	"Pantry": {
+		"baking powder", "baking soda", "bean", "broth", "canned", "cereal", "chickpea",
+		"cornstarch", "couscous", "flour", "honey", "jam", "ketchup", "lentil", "mayo",
+		"mayonnaise", "mustard", "noodle", "oat", "oatmeal", "oil", "olive oil", "pasta",
+		"peanut butter", "quinoa", "rice", "salt", "sauce", "soup", "soy sauce", "spaghetti",
+		"spice", "stock", "sugar", "syrup", "tuna", "vanilla", "vinegar", "yeast",
+	},
+	"Household": {
+		"aluminum foil", "battery", "bin bag", "bleach", "cleaner", "detergent", "dish soap",
+		"dishwasher", "foil", "garbage bag", "hand soap", "laundry", "light bulb", "napkin",
+		"paper towel", "plastic wrap", "shampoo", "soap", "sponge", "tissue", "toilet paper",
+		"toothbrush", "toothpaste", "trash bag", "conditioner", "deodorant", "razor",
+	},
+}
+
+// keywordCategories maps each keyword to its category ID
+var keywordCategories = buildKeywordCategories()
+
+func buildKeywordCategories() map[string]string {
+	ids := make(map[string]string, len(db.DefaultCategories))
+	for _, category := range db.DefaultCategories {
+		ids[category.Name] = category.ID
+	}
+
+	result := make(map[string]string)
+	for name, words := range keywords {
+		for _, word := range words {
+			result[word] = ids[name]
+		}
+	}
+	return result
+}
+
+// Suggest picks a category for an item name. The category most used for the
+// same name before wins; learned looks it up and returns "" when there is
+// none. Otherwise a keyword match against the default categories is used,
+// and Other when nothing matches.
+func Suggest(name string, learned func(name string) (string, error)) (models.CategorySuggestion, error) {
+	if learned != nil {
+		categoryID, err := learned(name)
+		if err != nil {
+			return models.CategorySuggestion{}, err
+		}
+		if categoryID != "" {
+			return models.CategorySuggestion{CategoryID: categoryID, Source: SourceHistory}, nil
+		}
+	}
+
+	if categoryID, ok := Guess(name); ok {
+		return models.CategorySuggestion{CategoryID: categoryID, Source: SourceKeyword}, nil
+	}
+
+	return models.CategorySuggestion{CategoryID: db.OtherCategoryID, Source: SourceDefault}, nil
+}
+
+// Guess returns the default category whose keywords match name. Two-word
+// phrases win over single words, and later words win over earlier ones since
+// the last word usually says what the item is ("chicken soup" is soup).
+func Guess(name string) (string, bool) {
+	words := words(name)
+
+	for i := len(words) - 2; i >= 0; i-- {
+		if id, ok := lookup(words[i] + " " + words[i+1]); ok {
+			return id, true
+		}
+	}
+	for i := len(words) - 1; i >= 0; i-- {
+		if id, ok := lookup(words[i]); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// lookup finds a keyword, also trying the singular of a plural last word
+func lookup(phrase string) (string, bool) {
+	if id, ok := keywordCategories[phrase]; ok {
+		return id, true
+	}
+	for _, singular := range singulars(phrase) {
+		if id, ok := keywordCategories[singular]; ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func singulars(phrase string) []string {
+	var forms []string
+	switch {
+	case strings.HasSuffix(phrase, "ies"):
+		forms = append(forms, strings.TrimSuffix(phrase, "ies")+"y")
+	case strings.HasSuffix(phrase, "oes"), strings.HasSuffix(phrase, "ches"), strings.HasSuffix(phrase, "shes"):
+		forms = append(forms, strings.TrimSuffix(phrase, "es"))
+	}
+	if strings.HasSuffix(phrase, "s") && !strings.HasSuffix(phrase, "ss") {
+		forms = append(forms, strings.TrimSuffix(phrase, "s"))
+	}
+	return forms
+}
+
+func words(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
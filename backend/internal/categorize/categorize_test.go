@@ -0,0 +1,90 @@
+package categorize
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/db"
+)
+
+func categoryID(t *testing.T, name string) string {
+	for _, category := range db.DefaultCategories {
+		if category.Name == name {
+			return category.ID
+		}
+	}
+	t.Fatalf("No default category named %s", name)
+	return ""
+}
+
+func TestGuess(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+	}{
+		{"Bananas", "Produce"},
+		{"cherry tomatoes", "Produce"},
+		{"2% Milk", "Dairy"},
+		{"Eggs", "Dairy"},
+		{"sour cream", "Dairy"},
+		{"vanilla ice cream", "Frozen"},
+		{"chicken breast", "Meat"},
+		{"chicken soup", "Pantry"},
+		{"peanut butter", "Pantry"},
+		{"Sourdough bread", "Bakery"},
+		{"sparkling water", "Beverages"},
+		{"Potato chips", "Snacks"},
+		{"toilet paper", "Household"},
+		{"Strawberries", "Produce"},
+		{"peaches", "Produce"},
+		{"sandwiches", ""},
+		{"zzz", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Guess(tt.name)
+			if tt.category == "" {
+				if ok {
+					t.Errorf("Guess(%q) = %s, want no match", tt.name, got)
+				}
+				return
+			}
+			if want := categoryID(t, tt.category); got != want {
+				t.Errorf("Guess(%q) = %s, want %s", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	learned := func(name string) (string, error) {
+		if name == "Oat milk" {
+			return "custom-cat", nil
+		}
+		return "", nil
+	}
+
+	suggestion, err := Suggest("Oat milk", learned)
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if suggestion.CategoryID != "custom-cat" || suggestion.Source != SourceHistory {
+		t.Errorf("Expected learned category, got %+v", suggestion)
+	}
+
+	suggestion, _ = Suggest("Whole milk", learned)
+	if suggestion.CategoryID != categoryID(t, "Dairy") || suggestion.Source != SourceKeyword {
+		t.Errorf("Expected keyword match, got %+v", suggestion)
+	}
+
+	suggestion, _ = Suggest("Gift card", learned)
+	if suggestion.CategoryID != db.OtherCategoryID || suggestion.Source != SourceDefault {
+		t.Errorf("Expected Other, got %+v", suggestion)
+	}
+
+	errLookup := errors.New("lookup failed")
+	if _, err := Suggest("milk", func(string) (string, error) { return "", errLookup }); err != errLookup {
+		t.Errorf("Expected lookup error, got %v", err)
+	}
+}
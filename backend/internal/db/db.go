@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/models"
 )
 
@@ -63,13 +64,112 @@ func (db *DB) Migrate() error {
 		&models.Session{},
 		&models.Category{},
 		&models.List{},
+		&models.ListMember{},
 		&models.Item{},
 		&models.PriceHistory{},
+		&models.Purchase{},
+		&models.SyncOperation{},
+		&models.Tombstone{},
+		&models.Activity{},
+		&models.Template{},
+		&models.TemplateItem{},
+		&models.PantryItem{},
+		&models.Recipe{},
+		&models.RecipeIngredient{},
+		&models.RecipeStep{},
+		&models.MealPlanEntry{},
+		&models.Budget{},
+		&models.JobState{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
-	return nil
+
+	// List deletions go to the list's members, found through its list ID
+	if err := db.Model(&models.Tombstone{}).Where("entity_type = ? AND list_id IS NULL", models.EntityList).
+		UpdateColumn("list_id", gorm.Expr("entity_id")).Error; err != nil {
+		return fmt.Errorf("failed to link list tombstones: %w", err)
+	}
+
+	if err := db.normalizePriceHistoryNames(); err != nil {
+		return fmt.Errorf("failed to normalize price history names: %w", err)
+	}
+
+	// Items checked before the time was recorded count as checked when last
+	// changed. Rows older still have no change time either; their checked
+	// time stays unknown rather than landing in 1970, including where an
+	// earlier version of this backfill already put it there.
+	if err := db.Model(&models.Item{}).Where("checked = ? AND checked_at IS NULL AND updated_at > 0", true).
+		UpdateColumn("checked_at", gorm.Expr("updated_at")).Error; err != nil {
+		return fmt.Errorf("failed to fill in checked times: %w", err)
+	}
+	if err := db.Model(&models.Item{}).Where("checked_at = 0").
+		UpdateColumn("checked_at", nil).Error; err != nil {
+		return fmt.Errorf("failed to fill in checked times: %w", err)
+	}
+	if err := db.Where("checked_at = 0").Delete(&models.Purchase{}).Error; err != nil {
+		return fmt.Errorf("failed to fill in checked times: %w", err)
+	}
+
+	if err := db.backfillPurchases(); err != nil {
+		return fmt.Errorf("failed to record purchases: %w", err)
+	}
+
+	if err := db.assignListOwners(); err != nil {
+		return fmt.Errorf("failed to assign list owners: %w", err)
+	}
+
+	// Full-text indexes are SQLite virtual tables GORM doesn't manage
+	return db.migrateSearch()
+}
+
+// backfillPurchases records the priced items checked off before purchases
+// were kept as bought by whoever checked them, when they were checked
+func (db *DB) backfillPurchases() error {
+	var items []models.Item
+	err := db.Where("checked = ? AND price IS NOT NULL AND checked_at IS NOT NULL", true).
+		Where("NOT EXISTS (SELECT 1 FROM purchases p WHERE p.item_id = items.id AND p.checked_at = items.checked_at)").
+		Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return err
+	}
+
+	purchases := make([]models.Purchase, len(items))
+	for i, item := range items {
+		purchases[i] = models.Purchase{
+			ID:            auth.GenerateID(),
+			ItemID:        &items[i].ID,
+			ListID:        item.ListID,
+			Name:          item.Name,
+			Quantity:      item.Quantity,
+			Unit:          item.Unit,
+			Price:         *item.Price,
+			CategoryID:    item.CategoryID,
+			Store:         item.Store,
+			CheckedBy:     item.CheckedBy,
+			CheckedByName: item.CheckedByName,
+			CheckedAt:     *item.CheckedAt,
+		}
+	}
+	return db.CreateInBatches(purchases, 100).Error
+}
+
+// normalizePriceHistoryNames fills in the normalized name of prices recorded
+// before it was stored
+func (db *DB) normalizePriceHistoryNames() error {
+	var rows []models.PriceHistory
+	if err := db.Select("id", "item_name").Where("normalized_name = ''").Find(&rows).Error; err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			if err := tx.Model(&models.PriceHistory{}).Where("id = ?", row.ID).
+				Update("normalized_name", models.NormalizeItemName(row.ItemName)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // Close closes the database connection
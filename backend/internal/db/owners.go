@@ -0,0 +1,72 @@
+package db
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+// MemberRankOrder sorts list members owners first, then editors, then
+// viewers, earliest first within a role
+const MemberRankOrder = "CASE role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, created_at ASC"
+
+// assignListOwners gives every list without an owner a concrete one. Lists
+// left behind by a deleted user go to their highest ranking member. Lists
+// from before sharing, which have no members, were shared with the whole
+// household: they go to the first admin, or the first user, and everyone
+// else becomes an editor. Lists stay ownerless until there is a user.
+func (db *DB) assignListOwners() error {
+	var lists []models.List
+	if err := db.Select("id").Where("owner_id IS NULL").Find(&lists).Error; err != nil {
+		return err
+	}
+	if len(lists) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	if err := db.Select("id").Order("is_admin DESC, created_at ASC").Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	now := auth.GetCurrentTimestamp()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, list := range lists {
+			var members []models.ListMember
+			if err := tx.Where("list_id = ?", list.ID).Order(MemberRankOrder).Find(&members).Error; err != nil {
+				return err
+			}
+
+			var ownerID string
+			if len(members) > 0 {
+				ownerID = members[0].UserID
+				if err := tx.Model(&models.ListMember{}).Where("id = ?", members[0].ID).
+					UpdateColumn("role", models.ListRoleOwner).Error; err != nil {
+					return err
+				}
+			} else {
+				ownerID = users[0].ID
+				for i, user := range users {
+					role := models.ListRoleEditor
+					if i == 0 {
+						role = models.ListRoleOwner
+					}
+					member := models.ListMember{ID: auth.GenerateID(), ListID: list.ID, UserID: user.ID, Role: role, CreatedAt: now}
+					if err := tx.Create(&member).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := tx.Model(&models.List{}).Where("id = ?", list.ID).
+				UpdateColumn("owner_id", ownerID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
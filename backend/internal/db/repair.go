@@ -0,0 +1,69 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+// orphansRepairedVersion is the schema version, stored as SQLite's
+// user_version, from which deleting a category moves what used it to Other.
+// Orphaned categories can only predate it.
+const orphansRepairedVersion = 1
+
+// RepairOrphanedCategories points items, and the template, pantry and recipe
+// entries copied to and from them, whose category no longer exists, or never
+// did (older versions stored "other"), at the Other category. It runs once,
+// on databases from before orphansRepairedVersion. It returns how many items
+// were repaired.
+func (db *DB) RepairOrphanedCategories() (int64, error) {
+	var repaired int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var version int
+		if err := tx.Raw("PRAGMA user_version").Scan(&version).Error; err != nil {
+			return err
+		}
+		if version >= orphansRepairedVersion {
+			return nil
+		}
+
+		known := tx.Model(&models.Category{}).Select("id")
+		var err error
+		repaired, err = MoveToOtherCategory(tx, "category_id NOT IN (?)", known)
+		if err != nil {
+			return err
+		}
+		// PRAGMA takes no bound parameters
+		return tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", orphansRepairedVersion)).Error
+	})
+	return repaired, err
+}
+
+// MoveToOtherCategory points the items, and the template, pantry, recipe and
+// purchase entries, whose category matches condition at the Other category. Moved
+// items get a new version so clients pick up the change. It returns how many
+// items were moved.
+func MoveToOtherCategory(tx *gorm.DB, condition string, args ...interface{}) (int64, error) {
+	result := tx.Model(&models.Item{}).
+		Where(condition, args...).
+		Updates(map[string]interface{}{
+			"category_id": OtherCategoryID,
+			"version":     gorm.Expr("version + 1"),
+			"updated_at":  auth.GetCurrentTimestamp(),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	for _, model := range []interface{}{&models.TemplateItem{}, &models.PantryItem{}, &models.RecipeIngredient{}, &models.Purchase{}} {
+		if err := tx.Model(model).
+			Where(condition, args...).
+			Update("category_id", OtherCategoryID).Error; err != nil {
+			return 0, err
+		}
+	}
+	return result.RowsAffected, nil
+}
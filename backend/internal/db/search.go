@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// searchIndexes are the FTS5 tables kept in step with their table by
+// triggers. Each stores the row's id, unindexed, followed by the named
+// columns. The tables have text primary keys whose implicit rowid VACUUM may
+// renumber, so rows are matched on id rather than rowid.
+var searchIndexes = []struct {
+	name    string
+	table   string
+	columns []string
+}{
+	{name: "items_fts", table: "items", columns: []string{"name"}},
+	{name: "lists_fts", table: "lists", columns: []string{"name"}},
+}
+
+// migrateSearch creates the full-text indexes and their triggers. An index
+// that did not exist yet is filled from its table; one from before ids were
+// stored, which relied on the rowid, is replaced.
+func (db *DB) migrateSearch() error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, index := range searchIndexes {
+			columns := strings.Join(index.columns, ", ")
+			var schemas []string
+			if err := tx.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", index.name).
+				Scan(&schemas).Error; err != nil {
+				return err
+			}
+
+			var statements []string
+			if len(schemas) > 0 && strings.Contains(schemas[0], "content_rowid") {
+				statements = append(statements,
+					fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ai`, index.name),
+					fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ad`, index.name),
+					fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_au`, index.name),
+					fmt.Sprintf(`DROP TABLE %s`, index.name),
+				)
+				schemas = nil
+			}
+
+			statements = append(statements,
+				fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %[1]s USING fts5(id UNINDEXED, %[2]s, tokenize='unicode61 remove_diacritics 2')`,
+					index.name, columns),
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ai AFTER INSERT ON %[2]s BEGIN
+					INSERT INTO %[1]s(id, %[3]s) VALUES (new.id, %[4]s);
+				END`, index.name, index.table, columns, prefixed("new", index.columns)),
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ad AFTER DELETE ON %[2]s BEGIN
+					DELETE FROM %[1]s WHERE id = old.id;
+				END`, index.name, index.table),
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_au AFTER UPDATE OF %[3]s ON %[2]s BEGIN
+					DELETE FROM %[1]s WHERE id = old.id;
+					INSERT INTO %[1]s(id, %[3]s) VALUES (new.id, %[4]s);
+				END`, index.name, index.table, columns, prefixed("new", index.columns)),
+			)
+			if len(schemas) == 0 {
+				statements = append(statements, fmt.Sprintf(`INSERT INTO %[1]s(id, %[3]s) SELECT id, %[3]s FROM %[2]s`,
+					index.name, index.table, columns))
+			}
+
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return fmt.Errorf("failed to set up %s: %w", index.name, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// prefixed turns [name store] into "new.name, new.store"
+func prefixed(prefix string, columns []string) string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = prefix + "." + column
+	}
+	return strings.Join(names, ", ")
+}
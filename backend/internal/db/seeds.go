@@ -6,6 +6,9 @@ import (
 	"github.com/kleyson/groceries/backend/internal/models"
 )
 
+// OtherCategoryID is the seeded catch-all category for items without a better fit
+const OtherCategoryID = "10OTHER00000000000000000000"
+
 // DefaultCategories are the preset grocery categories
 var DefaultCategories = []models.Category{
 	{ID: "01PRODUCE000000000000000000", Name: "Produce", Icon: "shopping-bag", Color: "#22C55E", SortOrder: 0, IsDefault: true},
@@ -17,7 +20,7 @@ var DefaultCategories = []models.Category{
 	{ID: "07SNACKS0000000000000000000", Name: "Snacks", Icon: "zap", Color: "#EC4899", SortOrder: 6, IsDefault: true},
 	{ID: "08PANTRY0000000000000000000", Name: "Pantry", Icon: "archive", Color: "#78716C", SortOrder: 7, IsDefault: true},
 	{ID: "09HOUSEHOLD00000000000000000", Name: "Household", Icon: "home", Color: "#6366F1", SortOrder: 8, IsDefault: true},
-	{ID: "10OTHER00000000000000000000", Name: "Other", Icon: "package", Color: "#94A3B8", SortOrder: 9, IsDefault: true},
+	{ID: OtherCategoryID, Name: "Other", Icon: "package", Color: "#94A3B8", SortOrder: 9, IsDefault: true},
 }
 
 // Seed populates the database with default data
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+	"github.com/kleyson/groceries/backend/internal/webhook"
+)
+
+// EventBudgetReached is the webhook event for a budget reaching a threshold
+const EventBudgetReached = "budget.reached"
+
+// budgetThresholds are the shares of a budget, in percent, that alert,
+// highest first
+var budgetThresholds = []int{100, 80}
+
+// budgetPayload is the webhook data for EventBudgetReached
+type budgetPayload struct {
+	Threshold int `json:"threshold"`
+	models.BudgetStatus
+}
+
+// NotifyBudgets returns a job that sends a budget to hook when this month's
+// spending reaches 80% and again at 100% of it. Each threshold is sent once
+// a month; when spending jumps past both at once only 100% is sent.
+func NotifyBudgets(budgetRepo *repository.BudgetRepository, hook *webhook.Webhook) func(now time.Time) error {
+	return func(now time.Time) error {
+		statuses, err := budgetRepo.Status(now)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			threshold := reachedThreshold(status.Percent)
+			if threshold <= status.Alerted {
+				continue
+			}
+			if err := hook.Send(EventBudgetReached, budgetPayload{Threshold: threshold, BudgetStatus: status}); err != nil {
+				return err
+			}
+			if err := budgetRepo.MarkAlerted(status.BudgetID, status.Month, threshold); err != nil {
+				return err
+			}
+			log.Printf("Sent budget alert for %d%% of budget %s", threshold, status.BudgetID)
+		}
+		return nil
+	}
+}
+
+// reachedThreshold returns the highest threshold percent has reached, or 0
+func reachedThreshold(percent float64) int {
+	for _, threshold := range budgetThresholds {
+		if percent >= float64(threshold) {
+			return threshold
+		}
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/repository"
+	"github.com/kleyson/groceries/backend/internal/webhook"
+)
+
+// EventItemsExpiring is the webhook event for the daily expiry reminder
+const EventItemsExpiring = "items.expiring"
+
+// expiryLastSent is the job state holding the last day the reminder was done
+const expiryLastSent = "expiry.last_sent"
+
+// expiringPayload is the webhook data for EventItemsExpiring
+type expiringPayload struct {
+	Days int `json:"days"`
+	models.ExpiringReport
+}
+
+// NotifyExpiring returns a job, meant to run hourly, that sends the pantry
+// items and checked list items expiring within days to hook once a day, on
+// the first run from hour on, server local time. Nothing is sent on days with
+// nothing expiring. A day only counts as done once the webhook took the
+// reminder, so a failed send is retried on the next run and a restart
+// doesn't send it twice.
+func NotifyExpiring(pantryRepo *repository.PantryRepository, itemRepo *repository.ItemRepository, jobStateRepo *repository.JobStateRepository, hook *webhook.Webhook, days, hour int) func(now time.Time) error {
+	return func(now time.Time) error {
+		if now.Hour() < hour {
+			return nil
+		}
+		today := now.Format(time.DateOnly)
+		lastSent, err := jobStateRepo.Get(expiryLastSent)
+		if err != nil {
+			return err
+		}
+		if lastSent == today {
+			return nil
+		}
+
+		report := models.ExpiringReport{Until: now.AddDate(0, 0, days).UnixMilli()}
+		if report.Pantry, err = pantryRepo.GetExpiring(report.Until); err != nil {
+			return err
+		}
+		if report.Items, err = itemRepo.GetExpiring(report.Until); err != nil {
+			return err
+		}
+
+		if len(report.Pantry) == 0 && len(report.Items) == 0 {
+			return jobStateRepo.Set(expiryLastSent, today)
+		}
+		if err := hook.Send(EventItemsExpiring, expiringPayload{Days: days, ExpiringReport: report}); err != nil {
+			return err
+		}
+		log.Printf("Sent expiry reminder for %d pantry items and %d list items", len(report.Pantry), len(report.Items))
+		return jobStateRepo.Set(expiryLastSent, today)
+	}
+}
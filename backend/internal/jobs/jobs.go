@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunEvery calls fn once immediately and then on every tick of interval until
+// ctx is cancelled. Errors are logged and do not stop the job.
+func RunEvery(ctx context.Context, name string, interval time.Duration, fn func(now time.Time) error) {
+	run := func(now time.Time) {
+		if err := fn(now); err != nil {
+			log.Printf("Job %s failed: %v", name, err)
+		}
+	}
+
+	run(time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			run(now)
+		}
+	}
+}
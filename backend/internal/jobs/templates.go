@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/realtime"
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// RunScheduledTemplates returns a job that instantiates templates whose
+// weekly schedule is due. A template with a target list merges into it; one
+// without, or whose owner can no longer edit the target list, creates a new
+// list owned by the template's owner. Changes are published to hub and
+// recorded in the list's history as made by the owner.
+func RunScheduledTemplates(templateRepo *repository.TemplateRepository, memberRepo *repository.ListMemberRepository, userRepo *repository.UserRepository, activityRepo *repository.ActivityRepository, hub *realtime.Hub) func(now time.Time) error {
+	return func(now time.Time) error {
+		due, err := templateRepo.GetDue(now.UnixMilli())
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			template := &due[i]
+			owner, err := userRepo.GetByID(template.OwnerID)
+			if err != nil {
+				log.Printf("Scheduled template %s failed: %v", template.ID, err)
+				continue
+			}
+			listID, created, merged, err := runTemplate(templateRepo, memberRepo, template, now)
+			if err != nil {
+				log.Printf("Scheduled template %s failed: %v", template.ID, err)
+				continue
+			}
+			announceTemplate(hub, activityRepo, owner, listID, created, merged, now)
+			// Schedule from now so a server that was down does not replay missed weeks
+			if err := templateRepo.MarkRun(template.ID, now.UnixMilli(), template.NextRunAfter(now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// runTemplate instantiates template and returns the list it went into with
+// the items it created there and the ones it merged into
+func runTemplate(templateRepo *repository.TemplateRepository, memberRepo *repository.ListMemberRepository, template *models.Template, now time.Time) (string, []models.Item, []repository.MergedItem, error) {
+	if template.ScheduleListID != nil {
+		listID := *template.ScheduleListID
+		role, err := memberRepo.GetRole(listID, template.OwnerID)
+		if err == nil && role.AtLeast(models.ListRoleEditor) {
+			created, merged, err := templateRepo.InstantiateInto(template, listID)
+			if !errors.Is(err, repository.ErrListNotFound) {
+				return listID, created, merged, err
+			}
+		} else if err != nil && !errors.Is(err, repository.ErrListNotFound) && !errors.Is(err, repository.ErrMemberNotFound) {
+			return "", nil, nil, err
+		}
+	}
+
+	list := &models.List{
+		ID:        auth.GenerateID(),
+		Name:      template.Name,
+		OwnerID:   &template.OwnerID,
+		CreatedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+	}
+	created, err := templateRepo.InstantiateNew(template, list)
+	return list.ID, created, nil, err
+}
+
+// announceTemplate publishes the items a run created and merged into and
+// records them in the list's history as one change. History is best effort.
+func announceTemplate(hub *realtime.Hub, activityRepo *repository.ActivityRepository, owner *models.User, listID string, created []models.Item, merged []repository.MergedItem, now time.Time) {
+	groupID := auth.GenerateID()
+	record := func(action string, item *models.Item, before, after interface{}) {
+		activity := models.NewActivity(owner, listID, action, item, before, after, now.UnixMilli())
+		activity.GroupID = &groupID
+		_ = activityRepo.Create(activity)
+	}
+	for i := range created {
+		item := &created[i]
+		hub.Publish(realtime.Event{Type: realtime.ItemCreated, ListID: listID, Version: item.Version, Item: item})
+		record(models.ActivityItemCreated, item, nil, item)
+	}
+	for i := range merged {
+		item := &merged[i].After
+		hub.Publish(realtime.Event{Type: realtime.ItemUpdated, ListID: listID, Version: item.Version, Item: item})
+		record(models.ActivityItemUpdated, item, merged[i].Before, item)
+	}
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/repository"
+)
+
+// PurgeTrash returns a job that permanently deletes lists and items that
+// have been in the trash for longer than retention
+func PurgeTrash(listRepo *repository.ListRepository, itemRepo *repository.ItemRepository, retention time.Duration) func(now time.Time) error {
+	return func(now time.Time) error {
+		cutoff := now.Add(-retention).UnixMilli()
+
+		lists, err := listRepo.PurgeDeletedBefore(cutoff)
+		if err != nil {
+			return err
+		}
+		items, err := itemRepo.PurgeDeletedBefore(cutoff)
+		if err != nil {
+			return err
+		}
+
+		if lists > 0 || items > 0 {
+			log.Printf("Purged %d lists and %d items from the trash", lists, items)
+		}
+		return nil
+	}
+}
@@ -1,5 +1,14 @@
 package models
 
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/units"
+)
+
 // User represents a registered user
 type User struct {
 	ID           string `json:"id" gorm:"primaryKey;size:26"`
@@ -27,27 +36,83 @@ type Category struct {
 	Color     string `json:"color" gorm:"size:20;not null"`
 	SortOrder int    `json:"sortOrder" gorm:"column:sort_order;default:0;not null"`
 	IsDefault bool   `json:"isDefault" gorm:"column:is_default;default:false;not null"`
+	UpdatedAt int64  `json:"updatedAt" gorm:"column:updated_at;index;default:0;not null"`
 }
 
-// List represents a grocery list
+// List represents a grocery list.
+// Every list has an owner; lists from before per-list sharing are given one
+// by a migration.
+// Lists with DeletedAt set are in the trash until restored or purged.
 type List struct {
-	ID        string `json:"id" gorm:"primaryKey;size:26"`
-	Name      string `json:"name" gorm:"size:200;not null"`
-	Version   int    `json:"version" gorm:"default:1;not null"`
-	CreatedAt int64  `json:"createdAt" gorm:"column:created_at;not null"`
-	UpdatedAt int64  `json:"updatedAt" gorm:"column:updated_at;not null"`
-	Items     []Item `json:"-" gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
+	ID        string       `json:"id" gorm:"primaryKey;size:26"`
+	Name      string       `json:"name" gorm:"size:200;not null"`
+	OwnerID   *string      `json:"ownerId" gorm:"column:owner_id;index;size:26"`
+	Version   int          `json:"version" gorm:"default:1;not null"`
+	CreatedAt int64        `json:"createdAt" gorm:"column:created_at;not null"`
+	UpdatedAt int64        `json:"updatedAt" gorm:"column:updated_at;not null"`
+	DeletedAt *int64       `json:"deletedAt,omitempty" gorm:"column:deleted_at;index"`
+	Items     []Item       `json:"-" gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
+	Members   []ListMember `json:"-" gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
 }
 
 // ListWithCounts includes item statistics (not a GORM model, used for queries)
 type ListWithCounts struct {
 	List
-	TotalItems   int     `json:"totalItems"`
-	CheckedItems int     `json:"checkedItems"`
-	TotalPrice   float64 `json:"totalPrice"`
+	TotalItems   int      `json:"totalItems"`
+	CheckedItems int      `json:"checkedItems"`
+	TotalPrice   float64  `json:"totalPrice"`
+	Role         ListRole `json:"role,omitempty"`
+
+	// Budgets is how this month's household budgets stand, in list
+	// responses only
+	Budgets []BudgetStatus `json:"budgets,omitempty" gorm:"-"`
+}
+
+// ListRole is a user's permission level on a list
+type ListRole string
+
+const (
+	ListRoleViewer ListRole = "viewer"
+	ListRoleEditor ListRole = "editor"
+	ListRoleOwner  ListRole = "owner"
+)
+
+var listRoleRank = map[ListRole]int{
+	ListRoleViewer: 1,
+	ListRoleEditor: 2,
+	ListRoleOwner:  3,
 }
 
-// Item represents a grocery item in a list
+// Valid reports whether the role is one of the known roles
+func (r ListRole) Valid() bool {
+	return listRoleRank[r] > 0
+}
+
+// AtLeast reports whether the role grants the permissions of required
+func (r ListRole) AtLeast(required ListRole) bool {
+	return r.Valid() && listRoleRank[r] >= listRoleRank[required]
+}
+
+// ListMember grants a user access to a list
+type ListMember struct {
+	ID        string   `json:"id" gorm:"primaryKey;size:26"`
+	ListID    string   `json:"listId" gorm:"column:list_id;uniqueIndex:idx_list_members_list_user;size:26;not null"`
+	UserID    string   `json:"userId" gorm:"column:user_id;uniqueIndex:idx_list_members_list_user;index;size:26;not null"`
+	User      *User    `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Role      ListRole `json:"role" gorm:"size:20;not null"`
+	CreatedAt int64    `json:"createdAt" gorm:"column:created_at;not null"`
+}
+
+// ListMemberWithUser includes the member's user details (not a GORM model, used for queries)
+type ListMemberWithUser struct {
+	ListMember
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// Item represents a grocery item in a list.
+// Items with DeletedAt set are in the trash until restored or purged.
+// Price is per unit of Quantity and buys PackageSize of PackageUnit when set.
 type Item struct {
 	ID            string    `json:"id" gorm:"primaryKey;size:26"`
 	ListID        string    `json:"listId" gorm:"column:list_id;index;size:26;not null"`
@@ -61,19 +126,374 @@ type Item struct {
 	CheckedBy     *string   `json:"checkedBy" gorm:"column:checked_by;size:26"`
 	CheckedByUser *User     `json:"-" gorm:"foreignKey:CheckedBy"`
 	CheckedByName *string   `json:"checkedByName" gorm:"column:checked_by_name;size:200"`
+	CheckedAt     *int64    `json:"checkedAt" gorm:"column:checked_at;index"`
 	Price         *float64  `json:"price"`
+	PackageSize   *float64  `json:"packageSize" gorm:"column:package_size"`
+	PackageUnit   *string   `json:"packageUnit" gorm:"column:package_unit;size:50"`
 	Store         *string   `json:"store" gorm:"size:200"`
+	ExpiresAt     *int64    `json:"expiresAt,omitempty" gorm:"column:expires_at;index"`
 	SortOrder     int       `json:"sortOrder" gorm:"column:sort_order;default:0;not null"`
 	Version       int       `json:"version" gorm:"default:1;not null"`
+	UpdatedAt     int64     `json:"updatedAt" gorm:"column:updated_at;index;default:0;not null"`
+	DeletedAt     *int64    `json:"deletedAt,omitempty" gorm:"column:deleted_at;index"`
+
+	// UnitPrice is computed from the price and package size, in responses only
+	UnitPrice *UnitPrice `json:"unitPrice,omitempty" gorm:"-"`
 }
 
-// PriceHistory tracks historical prices for items
-type PriceHistory struct {
+// SetUnitPrice computes UnitPrice
+func (i *Item) SetUnitPrice() {
+	i.UnitPrice = unitPriceOf(i.Price, i.PackageSize, i.PackageUnit, i.Unit)
+}
+
+// UnitPrice is a price per kg, l or piece, for comparing packages of
+// different sizes
+type UnitPrice struct {
+	Price float64 `json:"price"`
+	Per   string  `json:"per"`
+}
+
+// unitPriceOf works out the unit price of something costing price. With a
+// package size the price buys that much of packageUnit; without one it buys
+// one unit, which only says something when there is a unit.
+func unitPriceOf(price, packageSize *float64, packageUnit, unit *string) *UnitPrice {
+	if price == nil {
+		return nil
+	}
+
+	amount, in := 1.0, ""
+	switch {
+	case packageSize != nil:
+		amount = *packageSize
+		if packageUnit != nil {
+			in = *packageUnit
+		}
+	case unit != nil && *unit != "":
+		in = *unit
+	default:
+		return nil
+	}
+
+	perUnit, per, ok := units.UnitPrice(*price, amount, in)
+	if !ok {
+		return nil
+	}
+	return &UnitPrice{Price: math.Round(perUnit*100) / 100, Per: per}
+}
+
+// Template is a saved list that can be turned into a new list, or merged into
+// an existing one, by hand or on a weekly schedule. Templates belong to the
+// user who saved them.
+type Template struct {
+	ID        string         `json:"id" gorm:"primaryKey;size:26"`
+	Name      string         `json:"name" gorm:"size:100;not null"`
+	OwnerID   string         `json:"ownerId" gorm:"column:owner_id;index;size:26;not null"`
+	Owner     *User          `json:"-" gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE"`
+	CreatedAt int64          `json:"createdAt" gorm:"column:created_at;not null"`
+	UpdatedAt int64          `json:"updatedAt" gorm:"column:updated_at;not null"`
+	Items     []TemplateItem `json:"items" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+
+	// Scheduled runs merge into ScheduleListID when set and create a new list otherwise
+	ScheduleWeekday *int    `json:"scheduleWeekday" gorm:"column:schedule_weekday"`
+	ScheduleHour    int     `json:"scheduleHour" gorm:"column:schedule_hour;default:0;not null"`
+	ScheduleListID  *string `json:"scheduleListId" gorm:"column:schedule_list_id;size:26"`
+	NextRunAt       *int64  `json:"nextRunAt" gorm:"column:next_run_at;index"`
+	LastRunAt       *int64  `json:"lastRunAt" gorm:"column:last_run_at"`
+}
+
+// NextRunAfter returns when a scheduled template should next run after t, in
+// t's time zone, or nil when the template has no schedule
+func (t *Template) NextRunAfter(after time.Time) *int64 {
+	if t.ScheduleWeekday == nil {
+		return nil
+	}
+
+	next := time.Date(after.Year(), after.Month(), after.Day(), t.ScheduleHour, 0, 0, 0, after.Location())
+	days := (*t.ScheduleWeekday - int(next.Weekday()) + 7) % 7
+	next = next.AddDate(0, 0, days)
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 7)
+	}
+
+	ms := next.UnixMilli()
+	return &ms
+}
+
+// TemplateItem is an item saved in a template
+type TemplateItem struct {
+	ID          string   `json:"id" gorm:"primaryKey;size:26"`
+	TemplateID  string   `json:"templateId" gorm:"column:template_id;index;size:26;not null"`
+	Name        string   `json:"name" gorm:"size:200;not null"`
+	Quantity    int      `json:"quantity" gorm:"default:1;not null"`
+	Unit        *string  `json:"unit" gorm:"size:50"`
+	CategoryID  string   `json:"categoryId" gorm:"column:category_id;size:26;not null"`
+	Price       *float64 `json:"price"`
+	PackageSize *float64 `json:"packageSize" gorm:"column:package_size"`
+	PackageUnit *string  `json:"packageUnit" gorm:"column:package_unit;size:50"`
+	Store       *string  `json:"store" gorm:"size:200"`
+	SortOrder   int      `json:"sortOrder" gorm:"column:sort_order;default:0;not null"`
+}
+
+// Recipe is a recipe shared by the household. Ingredient quantities are for
+// Servings servings.
+type Recipe struct {
+	ID          string             `json:"id" gorm:"primaryKey;size:26"`
+	Name        string             `json:"name" gorm:"size:200;not null"`
+	Description *string            `json:"description" gorm:"type:text"`
+	Servings    int                `json:"servings" gorm:"default:1;not null"`
+	CreatedBy   *string            `json:"createdBy" gorm:"column:created_by;size:26"`
+	Creator     *User              `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	CreatedAt   int64              `json:"createdAt" gorm:"column:created_at;not null"`
+	UpdatedAt   int64              `json:"updatedAt" gorm:"column:updated_at;not null"`
+	Ingredients []RecipeIngredient `json:"ingredients" gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE"`
+	Steps       []RecipeStep       `json:"steps" gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE"`
+}
+
+// RecipeIngredient is one ingredient of a recipe
+type RecipeIngredient struct {
 	ID         string  `json:"id" gorm:"primaryKey;size:26"`
-	ItemName   string  `json:"itemName" gorm:"column:item_name;index;size:200;not null"`
-	Price      float64 `json:"price" gorm:"not null"`
-	Store      *string `json:"store" gorm:"size:200"`
-	RecordedAt int64   `json:"recordedAt" gorm:"column:recorded_at;not null"`
+	RecipeID   string  `json:"recipeId" gorm:"column:recipe_id;index;size:26;not null"`
+	Name       string  `json:"name" gorm:"size:200;not null"`
+	Quantity   float64 `json:"quantity" gorm:"default:0;not null"`
+	Unit       *string `json:"unit" gorm:"size:50"`
+	CategoryID string  `json:"categoryId" gorm:"column:category_id;size:26;not null"`
+	SortOrder  int     `json:"sortOrder" gorm:"column:sort_order;default:0;not null"`
+}
+
+// RecipeStep is one step of a recipe's method
+type RecipeStep struct {
+	ID       string `json:"id" gorm:"primaryKey;size:26"`
+	RecipeID string `json:"recipeId" gorm:"column:recipe_id;index;size:26;not null"`
+	Position int    `json:"position" gorm:"default:0;not null"`
+	Text     string `json:"text" gorm:"type:text;not null"`
+}
+
+// PantryItem is something the household has at home. When LowStockThreshold
+// is set and the quantity falls to it or below, the item is added to
+// RestockListID.
+type PantryItem struct {
+	ID                string    `json:"id" gorm:"primaryKey;size:26"`
+	Name              string    `json:"name" gorm:"size:200;not null"`
+	Quantity          int       `json:"quantity" gorm:"default:0;not null"`
+	Unit              *string   `json:"unit" gorm:"size:50"`
+	Location          *string   `json:"location" gorm:"size:100;index"`
+	CategoryID        string    `json:"categoryId" gorm:"column:category_id;index;size:26;not null;default:'10OTHER00000000000000000000'"`
+	Category          *Category `json:"-" gorm:"foreignKey:CategoryID"`
+	ExpiresAt         *int64    `json:"expiresAt" gorm:"column:expires_at;index"`
+	LowStockThreshold *int      `json:"lowStockThreshold" gorm:"column:low_stock_threshold"`
+	RestockListID     *string   `json:"restockListId" gorm:"column:restock_list_id;size:26"`
+	RestockList       *List     `json:"-" gorm:"foreignKey:RestockListID;constraint:OnDelete:SET NULL"`
+	CreatedAt         int64     `json:"createdAt" gorm:"column:created_at;not null"`
+	UpdatedAt         int64     `json:"updatedAt" gorm:"column:updated_at;not null"`
+
+	// Restocked is the shopping list item a change added, in responses only
+	Restocked *Item `json:"restocked,omitempty" gorm:"-"`
+}
+
+// IsLow reports whether the item has fallen to its low-stock threshold
+func (p *PantryItem) IsLow() bool {
+	return p.LowStockThreshold != nil && p.Quantity <= *p.LowStockThreshold
+}
+
+// Meal plan slots, in the order they come in a day
+const (
+	MealSlotBreakfast = "breakfast"
+	MealSlotLunch     = "lunch"
+	MealSlotDinner    = "dinner"
+	MealSlotSnack     = "snack"
+)
+
+// MealPlanEntry is a meal planned for a day and slot, either a recipe cooked
+// for Servings servings or free text such as "Leftovers". Shared by the
+// household.
+type MealPlanEntry struct {
+	ID        string  `json:"id" gorm:"primaryKey;size:26"`
+	Date      string  `json:"date" gorm:"size:10;index;not null"`
+	Slot      string  `json:"slot" gorm:"size:20;not null"`
+	RecipeID  *string `json:"recipeId" gorm:"column:recipe_id;index;size:26"`
+	Recipe    *Recipe `json:"recipe,omitempty" gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE"`
+	Text      *string `json:"text" gorm:"size:200"`
+	Servings  int     `json:"servings" gorm:"default:0;not null"`
+	CreatedBy *string `json:"createdBy" gorm:"column:created_by;size:26"`
+	Creator   *User   `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL"`
+	CreatedAt int64   `json:"createdAt" gorm:"column:created_at;not null"`
+	UpdatedAt int64   `json:"updatedAt" gorm:"column:updated_at;not null"`
+}
+
+// Budget is a monthly spending limit for the household, over everything or
+// over one category when CategoryID is set. AlertMonth and AlertPercent
+// record the last threshold the budget webhook was sent for.
+type Budget struct {
+	ID           string    `json:"id" gorm:"primaryKey;size:26"`
+	CategoryID   *string   `json:"categoryId" gorm:"column:category_id;uniqueIndex;size:26"`
+	Category     *Category `json:"-" gorm:"foreignKey:CategoryID;constraint:OnDelete:CASCADE"`
+	Amount       float64   `json:"amount" gorm:"not null"`
+	AlertMonth   string    `json:"-" gorm:"column:alert_month;size:7;default:'';not null"`
+	AlertPercent int       `json:"-" gorm:"column:alert_percent;default:0;not null"`
+	CreatedAt    int64     `json:"createdAt" gorm:"column:created_at;not null"`
+	UpdatedAt    int64     `json:"updatedAt" gorm:"column:updated_at;not null"`
+}
+
+// JobState is a value a background job keeps across restarts, such as the
+// day a reminder was last sent
+type JobState struct {
+	Name      string `gorm:"primaryKey;size:50"`
+	Value     string `gorm:"size:200;not null"`
+	UpdatedAt int64  `gorm:"column:updated_at;not null"`
+}
+
+// Purchase is a priced item as it was when checked off. Spending is counted
+// from purchases, so unchecking, editing, trashing or purging the item later
+// leaves what was spent alone. The list and user are kept as IDs so they
+// outlive the list and its members.
+type Purchase struct {
+	ID            string  `json:"id" gorm:"primaryKey;size:26"`
+	ItemID        *string `json:"itemId" gorm:"column:item_id;index;size:26"`
+	Item          *Item   `json:"-" gorm:"foreignKey:ItemID;constraint:OnDelete:SET NULL"`
+	ListID        string  `json:"listId" gorm:"column:list_id;index;size:26;not null"`
+	Name          string  `json:"name" gorm:"size:200;not null"`
+	Quantity      int     `json:"quantity" gorm:"not null"`
+	Unit          *string `json:"unit" gorm:"size:50"`
+	Price         float64 `json:"price" gorm:"not null"`
+	CategoryID    string  `json:"categoryId" gorm:"column:category_id;index;size:26;not null"`
+	Store         *string `json:"store" gorm:"size:200"`
+	CheckedBy     *string `json:"checkedBy" gorm:"column:checked_by;size:26"`
+	CheckedByName *string `json:"checkedByName" gorm:"column:checked_by_name;size:200"`
+	CheckedAt     int64   `json:"checkedAt" gorm:"column:checked_at;index;not null"`
+}
+
+// PriceHistory tracks historical prices for items. Price is per Unit, or per
+// item without one, and buys PackageSize of PackageUnit when that is known.
+// Prices recorded when an item was checked off keep its ID.
+type PriceHistory struct {
+	ID             string   `json:"id" gorm:"primaryKey;size:26"`
+	ItemName       string   `json:"itemName" gorm:"column:item_name;index;size:200;not null"`
+	NormalizedName string   `json:"-" gorm:"column:normalized_name;index;size:200;not null;default:''"`
+	Price          float64  `json:"price" gorm:"not null"`
+	Unit           *string  `json:"unit" gorm:"size:50"`
+	PackageSize    *float64 `json:"packageSize" gorm:"column:package_size"`
+	PackageUnit    *string  `json:"packageUnit" gorm:"column:package_unit;size:50"`
+	Store          *string  `json:"store" gorm:"size:200"`
+	ItemID         *string  `json:"itemId,omitempty" gorm:"column:item_id;index;size:26"`
+	Item           *Item    `json:"-" gorm:"foreignKey:ItemID;constraint:OnDelete:SET NULL"`
+	RecordedAt     int64    `json:"recordedAt" gorm:"column:recorded_at;not null"`
+
+	// UnitPrice is computed from the price and package size, in responses only
+	UnitPrice *UnitPrice `json:"unitPrice,omitempty" gorm:"-"`
+}
+
+// SetUnitPrice computes UnitPrice
+func (p *PriceHistory) SetUnitPrice() {
+	p.UnitPrice = unitPriceOf(&p.Price, p.PackageSize, p.PackageUnit, p.Unit)
+}
+
+// NormalizeItemName is the form item names are matched in across price
+// history: lower case with runs of whitespace collapsed
+func NormalizeItemName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Activity records one change to a list for its history and undo. Before and
+// After hold JSON snapshots of what changed: the item for item actions, the
+// item ID order for reorders and the name for renames. Undone activities keep
+// their entry with UndoneAt set. IDs increase with every entry so they give a
+// stable order even for changes made in the same millisecond. Entries written
+// by one action on several items share a GroupID and are undone together.
+type Activity struct {
+	ID        int64           `json:"id" gorm:"primaryKey;autoIncrement"`
+	ListID    string          `json:"listId" gorm:"column:list_id;index;size:26;not null"`
+	UserID    string          `json:"userId" gorm:"column:user_id;index;size:26;not null"`
+	UserName  string          `json:"userName" gorm:"column:user_name;size:200;not null"`
+	Action    string          `json:"action" gorm:"size:30;not null"`
+	ItemID    *string         `json:"itemId" gorm:"column:item_id;size:26"`
+	ItemName  *string         `json:"itemName" gorm:"column:item_name;size:200"`
+	Before    json.RawMessage `json:"before,omitempty" gorm:"type:text"`
+	After     json.RawMessage `json:"after,omitempty" gorm:"type:text"`
+	CreatedAt int64           `json:"createdAt" gorm:"column:created_at;not null"`
+	UndoneAt  *int64          `json:"undoneAt,omitempty" gorm:"column:undone_at"`
+	GroupID   *string         `json:"groupId,omitempty" gorm:"column:group_id;index;size:26"`
+}
+
+// Activity actions
+const (
+	ActivityItemCreated    = "item.created"
+	ActivityItemUpdated    = "item.updated"
+	ActivityItemChecked    = "item.checked"
+	ActivityItemUnchecked  = "item.unchecked"
+	ActivityItemDeleted    = "item.deleted"
+	ActivityItemRestored   = "item.restored"
+	ActivityItemsReordered = "items.reordered"
+	ActivityListRenamed    = "list.renamed"
+	// ActivityUndone records an undo; After holds the IDs of the activities
+	// it reverted. It cannot be undone itself.
+	ActivityUndone = "activity.undone"
+)
+
+// NewActivity builds an activity entry for a change made by user at now. item
+// may be nil for list-level actions; before and after are stored as JSON.
+func NewActivity(user *User, listID, action string, item *Item, before, after interface{}, now int64) *Activity {
+	activity := &Activity{
+		ListID:    listID,
+		UserID:    user.ID,
+		UserName:  user.Name,
+		Action:    action,
+		Before:    activitySnapshot(before),
+		After:     activitySnapshot(after),
+		CreatedAt: now,
+	}
+	if item != nil {
+		activity.ItemID = &item.ID
+		activity.ItemName = &item.Name
+	}
+	return activity
+}
+
+func activitySnapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// ActivityPage is a page of list activity, newest first. NextCursor is passed
+// as before to fetch the next page and is empty on the last page.
+type ActivityPage struct {
+	Activities []Activity `json:"activities"`
+	NextCursor int64      `json:"nextCursor,omitempty"`
+}
+
+// Tombstone records that an entity was deleted so delta sync clients can
+// drop their local copy
+type Tombstone struct {
+	ID         string  `json:"-" gorm:"primaryKey;size:26"`
+	EntityType string  `json:"entityType" gorm:"column:entity_type;size:20;not null"`
+	EntityID   string  `json:"entityId" gorm:"column:entity_id;size:26;not null"`
+	ListID     *string `json:"listId,omitempty" gorm:"column:list_id;size:26"`
+	DeletedAt  int64   `json:"deletedAt" gorm:"column:deleted_at;index;not null"`
+	// UserID is set when only that user lost the entity, by leaving its list
+	// or the items moving to a list they cannot see
+	UserID *string `json:"-" gorm:"column:user_id;index;size:26"`
+}
+
+// Tombstone entity types
+const (
+	EntityList     = "list"
+	EntityItem     = "item"
+	EntityCategory = "category"
+)
+
+// SyncOperation records a client operation applied through the sync endpoint
+// so that replaying the same batch returns the original result
+type SyncOperation struct {
+	ID         string `json:"id" gorm:"primaryKey;size:26"`
+	UserID     string `json:"userId" gorm:"column:user_id;uniqueIndex:idx_sync_operations_user_op;size:26;not null"`
+	ClientOpID string `json:"clientOpId" gorm:"column:client_op_id;uniqueIndex:idx_sync_operations_user_op;size:64;not null"`
+	Result     string `json:"-" gorm:"type:text;not null"`
+	CreatedAt  int64  `json:"createdAt" gorm:"column:created_at;index;not null"`
 }
 
 // CreateListRequest is the request body for creating a list
@@ -81,29 +501,244 @@ type CreateListRequest struct {
 	Name string `json:"name"`
 }
 
-// UpdateListRequest is the request body for updating a list
+// UpdateListRequest is the request body for updating a list.
+// Version is the version the client last saw; it may also be sent as If-Match.
 type UpdateListRequest struct {
-	Name string `json:"name"`
+	Name    string `json:"name"`
+	Version *int   `json:"version,omitempty"`
 }
 
-// CreateItemRequest is the request body for creating an item
+// DuplicateListRequest is the request body for copying a list. An empty name
+// names the copy after the original.
+type DuplicateListRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+// CarryOverRequest is the request body for moving a list's unchecked items.
+// When ListID is set they move into that list; otherwise into a new list
+// named Name, or after the original when Name is empty.
+type CarryOverRequest struct {
+	ListID *string `json:"listId,omitempty"`
+	Name   string  `json:"name,omitempty"`
+}
+
+// CarryOverResponse describes the list that received carried-over items
+type CarryOverResponse struct {
+	List  ListWithCounts `json:"list"`
+	Items []Item         `json:"items"`
+}
+
+// AddListMemberRequest is the request body for sharing a list with a user
+type AddListMemberRequest struct {
+	Username string   `json:"username"`
+	Role     ListRole `json:"role"`
+}
+
+// UpdateListMemberRequest is the request body for changing a member's role
+type UpdateListMemberRequest struct {
+	Role ListRole `json:"role"`
+}
+
+// CreateItemRequest is the request body for creating an item. Text is a
+// quick-add entry such as "2 kg chicken @ Costco $12.99" that fills in the
+// fields left empty.
 type CreateItemRequest struct {
-	Name       string   `json:"name"`
-	Quantity   int      `json:"quantity"`
-	Unit       *string  `json:"unit"`
-	CategoryID string   `json:"categoryId"`
-	Price      *float64 `json:"price"`
-	Store      *string  `json:"store"`
+	Name        string   `json:"name"`
+	Quantity    int      `json:"quantity"`
+	Unit        *string  `json:"unit"`
+	CategoryID  string   `json:"categoryId"`
+	Price       *float64 `json:"price"`
+	PackageSize *float64 `json:"packageSize,omitempty"`
+	PackageUnit *string  `json:"packageUnit,omitempty"`
+	Store       *string  `json:"store"`
+	Text        string   `json:"text,omitempty"`
+}
+
+// ParseItemsRequest is the request body for parsing quick-add text, one item
+// per line. With Create set the parsed items are also added to the list.
+type ParseItemsRequest struct {
+	Text   string `json:"text"`
+	Create bool   `json:"create,omitempty"`
 }
 
-// UpdateItemRequest is the request body for updating an item
+// ParseItemsResponse returns the parsed items and, when requested, the items
+// created from them
+type ParseItemsResponse struct {
+	Items   []CreateItemRequest `json:"items"`
+	Created []Item              `json:"created,omitempty"`
+}
+
+// UpdateItemRequest is the request body for updating an item.
+// Version is the version the client last saw; it may also be sent as If-Match.
+// A PackageSize of 0 clears the package size and unit.
 type UpdateItemRequest struct {
-	Name       *string  `json:"name,omitempty"`
-	Quantity   *int     `json:"quantity,omitempty"`
-	Unit       *string  `json:"unit,omitempty"`
-	CategoryID *string  `json:"categoryId,omitempty"`
-	Price      *float64 `json:"price,omitempty"`
-	Store      *string  `json:"store,omitempty"`
+	Version     *int     `json:"version,omitempty"`
+	Name        *string  `json:"name,omitempty"`
+	Quantity    *int     `json:"quantity,omitempty"`
+	Unit        *string  `json:"unit,omitempty"`
+	CategoryID  *string  `json:"categoryId,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	PackageSize *float64 `json:"packageSize,omitempty"`
+	PackageUnit *string  `json:"packageUnit,omitempty"`
+	Store       *string  `json:"store,omitempty"`
+}
+
+// ToggleItemRequest is the optional request body for toggling an item.
+// ExpiresAt is the best-before date of what was bought. AddToPantry adds the
+// item's quantity to the pantry when it gets checked, into Location when the
+// pantry doesn't have it yet; the best-before date then goes to the pantry
+// item instead.
+type ToggleItemRequest struct {
+	ExpiresAt   *int64  `json:"expiresAt,omitempty"`
+	AddToPantry bool    `json:"addToPantry,omitempty"`
+	Location    *string `json:"location,omitempty"`
+}
+
+// RecipeRequest is the request body for creating or replacing a recipe
+type RecipeRequest struct {
+	Name        string                    `json:"name"`
+	Description *string                   `json:"description,omitempty"`
+	Servings    int                       `json:"servings"`
+	Ingredients []RecipeIngredientRequest `json:"ingredients"`
+	Steps       []string                  `json:"steps"`
+}
+
+// RecipeIngredientRequest is one ingredient in a RecipeRequest. Without a
+// category one is suggested from the name.
+type RecipeIngredientRequest struct {
+	Name       string  `json:"name"`
+	Quantity   float64 `json:"quantity"`
+	Unit       *string `json:"unit,omitempty"`
+	CategoryID string  `json:"categoryId,omitempty"`
+}
+
+// AddRecipeToListRequest is the request body for adding a recipe's
+// ingredients to a list. Servings defaults to the recipe's own.
+type AddRecipeToListRequest struct {
+	ListID   string `json:"listId"`
+	Servings int    `json:"servings,omitempty"`
+}
+
+// ImportRecipeRequest is the request body for importing a recipe from a
+// pasted page or JSON-LD document. With a list ID the ingredients go straight
+// onto that list instead of being saved as a recipe.
+type ImportRecipeRequest struct {
+	Content  string `json:"content"`
+	ListID   string `json:"listId,omitempty"`
+	Servings int    `json:"servings,omitempty"`
+}
+
+// Spending report dimensions
+const (
+	SpendingByMonth    = "month"
+	SpendingByCategory = "category"
+	SpendingByStore    = "store"
+	SpendingByUser     = "user"
+)
+
+// SpendingReport totals the price times quantity of items bought from From
+// to To, as they were when checked off, in groups by the GroupBy dimensions
+type SpendingReport struct {
+	From    int64           `json:"from"`
+	To      int64           `json:"to"`
+	GroupBy []string        `json:"groupBy"`
+	Total   float64         `json:"total"`
+	Items   int             `json:"items"`
+	Groups  []SpendingGroup `json:"groups"`
+}
+
+// SpendingGroup is the spending for one combination of the grouped
+// dimensions. Only those fields are set; Store is also empty for items
+// bought without one.
+type SpendingGroup struct {
+	Month      string  `json:"month,omitempty"`
+	CategoryID string  `json:"categoryId,omitempty"`
+	Category   string  `json:"category,omitempty"`
+	Store      string  `json:"store,omitempty"`
+	UserID     string  `json:"userId,omitempty"`
+	UserName   string  `json:"userName,omitempty"`
+	Total      float64 `json:"total"`
+	Items      int     `json:"items"`
+}
+
+// BudgetStatus is what has been spent against a budget in Month, a
+// calendar month like 2024-03. Percent is Spent as a share of Amount.
+type BudgetStatus struct {
+	BudgetID   string  `json:"budgetId"`
+	CategoryID *string `json:"categoryId"`
+	Category   string  `json:"category,omitempty"`
+	Month      string  `json:"month"`
+	Amount     float64 `json:"amount"`
+	Spent      float64 `json:"spent"`
+	Remaining  float64 `json:"remaining"`
+	Percent    float64 `json:"percent"`
+
+	// Alerted is the highest threshold already sent for Month
+	Alerted int `json:"-"`
+}
+
+// BudgetRequest is the body for creating a budget; only Amount can be
+// updated
+type BudgetRequest struct {
+	CategoryID *string `json:"categoryId"`
+	Amount     float64 `json:"amount"`
+}
+
+// ExpiringReport lists what expires by Until, or already has: pantry items
+// in stock and checked list items, soonest first
+type ExpiringReport struct {
+	Until  int64        `json:"until"`
+	Pantry []PantryItem `json:"pantry"`
+	Items  []Item       `json:"items"`
+}
+
+// CreatePantryItemRequest is the request body for adding to the pantry
+type CreatePantryItemRequest struct {
+	Name              string  `json:"name"`
+	Quantity          int     `json:"quantity"`
+	Unit              *string `json:"unit,omitempty"`
+	Location          *string `json:"location,omitempty"`
+	CategoryID        string  `json:"categoryId,omitempty"`
+	ExpiresAt         *int64  `json:"expiresAt,omitempty"`
+	LowStockThreshold *int    `json:"lowStockThreshold,omitempty"`
+	RestockListID     *string `json:"restockListId,omitempty"`
+}
+
+// UpdatePantryItemRequest is the request body for updating a pantry item.
+// An empty unit, location or restock list, an expiry of 0 and a negative
+// threshold clear the field.
+type UpdatePantryItemRequest struct {
+	Name              *string `json:"name,omitempty"`
+	Quantity          *int    `json:"quantity,omitempty"`
+	Unit              *string `json:"unit,omitempty"`
+	Location          *string `json:"location,omitempty"`
+	CategoryID        *string `json:"categoryId,omitempty"`
+	ExpiresAt         *int64  `json:"expiresAt,omitempty"`
+	LowStockThreshold *int    `json:"lowStockThreshold,omitempty"`
+	RestockListID     *string `json:"restockListId,omitempty"`
+}
+
+// MealPlanEntryRequest is the request body for planning a meal. Exactly one
+// of RecipeID and Text is set; Servings defaults to the recipe's own.
+type MealPlanEntryRequest struct {
+	Date     string  `json:"date"`
+	Slot     string  `json:"slot"`
+	RecipeID *string `json:"recipeId,omitempty"`
+	Text     *string `json:"text,omitempty"`
+	Servings int     `json:"servings,omitempty"`
+}
+
+// GenerateMealPlanListRequest is the request body for turning the meals
+// planned from From to To (inclusive) into shopping. When ListID is set the
+// ingredients are merged into that list; otherwise a new list is created,
+// named Name or after the dates. Pantry stock is subtracted unless
+// IgnorePantry is set.
+type GenerateMealPlanListRequest struct {
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	ListID       *string `json:"listId,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	IgnorePantry bool    `json:"ignorePantry,omitempty"`
 }
 
 // ReorderItemsRequest is the request body for reordering items
@@ -111,6 +746,125 @@ type ReorderItemsRequest struct {
 	ItemIDs []string `json:"itemIds"`
 }
 
+// CreateTemplateRequest is the request body for saving a list as a template
+type CreateTemplateRequest struct {
+	ListID string `json:"listId"`
+	Name   string `json:"name"`
+}
+
+// UpdateTemplateRequest is the request body for renaming a template
+type UpdateTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+// TemplateScheduleRequest is the request body for scheduling a template to
+// run weekly. Weekday is 0 (Sunday) to 6 and Hour is 0 to 23 in server time.
+// When ListID is set, runs merge into that list instead of creating one.
+type TemplateScheduleRequest struct {
+	Weekday int     `json:"weekday"`
+	Hour    int     `json:"hour"`
+	ListID  *string `json:"listId,omitempty"`
+}
+
+// InstantiateTemplateRequest is the request body for using a template. When
+// ListID is set the template's items are merged into that list; otherwise a
+// new list is created, named Name or after the template.
+type InstantiateTemplateRequest struct {
+	ListID *string `json:"listId,omitempty"`
+	Name   string  `json:"name,omitempty"`
+}
+
+// Item filters for bulk operations
+const (
+	ItemFilterAll       = "all"
+	ItemFilterChecked   = "checked"
+	ItemFilterUnchecked = "unchecked"
+)
+
+// ItemSelection picks the items of a list a bulk operation applies to: the
+// given IDs, narrowed by Filter when both are set
+type ItemSelection struct {
+	ItemIDs []string `json:"itemIds,omitempty"`
+	Filter  string   `json:"filter,omitempty"`
+}
+
+// Bulk item actions
+const (
+	BulkActionCheck       = "check"
+	BulkActionUncheck     = "uncheck"
+	BulkActionDelete      = "delete"
+	BulkActionMove        = "move"
+	BulkActionSetCategory = "set_category"
+)
+
+// BulkItemsRequest is the request body for applying one action to many items.
+// ListID is the destination for moves; CategoryID is the new category for
+// set_category.
+type BulkItemsRequest struct {
+	ItemSelection
+	Action     string `json:"action"`
+	ListID     string `json:"listId,omitempty"`
+	CategoryID string `json:"categoryId,omitempty"`
+}
+
+// BulkItemsResponse returns the items a bulk operation changed and the list
+// version afterwards
+type BulkItemsResponse struct {
+	Items   []Item `json:"items"`
+	Version int    `json:"version"`
+}
+
+// CategorySuggestion is the category suggested for an item name and where the
+// suggestion came from: history, keyword or default
+type CategorySuggestion struct {
+	CategoryID string `json:"categoryId"`
+	Source     string `json:"source"`
+}
+
+// ItemSuggestion is an item name from history with the fields it was last
+// added with
+type ItemSuggestion struct {
+	Name       string   `json:"name"`
+	CategoryID string   `json:"categoryId"`
+	Unit       *string  `json:"unit"`
+	Store      *string  `json:"store"`
+	Price      *float64 `json:"price"`
+	Uses       int      `json:"uses"`
+	LastUsedAt int64    `json:"lastUsedAt"`
+}
+
+// SearchOptions controls what a search covers and which page it returns
+type SearchOptions struct {
+	IncludeChecked bool
+	IncludeTrashed bool
+	Offset         int
+	Limit          int
+}
+
+// SearchItem is an item matching a search. Highlight is the item's name,
+// HTML-escaped, with matched words wrapped in <mark>.
+type SearchItem struct {
+	Item
+	Highlight string `json:"highlight"`
+}
+
+// SearchGroup is a list with its matching items. Matched tells whether the
+// list's own name matched; Highlight marks the matches in it as for items.
+type SearchGroup struct {
+	List      List         `json:"list"`
+	Role      ListRole     `json:"role"`
+	Matched   bool         `json:"matched"`
+	Highlight string       `json:"highlight"`
+	Items     []SearchItem `json:"items"`
+}
+
+// SearchPage is a page of search results grouped by list. NextOffset is set
+// when more lists match.
+type SearchPage struct {
+	Groups     []SearchGroup `json:"groups"`
+	NextOffset int           `json:"nextOffset,omitempty"`
+}
+
 // CreateCategoryRequest is the request body for creating a category
 type CreateCategoryRequest struct {
 	Name      string `json:"name"`
@@ -129,9 +883,102 @@ type UpdateCategoryRequest struct {
 
 // CreatePriceHistoryRequest is the request body for recording a price
 type CreatePriceHistoryRequest struct {
-	ItemName string  `json:"itemName"`
-	Price    float64 `json:"price"`
-	Store    *string `json:"store,omitempty"`
+	ItemName    string   `json:"itemName"`
+	Price       float64  `json:"price"`
+	Unit        *string  `json:"unit,omitempty"`
+	PackageSize *float64 `json:"packageSize,omitempty"`
+	PackageUnit *string  `json:"packageUnit,omitempty"`
+	Store       *string  `json:"store,omitempty"`
+}
+
+// PriceStats summarizes the prices recorded for an item since Since. Change
+// is the percent change from the first price in the window to the last, nil
+// when the first was free. UnitPrice averages the unit prices that are known.
+// Stores come cheapest first, by unit price when every store has one and by
+// average price otherwise.
+type PriceStats struct {
+	ItemName      string       `json:"itemName"`
+	Since         int64        `json:"since"`
+	Count         int          `json:"count"`
+	Min           float64      `json:"min"`
+	Max           float64      `json:"max"`
+	Average       float64      `json:"average"`
+	Last          float64      `json:"last"`
+	LastRecorded  int64        `json:"lastRecordedAt"`
+	Change        *float64     `json:"changePercent"`
+	UnitPrice     *UnitPrice   `json:"unitPrice"`
+	CheapestStore *string      `json:"cheapestStore"`
+	Stores        []StorePrice `json:"stores"`
+}
+
+// StorePrice summarizes an item's prices at one store. Store is nil for
+// prices recorded without one.
+type StorePrice struct {
+	Store        *string    `json:"store"`
+	Count        int        `json:"count"`
+	Min          float64    `json:"min"`
+	Max          float64    `json:"max"`
+	Average      float64    `json:"average"`
+	Last         float64    `json:"last"`
+	LastRecorded int64      `json:"lastRecordedAt"`
+	UnitPrice    *UnitPrice `json:"unitPrice"`
+}
+
+// TrashResponse lists the trashed lists and items the user can restore
+type TrashResponse struct {
+	Lists []ListWithCounts `json:"lists"`
+	Items []Item           `json:"items"`
+}
+
+// ChangesResponse is the response for a delta sync. Cursor is passed back as
+// since on the next request.
+type ChangesResponse struct {
+	Cursor     int64            `json:"cursor"`
+	Lists      []ListWithCounts `json:"lists"`
+	Items      []Item           `json:"items"`
+	Categories []Category       `json:"categories"`
+	Deleted    []Tombstone      `json:"deleted"`
+}
+
+// SyncOperationRequest is one queued client operation.
+// Type is one of list.create, list.update, list.delete, item.create,
+// item.update, item.toggle, item.delete or item.reorder.
+type SyncOperationRequest struct {
+	ClientOpID  string          `json:"clientOpId"`
+	Type        string          `json:"type"`
+	EntityID    string          `json:"entityId"`
+	ListID      string          `json:"listId,omitempty"`
+	BaseVersion *int            `json:"baseVersion,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+}
+
+// SyncRequest is the request body for replaying an offline queue
+type SyncRequest struct {
+	Operations []SyncOperationRequest `json:"operations"`
+}
+
+// Sync operation result statuses
+const (
+	SyncStatusApplied  = "applied"
+	SyncStatusConflict = "conflict"
+	SyncStatusRejected = "rejected"
+)
+
+// SyncOperationResult reports the outcome of one sync operation. Server holds
+// the current server copy of the entity when the operation conflicted.
+type SyncOperationResult struct {
+	ClientOpID string      `json:"clientOpId"`
+	Status     string      `json:"status"`
+	EntityID   string      `json:"entityId,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Error      *APIError   `json:"error,omitempty"`
+	Server     interface{} `json:"server,omitempty"`
+	Replayed   bool        `json:"replayed,omitempty"`
+}
+
+// SyncResponse is the response for a sync batch, in operation order
+type SyncResponse struct {
+	Results []SyncOperationResult `json:"results"`
 }
 
 // LoginRequest is the request body for login
@@ -0,0 +1,292 @@
+// Package quickadd parses free-text shopping entries such as
+// "2 kg chicken breast @ Costco $12.99" into item fields.
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/units"
+)
+
+// spellings maps the spellings we accept to the unit stored on items
+var spellings = map[string]string{
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramme": "g", "grammes": "g",
+	"kg": "kg", "kgs": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg",
+	"mg": "mg", "milligram": "mg", "milligrams": "mg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "centiliter": "cl", "centiliters": "cl",
+	"l": "l", "lt": "l", "ltr": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"floz": "fl oz", "fluidounce": "fl oz", "fluidounces": "fl oz",
+	"gal": "gal", "gallon": "gal", "gallons": "gal",
+	"qt": "qt", "quart": "qt", "quarts": "qt",
+	"pt": "pt", "pint": "pt", "pints": "pt",
+	"cup": "cup", "cups": "cup",
+	"tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"dozen": "dozen", "dz": "dozen",
+	"pc": "pc", "pcs": "pc", "piece": "pc", "pieces": "pc",
+	"pack": "pack", "packs": "pack", "pk": "pack", "package": "pack", "packages": "pack",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"bottle": "bottle", "bottles": "bottle",
+	"box": "box", "boxes": "box",
+	"bag": "bag", "bags": "bag",
+	"jar": "jar", "jars": "jar",
+	"bunch": "bunch", "bunches": "bunch",
+	"loaf": "loaf", "loaves": "loaf",
+	"head": "head", "heads": "head",
+}
+
+// maxAmount is the largest quantity an entry may ask for. It keeps amounts
+// well inside int range after conversion to a smaller unit.
+const maxAmount = 1000000
+
+var (
+	pricePattern = regexp.MustCompile(`(?:^|\s)(?:\$\s?(\d+(?:[.,]\d{1,2})?)|(\d+(?:[.,]\d{1,2})?)\s?\$)(?:\s|$)`)
+	// amountPattern matches "2", "1.5", "1,5", "1/2" and "1 1/2", optionally
+	// followed directly by a unit as in "500g"
+	amountPattern   = regexp.MustCompile(`^(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?)\s*([a-zA-Z]+\.?)?$`)
+	multiplierRegex = regexp.MustCompile(`^(?:x\s?(\d+)|(\d+)\s?x)$`)
+	bulletPattern   = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+)
+
+// Parse splits text into lines and parses each one. Blank lines and lines
+// without an item name are skipped.
+func Parse(text string) []models.CreateItemRequest {
+	items := []models.CreateItemRequest{}
+	for _, line := range strings.Split(text, "\n") {
+		if item, ok := ParseLine(line); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseLine parses one entry of the form
+//
+//	[quantity] [unit] name [@ store] [$price]
+//
+// Quantity may also be written as "2x" before the name or "x2" after it.
+// Fractional amounts are converted to a smaller unit when there is one
+// (1.5 kg becomes 1500 g) and rounded up otherwise. It reports false when the
+// line has no item name, only a unit, or an amount that is zero, divides by
+// zero or is larger than we accept.
+func ParseLine(line string) (models.CreateItemRequest, bool) {
+	req := models.CreateItemRequest{Quantity: 1}
+
+	line = bulletPattern.ReplaceAllString(line, "")
+	line = strings.TrimSpace(line)
+
+	// Price can appear anywhere; take the last one
+	if matches := pricePattern.FindAllStringSubmatchIndex(line, -1); len(matches) > 0 {
+		m := matches[len(matches)-1]
+		raw := submatch(line, m, 1)
+		if raw == "" {
+			raw = submatch(line, m, 2)
+		}
+		if price, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64); err == nil {
+			req.Price = &price
+		}
+		line = strings.TrimSpace(line[:m[0]] + " " + line[m[1]:])
+	}
+
+	// Everything after @ is the store
+	if at := strings.Index(line, "@"); at >= 0 {
+		if store := strings.TrimSpace(line[at+1:]); store != "" {
+			req.Store = &store
+		}
+		line = strings.TrimSpace(line[:at])
+	}
+
+	words := strings.Fields(line)
+	amount, unit, consumed := leadingAmount(words)
+	if consumed > 0 && amount == 0 {
+		return models.CreateItemRequest{}, false
+	}
+	words = words[consumed:]
+
+	// Trailing multiplier such as "milk x2"
+	if consumed == 0 && len(words) > 1 {
+		trailing := 0
+		if n, ok := multiplier(words[len(words)-1]); ok {
+			amount, trailing = float64(n), 1
+		} else if len(words) > 2 && strings.EqualFold(words[len(words)-2], "x") && isDigits(words[len(words)-1]) {
+			amount, trailing = float64(count(words[len(words)-1])), 2
+		}
+		if trailing > 0 && amount == 0 {
+			return models.CreateItemRequest{}, false
+		}
+		words = words[:len(words)-trailing]
+	}
+
+	name := strings.TrimSpace(strings.Join(words, " "))
+	name = strings.TrimSpace(strings.TrimPrefix(name, "of "))
+	// A bare unit such as "2 kg" names nothing
+	if !hasAlphanumeric(name) || NormalizeUnit(name) != "" {
+		return models.CreateItemRequest{}, false
+	}
+	req.Name = name
+
+	if amount > 0 {
+		req.Quantity, unit = units.Whole(amount, unit)
+	}
+	if unit != "" {
+		req.Unit = &unit
+	}
+
+	return req, true
+}
+
+// NormalizeUnit returns the stored spelling of a unit, or "" when the unit is
+// not one we know
+func NormalizeUnit(unit string) string {
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(unit), "."))
+	key = strings.ReplaceAll(key, " ", "")
+	return spellings[key]
+}
+
+// leadingAmount reads a quantity and unit from the start of words and returns
+// how many words it used. An amount of 0 with words used means the line
+// starts with an amount that can't be used.
+func leadingAmount(words []string) (amount float64, unit string, consumed int) {
+	if len(words) == 0 {
+		return 0, "", 0
+	}
+
+	if n, ok := multiplier(words[0]); ok {
+		return float64(n), "", 1
+	}
+
+	// "1 1/2" spans two words
+	first := words[0]
+	if len(words) > 1 && strings.Contains(words[1], "/") && isDigits(first) {
+		first = first + " " + words[1]
+		consumed = 1
+	}
+
+	m := amountPattern.FindStringSubmatch(first)
+	if m == nil {
+		return 0, "", 0
+	}
+	consumed++
+	amount, ok := parseAmount(m[1])
+	if !ok {
+		return 0, "", consumed
+	}
+
+	// Unit attached to the number, as in "500g"
+	if m[2] != "" {
+		unit = NormalizeUnit(m[2])
+		if unit == "" {
+			return 0, "", 0
+		}
+		return amount, unit, consumed
+	}
+
+	rest := words[consumed:]
+	if len(rest) > 1 && strings.EqualFold(rest[0], "x") {
+		return amount, "", consumed + 1
+	}
+	if len(rest) > 2 && strings.EqualFold(rest[0], "fl") {
+		if u := NormalizeUnit("fl" + rest[1]); u != "" {
+			return amount, u, consumed + 2
+		}
+	}
+	// Only take a unit when something is left to name the item
+	if len(rest) > 1 {
+		if u := NormalizeUnit(rest[0]); u != "" {
+			return amount, u, consumed + 1
+		}
+	}
+	return amount, "", consumed
+}
+
+// multiplier reads a count written as "x2" or "2x". The count is 0 when it is
+// zero or larger than maxAmount.
+func multiplier(word string) (int, bool) {
+	m := multiplierRegex.FindStringSubmatch(strings.ToLower(word))
+	if m == nil {
+		return 0, false
+	}
+	raw := m[1]
+	if raw == "" {
+		raw = m[2]
+	}
+	return count(raw), true
+}
+
+// count parses a string of digits, returning 0 when it is zero or larger
+// than maxAmount
+func count(digits string) int {
+	n, err := strconv.Atoi(digits)
+	if err != nil || n <= 0 || n > maxAmount {
+		return 0
+	}
+	return n
+}
+
+func parseAmount(raw string) (float64, bool) {
+	raw = strings.ReplaceAll(raw, ",", ".")
+	whole := 0.0
+	if parts := strings.Fields(raw); len(parts) == 2 {
+		w, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return 0, false
+		}
+		whole = w
+		raw = parts[1]
+	}
+	if num, den, ok := strings.Cut(raw, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return inRange(whole + n/d)
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return inRange(whole + v)
+}
+
+func inRange(amount float64) (float64, bool) {
+	if amount <= 0 || amount > maxAmount {
+		return 0, false
+	}
+	return amount, true
+}
+
+func hasAlphanumeric(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func submatch(s string, m []int, group int) string {
+	if m[2*group] < 0 {
+		return ""
+	}
+	return s[m[2*group]:m[2*group+1]]
+}
@@ -0,0 +1,132 @@
+package quickadd
+
+import "testing"
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		name     string
+		quantity int
+		unit     string
+		store    string
+		price    float64
+	}{
+		{line: "milk", name: "milk", quantity: 1},
+		{line: "2 kg chicken breast @ Costco $12.99", name: "chicken breast", quantity: 2, unit: "kg", store: "Costco", price: 12.99},
+		{line: "3 bananas", name: "bananas", quantity: 3},
+		{line: "500g ground beef", name: "ground beef", quantity: 500, unit: "g"},
+		{line: "1.5 kg potatoes", name: "potatoes", quantity: 1500, unit: "g"},
+		{line: "1,5 l milk", name: "milk", quantity: 1500, unit: "ml"},
+		{line: "1/2 lb ham", name: "ham", quantity: 8, unit: "oz"},
+		{line: "1 1/2 cups flour", name: "flour", quantity: 2, unit: "cup"},
+		{line: "2x yogurt", name: "yogurt", quantity: 2},
+		{line: "2 x yogurt", name: "yogurt", quantity: 2},
+		{line: "yogurt x3", name: "yogurt", quantity: 3},
+		{line: "yogurt x 3", name: "yogurt", quantity: 3},
+		{line: "2 Lbs. apples", name: "apples", quantity: 2, unit: "lb"},
+		{line: "6 Kilograms rice", name: "rice", quantity: 6, unit: "kg"},
+		{line: "12 fl oz orange juice", name: "orange juice", quantity: 12, unit: "fl oz"},
+		{line: "1 dozen eggs", name: "eggs", quantity: 1, unit: "dozen"},
+		{line: "2 cans of tomatoes", name: "tomatoes", quantity: 2, unit: "can"},
+		{line: "coffee $7,50", name: "coffee", quantity: 1, price: 7.5},
+		{line: "coffee 8$", name: "coffee", quantity: 1, price: 8},
+		{line: "bread @ Trader Joe's", name: "bread", quantity: 1, store: "Trader Joe's"},
+		{line: "- 4 lemons", name: "lemons", quantity: 4},
+		{line: "* olive oil", name: "olive oil", quantity: 1},
+		{line: "  7 up  ", name: "up", quantity: 7},
+		{line: "2 pcs avocado @ Aldi", name: "avocado", quantity: 2, unit: "pc", store: "Aldi"},
+		{line: "1000 kg rice", name: "rice", quantity: 1000, unit: "kg"},
+		{line: "0.5 kg rice", name: "rice", quantity: 500, unit: "g"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := ParseLine(tt.line)
+			if !ok {
+				t.Fatalf("ParseLine(%q) reported no item", tt.line)
+			}
+			if got.Name != tt.name {
+				t.Errorf("name = %q, want %q", got.Name, tt.name)
+			}
+			if got.Quantity != tt.quantity {
+				t.Errorf("quantity = %d, want %d", got.Quantity, tt.quantity)
+			}
+
+			unit := ""
+			if got.Unit != nil {
+				unit = *got.Unit
+			}
+			if unit != tt.unit {
+				t.Errorf("unit = %q, want %q", unit, tt.unit)
+			}
+
+			store := ""
+			if got.Store != nil {
+				store = *got.Store
+			}
+			if store != tt.store {
+				t.Errorf("store = %q, want %q", store, tt.store)
+			}
+
+			price := 0.0
+			if got.Price != nil {
+				price = *got.Price
+			}
+			if price != tt.price {
+				t.Errorf("price = %v, want %v", price, tt.price)
+			}
+		})
+	}
+}
+
+func TestParseLineWithoutName(t *testing.T) {
+	for _, line := range []string{"", "   ", "2 kg", "$4.99", "@ Costco", "-", "2 bags"} {
+		if got, ok := ParseLine(line); ok {
+			t.Errorf("ParseLine(%q) = %+v, want no item", line, got)
+		}
+	}
+}
+
+func TestParseLineWithBadAmount(t *testing.T) {
+	for _, line := range []string{
+		"0 eggs",
+		"1/0 eggs",
+		"0/2 eggs",
+		"2 1/0 cups flour",
+		"99999999999999999999 kg rice",
+		"1000001 apples",
+		"0x yogurt",
+		"yogurt x0",
+		"yogurt x 99999999999999999999",
+	} {
+		if got, ok := ParseLine(line); ok {
+			t.Errorf("ParseLine(%q) = %+v, want no item", line, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	items := Parse("2 kg chicken @ Costco\n\n  milk\n3 apples $2\n")
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+	if items[0].Name != "chicken" || items[1].Name != "milk" || items[2].Name != "apples" {
+		t.Errorf("Unexpected names: %q, %q, %q", items[0].Name, items[1].Name, items[2].Name)
+	}
+}
+
+func TestNormalizeUnit(t *testing.T) {
+	tests := map[string]string{
+		"KG":     "kg",
+		"lbs.":   "lb",
+		"Litres": "l",
+		"fl oz":  "fl oz",
+		"Tbsp":   "tbsp",
+		"parsec": "",
+	}
+	for in, want := range tests {
+		if got := NormalizeUnit(in); got != want {
+			t.Errorf("NormalizeUnit(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"sync"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+// EventType identifies the kind of change carried by an Event
+type EventType string
+
+const (
+	ItemCreated    EventType = "item.created"
+	ItemUpdated    EventType = "item.updated"
+	ItemToggled    EventType = "item.toggled"
+	ItemDeleted    EventType = "item.deleted"
+	ItemsReordered EventType = "items.reordered"
+	ListUpdated    EventType = "list.updated"
+	ListDeleted    EventType = "list.deleted"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const subscriberBuffer = 32
+
+// Event is a change notification pushed to subscribers of a list.
+// Version is the version of the entity the event describes (the item for
+// item events, the list for list and reorder events) so clients can drop
+// events older than the state they already hold.
+type Event struct {
+	Type    EventType    `json:"type"`
+	ListID  string       `json:"listId"`
+	Version int          `json:"version"`
+	Item    *models.Item `json:"item,omitempty"`
+	ItemID  string       `json:"itemId,omitempty"`
+	ItemIDs []string     `json:"itemIds,omitempty"`
+	List    *models.List `json:"list,omitempty"`
+}
+
+// Hub fans out list events to subscribers
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe registers interest in a list's events. The returned function
+// must be called to unsubscribe; it closes the channel.
+func (h *Hub) Subscribe(listID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[listID] == nil {
+		h.subs[listID] = make(map[chan Event]struct{})
+	}
+	h.subs[listID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[listID], ch)
+			if len(h.subs[listID]) == 0 {
+				delete(h.subs, listID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to every subscriber of its list without blocking.
+// Subscribers whose buffer is full miss the event and are expected to refetch.
+func (h *Hub) Publish(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subs[event.ListID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscribers for a list
+func (h *Hub) SubscriberCount(listID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs[listID])
+}
@@ -0,0 +1,63 @@
+package realtime
+
+import "testing"
+
+func TestHub_PublishDeliversToListSubscribers(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe("list-1")
+	defer unsubscribe()
+
+	other, unsubscribeOther := hub.Subscribe("list-2")
+	defer unsubscribeOther()
+
+	hub.Publish(Event{Type: ItemCreated, ListID: "list-1", ItemID: "item-1", Version: 1})
+
+	select {
+	case event := <-events:
+		if event.Type != ItemCreated || event.ItemID != "item-1" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	default:
+		t.Fatal("Expected event for list-1 subscriber")
+	}
+
+	select {
+	case event := <-other:
+		t.Errorf("Subscriber of list-2 should not receive list-1 events, got %+v", event)
+	default:
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe("list-1")
+	if hub.SubscriberCount("list-1") != 1 {
+		t.Fatalf("Expected 1 subscriber, got %d", hub.SubscriberCount("list-1"))
+	}
+
+	unsubscribe()
+	unsubscribe() // Must be safe to call twice
+
+	if hub.SubscriberCount("list-1") != 0 {
+		t.Errorf("Expected 0 subscribers, got %d", hub.SubscriberCount("list-1"))
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+
+	// Publishing with no subscribers must not panic
+	hub.Publish(Event{Type: ListDeleted, ListID: "list-1"})
+}
+
+func TestHub_PublishDoesNotBlockOnSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+
+	_, unsubscribe := hub.Subscribe("list-1")
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		hub.Publish(Event{Type: ItemUpdated, ListID: "list-1", Version: i})
+	}
+}
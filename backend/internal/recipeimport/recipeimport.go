@@ -0,0 +1,278 @@
+// Package recipeimport extracts a recipe from the schema.org JSON-LD that
+// recipe sites embed in their pages. It works only on the content it is
+// given and never fetches anything.
+package recipeimport
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/quickadd"
+)
+
+// ErrNoRecipe is returned when the content holds no schema.org Recipe
+var ErrNoRecipe = errors.New("no recipe found")
+
+var (
+	scriptPattern = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+	tagPattern    = regexp.MustCompile(`<[^>]*>`)
+	notePattern   = regexp.MustCompile(`\s*\([^)]*\)`)
+	numberPattern = regexp.MustCompile(`\d+`)
+	// amountPattern matches "2", "1.5", "1,5", "1/2", "1 1/2" and ranges such
+	// as "2-3", at the start of a line
+	amountPattern = regexp.MustCompile(`^(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?)(?:\s*(?:-|–|to)\s*(?:\d+(?:[.,]\d+)?))?\s*`)
+)
+
+// unicodeFractions are the vulgar fraction characters recipes use
+var unicodeFractions = map[rune]string{
+	'½': "1/2", '⅓': "1/3", '⅔': "2/3", '¼': "1/4", '¾': "3/4",
+	'⅕': "1/5", '⅖': "2/5", '⅗': "3/5", '⅘': "4/5", '⅙': "1/6",
+	'⅚': "5/6", '⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8",
+}
+
+// Parse extracts the first schema.org Recipe from content, which is either a
+// JSON-LD document or an HTML page with JSON-LD script tags. Ingredient lines
+// are split into quantity, unit and name; categories are left for the caller.
+func Parse(content string) (*models.RecipeRequest, error) {
+	content = strings.TrimSpace(content)
+
+	var documents []string
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		documents = []string{content}
+	} else {
+		for _, m := range scriptPattern.FindAllStringSubmatch(content, -1) {
+			documents = append(documents, m[1])
+		}
+	}
+
+	for _, document := range documents {
+		var data interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(document)), &data); err != nil {
+			continue
+		}
+		if recipe := findRecipe(data); recipe != nil {
+			return toRequest(recipe), nil
+		}
+	}
+	return nil, ErrNoRecipe
+}
+
+// findRecipe walks JSON-LD looking for an object typed Recipe, including
+// inside arrays and @graph
+func findRecipe(data interface{}) map[string]interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, entry := range v {
+			if recipe := findRecipe(entry); recipe != nil {
+				return recipe
+			}
+		}
+	case map[string]interface{}:
+		if hasType(v["@type"], "Recipe") {
+			return v
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findRecipe(graph)
+		}
+	}
+	return nil
+}
+
+func hasType(value interface{}, name string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == name || strings.HasSuffix(v, "/"+name)
+	case []interface{}:
+		for _, entry := range v {
+			if hasType(entry, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toRequest(recipe map[string]interface{}) *models.RecipeRequest {
+	req := &models.RecipeRequest{
+		Name:        truncate(text(recipe["name"]), 200),
+		Servings:    servings(recipe["recipeYield"]),
+		Ingredients: []models.RecipeIngredientRequest{},
+		Steps:       instructions(recipe["recipeInstructions"]),
+	}
+	if description := text(recipe["description"]); description != "" {
+		req.Description = &description
+	}
+
+	lines := stringList(recipe["recipeIngredient"])
+	if len(lines) == 0 {
+		// Older markup used "ingredients"
+		lines = stringList(recipe["ingredients"])
+	}
+	for _, line := range lines {
+		if ingredient, ok := ParseIngredient(line); ok {
+			req.Ingredients = append(req.Ingredients, ingredient)
+		}
+	}
+	return req
+}
+
+// ParseIngredient splits a line such as "1 ½ cups flour, sifted" into
+// quantity, unit and name. Notes after a comma and in parentheses are
+// dropped from the name. A line without an amount counts as 1.
+func ParseIngredient(line string) (models.RecipeIngredientRequest, bool) {
+	line = clean(line)
+	for r, fraction := range unicodeFractions {
+		line = strings.ReplaceAll(line, string(r), " "+fraction)
+	}
+	line = strings.Join(strings.Fields(line), " ")
+
+	ingredient := models.RecipeIngredientRequest{Quantity: 1}
+	if m := amountPattern.FindStringSubmatch(line); m != nil {
+		if amount, ok := parseAmount(m[1]); ok {
+			ingredient.Quantity = amount
+			line = line[len(m[0]):]
+		}
+	}
+
+	words := strings.Fields(line)
+	if len(words) > 1 {
+		if strings.EqualFold(words[0], "fl") {
+			if unit := quickadd.NormalizeUnit("fl" + words[1]); unit != "" && len(words) > 2 {
+				ingredient.Unit = &unit
+				words = words[2:]
+			}
+		} else if unit := quickadd.NormalizeUnit(words[0]); unit != "" {
+			ingredient.Unit = &unit
+			words = words[1:]
+		}
+	}
+
+	name := strings.Join(words, " ")
+	name = notePattern.ReplaceAllString(name, "")
+	if comma := strings.Index(name, ","); comma > 0 {
+		name = name[:comma]
+	}
+	name = strings.TrimSpace(strings.TrimPrefix(name, "of "))
+	if name == "" {
+		return models.RecipeIngredientRequest{}, false
+	}
+	ingredient.Name = truncate(name, 200)
+	return ingredient, true
+}
+
+func parseAmount(raw string) (float64, bool) {
+	raw = strings.ReplaceAll(raw, ",", ".")
+	whole := 0.0
+	if parts := strings.Fields(raw); len(parts) == 2 {
+		w, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return 0, false
+		}
+		whole = w
+		raw = parts[1]
+	}
+	if num, den, ok := strings.Cut(raw, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return whole + n/d, true
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return whole + v, true
+}
+
+// servings reads recipeYield, which sites give as a number, a string such as
+// "Serves 4" or a list of both. It defaults to 1.
+func servings(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		if v >= 1 {
+			return int(v)
+		}
+	case string:
+		if n, err := strconv.Atoi(numberPattern.FindString(v)); err == nil && n >= 1 {
+			return n
+		}
+	case []interface{}:
+		for _, entry := range v {
+			if n := servings(entry); n > 1 {
+				return n
+			}
+		}
+	}
+	return 1
+}
+
+// instructions flattens recipeInstructions: a string, a list of strings,
+// HowToStep objects or HowToSection objects holding steps
+func instructions(value interface{}) []string {
+	steps := []string{}
+	switch v := value.(type) {
+	case string:
+		for _, line := range strings.Split(html.UnescapeString(v), "\n") {
+			if line = clean(line); line != "" {
+				steps = append(steps, line)
+			}
+		}
+	case []interface{}:
+		for _, entry := range v {
+			steps = append(steps, instructions(entry)...)
+		}
+	case map[string]interface{}:
+		if list, ok := v["itemListElement"]; ok {
+			return instructions(list)
+		}
+		if step := text(v["text"]); step != "" {
+			steps = append(steps, step)
+		} else if step := text(v["name"]); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// strings_ reads a value that should be a list of strings but may be one
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func text(value interface{}) string {
+	s, _ := value.(string)
+	return clean(s)
+}
+
+// clean unescapes HTML entities, drops tags and collapses whitespace
+func clean(s string) string {
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, " "))
+	return strings.Join(strings.FieldsFunc(s, unicode.IsSpace), " ")
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return strings.TrimSpace(s[:max])
+}
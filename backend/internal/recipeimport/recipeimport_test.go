@@ -0,0 +1,179 @@
+package recipeimport
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+type ingredient struct {
+	name     string
+	quantity float64
+	unit     string
+}
+
+func ingredients(in []models.RecipeIngredientRequest) []ingredient {
+	result := make([]ingredient, len(in))
+	for i, ing := range in {
+		result[i] = ingredient{name: ing.Name, quantity: math.Round(ing.Quantity*1000) / 1000}
+		if ing.Unit != nil {
+			result[i].unit = *ing.Unit
+		}
+	}
+	return result
+}
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return string(data)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		name        string
+		description string
+		servings    int
+		ingredients []ingredient
+		steps       []string
+	}{
+		{
+			fixture:     "graph.html",
+			name:        "Weeknight Chicken Curry",
+			description: "A quick curry with pantry staples & fresh ginger.",
+			servings:    4,
+			ingredients: []ingredient{
+				{name: "vegetable oil", quantity: 2, unit: "tbsp"},
+				{name: "large onion", quantity: 1},
+				{name: "cloves garlic", quantity: 3},
+				{name: "boneless chicken thighs", quantity: 1.5, unit: "lb"},
+				{name: "coconut milk", quantity: 400, unit: "ml"},
+				{name: "Salt to taste", quantity: 1},
+			},
+			steps: []string{
+				"Heat the oil and soften the onion.",
+				"Add the garlic and chicken and brown.",
+				"Pour in the coconut milk and simmer for 20 minutes.",
+			},
+		},
+		{
+			fixture:  "sections.html",
+			name:     "Lemon Drizzle Cake",
+			servings: 12,
+			ingredients: []ingredient{
+				{name: "unsalted butter", quantity: 225, unit: "g"},
+				{name: "caster sugar", quantity: 225, unit: "g"},
+				{name: "eggs", quantity: 4},
+				{name: "lemon", quantity: 1},
+				{name: "lemon juice", quantity: 2, unit: "fl oz"},
+			},
+			steps: []string{
+				"Beat the butter and sugar.",
+				"Add the eggs and zest, then bake.",
+				"Pour the juice over the warm cake.",
+			},
+		},
+		{
+			fixture:  "pancakes.json",
+			name:     "Fluffy Pancakes",
+			servings: 8,
+			ingredients: []ingredient{
+				{name: "all-purpose flour", quantity: 1.5, unit: "cup"},
+				{name: "milk", quantity: 0.75, unit: "cup"},
+				{name: "sugar", quantity: 2, unit: "tbsp"},
+				{name: "egg", quantity: 1},
+				{name: "salt", quantity: 0.5, unit: "tsp"},
+			},
+			steps: []string{
+				"Whisk everything together.",
+				"Cook on a hot griddle until golden.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := Parse(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if got.Name != tt.name {
+				t.Errorf("name = %q, want %q", got.Name, tt.name)
+			}
+
+			description := ""
+			if got.Description != nil {
+				description = *got.Description
+			}
+			if description != tt.description {
+				t.Errorf("description = %q, want %q", description, tt.description)
+			}
+			if got.Servings != tt.servings {
+				t.Errorf("servings = %d, want %d", got.Servings, tt.servings)
+			}
+			if gotIngredients := ingredients(got.Ingredients); !reflect.DeepEqual(gotIngredients, tt.ingredients) {
+				t.Errorf("ingredients = %+v, want %+v", gotIngredients, tt.ingredients)
+			}
+			if !reflect.DeepEqual(got.Steps, tt.steps) {
+				t.Errorf("steps = %q, want %q", got.Steps, tt.steps)
+			}
+		})
+	}
+}
+
+func TestParse_NoRecipe(t *testing.T) {
+	inputs := []string{
+		"",
+		"<html><body>Just a blog post</body></html>",
+		`<script type="application/ld+json">{"@type": "Organization", "name": "Bakes"}</script>`,
+		`<script type="application/ld+json">{not json</script>`,
+		`{"@type": "Person"}`,
+	}
+
+	for _, input := range inputs {
+		if _, err := Parse(input); !errors.Is(err, ErrNoRecipe) {
+			t.Errorf("Parse(%q) error = %v, want ErrNoRecipe", input, err)
+		}
+	}
+}
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		line string
+		want ingredient
+		ok   bool
+	}{
+		{line: "2 cans of chickpeas, drained", want: ingredient{name: "chickpeas", quantity: 2, unit: "can"}, ok: true},
+		{line: "⅓ cup olive oil", want: ingredient{name: "olive oil", quantity: 0.333, unit: "cup"}, ok: true},
+		{line: "1,5 kg potatoes", want: ingredient{name: "potatoes", quantity: 1.5, unit: "kg"}, ok: true},
+		{line: "3/4 tsp <strong>smoked</strong> paprika", want: ingredient{name: "smoked paprika", quantity: 0.75, unit: "tsp"}, ok: true},
+		{line: "1 to 2 limes", want: ingredient{name: "limes", quantity: 1}, ok: true},
+		{line: "fresh basil", want: ingredient{name: "fresh basil", quantity: 1}, ok: true},
+		{line: "2", ok: false},
+		{line: "  ", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := ParseIngredient(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if parsed := ingredients([]models.RecipeIngredientRequest{got})[0]; parsed != tt.want {
+				t.Errorf("got %+v, want %+v", parsed, tt.want)
+			}
+		})
+	}
+}
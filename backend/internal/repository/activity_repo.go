@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+type ActivityRepository struct {
+	db *db.DB
+}
+
+func NewActivityRepository(database *db.DB) *ActivityRepository {
+	return &ActivityRepository{db: database}
+}
+
+func (r *ActivityRepository) Create(activity *models.Activity) error {
+	return r.db.Create(activity).Error
+}
+
+// GetByListID returns up to limit activities for a list, newest first. When
+// before is non-zero only activities older than that activity ID are returned.
+func (r *ActivityRepository) GetByListID(listID string, before int64, limit int) ([]models.Activity, error) {
+	query := r.db.Where("list_id = ?", listID)
+	if before > 0 {
+		query = query.Where("id < ?", before)
+	}
+
+	var activities []models.Activity
+	err := query.Order("id DESC").Limit(limit).Find(&activities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if activities == nil {
+		activities = []models.Activity{}
+	}
+
+	return activities, nil
+}
+
+// GetLatestUndoable returns the user's most recent activity on a list that
+// has not been undone yet. Records of undos themselves are skipped.
+func (r *ActivityRepository) GetLatestUndoable(listID, userID string) (*models.Activity, error) {
+	var activity models.Activity
+	err := r.db.Where("list_id = ? AND user_id = ? AND undone_at IS NULL AND action <> ?", listID, userID, models.ActivityUndone).
+		Order("id DESC").
+		First(&activity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNothingToUndo
+		}
+		return nil, err
+	}
+	return &activity, nil
+}
+
+// GetUndoGroup returns what undoing activity reverts, newest first: the
+// activities on its list that share its group and are not undone yet, or just
+// activity when it has no group
+func (r *ActivityRepository) GetUndoGroup(activity *models.Activity) ([]models.Activity, error) {
+	if activity.GroupID == nil {
+		return []models.Activity{*activity}, nil
+	}
+
+	var activities []models.Activity
+	err := r.db.Where("list_id = ? AND group_id = ? AND undone_at IS NULL", activity.ListID, *activity.GroupID).
+		Order("id DESC").
+		Find(&activities).Error
+	if err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+// HasLaterChanges reports whether the same item, on any list it has been on
+// since, or for list-level actions the same kind of change on the list, was
+// touched again after the activity. Undoing would then overwrite someone
+// else's newer work. Later entries of the activity's own group are part of
+// the same change and do not count.
+func (r *ActivityRepository) HasLaterChanges(activity *models.Activity) (bool, error) {
+	query := r.db.Model(&models.Activity{}).
+		Where("id > ? AND undone_at IS NULL", activity.ID)
+	if activity.GroupID != nil {
+		query = query.Where("(group_id IS NULL OR group_id <> ?)", *activity.GroupID)
+	}
+	if activity.ItemID != nil {
+		query = query.Where("item_id = ?", *activity.ItemID)
+	} else {
+		query = query.Where("list_id = ? AND action = ?", activity.ListID, activity.Action)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *ActivityRepository) MarkUndone(id int64, undoneAt int64) error {
+	return r.db.Model(&models.Activity{}).Where("id = ?", id).Update("undone_at", undoneAt).Error
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func createTestActivity(t *testing.T, repo *ActivityRepository, listID, userID, action string, itemID *string) *models.Activity {
+	activity := &models.Activity{
+		ListID:    listID,
+		UserID:    userID,
+		UserName:  "Test User",
+		Action:    action,
+		ItemID:    itemID,
+		CreatedAt: 1000,
+	}
+	if err := repo.Create(activity); err != nil {
+		t.Fatalf("Failed to create activity: %v", err)
+	}
+	return activity
+}
+
+func TestActivityRepository_GetByListIDPaginates(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewActivityRepository(database)
+	for i := 0; i < 5; i++ {
+		createTestActivity(t, repo, "list-1", "user-1", models.ActivityItemCreated, nil)
+	}
+	createTestActivity(t, repo, "list-2", "user-1", models.ActivityItemCreated, nil)
+
+	page, err := repo.GetByListID("list-1", 0, 3)
+	if err != nil {
+		t.Fatalf("Failed to get activity: %v", err)
+	}
+	if len(page) != 3 {
+		t.Fatalf("Expected 3 activities, got %d", len(page))
+	}
+	if page[0].ID < page[1].ID {
+		t.Error("Expected newest activity first")
+	}
+
+	rest, err := repo.GetByListID("list-1", page[2].ID, 3)
+	if err != nil {
+		t.Fatalf("Failed to get next page: %v", err)
+	}
+	if len(rest) != 2 {
+		t.Fatalf("Expected 2 remaining activities, got %d", len(rest))
+	}
+	if rest[0].ID >= page[2].ID {
+		t.Error("Expected next page to continue after the cursor")
+	}
+}
+
+func TestActivityRepository_GetLatestUndoable(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewActivityRepository(database)
+
+	_, err := repo.GetLatestUndoable("list-1", "user-1")
+	if err != ErrNothingToUndo {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+
+	first := createTestActivity(t, repo, "list-1", "user-1", models.ActivityItemCreated, nil)
+	second := createTestActivity(t, repo, "list-1", "user-1", models.ActivityItemUpdated, nil)
+	createTestActivity(t, repo, "list-1", "user-2", models.ActivityItemDeleted, nil)
+
+	latest, err := repo.GetLatestUndoable("list-1", "user-1")
+	if err != nil {
+		t.Fatalf("Failed to get latest activity: %v", err)
+	}
+	if latest.ID != second.ID {
+		t.Errorf("Expected activity %d, got %d", second.ID, latest.ID)
+	}
+
+	if err := repo.MarkUndone(second.ID, 2000); err != nil {
+		t.Fatalf("Failed to mark undone: %v", err)
+	}
+
+	latest, err = repo.GetLatestUndoable("list-1", "user-1")
+	if err != nil {
+		t.Fatalf("Failed to get latest activity: %v", err)
+	}
+	if latest.ID != first.ID {
+		t.Errorf("Expected undo to move back to activity %d, got %d", first.ID, latest.ID)
+	}
+}
+
+func TestActivityRepository_HasLaterChanges(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewActivityRepository(database)
+	itemA := "item-a"
+	itemB := "item-b"
+
+	mine := createTestActivity(t, repo, "list-1", "user-1", models.ActivityItemUpdated, &itemA)
+	createTestActivity(t, repo, "list-1", "user-2", models.ActivityItemChecked, &itemB)
+
+	changed, err := repo.HasLaterChanges(mine)
+	if err != nil {
+		t.Fatalf("Failed to check later changes: %v", err)
+	}
+	if changed {
+		t.Error("Expected changes to other items to be ignored")
+	}
+
+	theirs := createTestActivity(t, repo, "list-1", "user-2", models.ActivityItemChecked, &itemA)
+
+	changed, _ = repo.HasLaterChanges(mine)
+	if !changed {
+		t.Error("Expected a later change to the same item to be detected")
+	}
+
+	// Once the later change is undone it no longer blocks
+	if err := repo.MarkUndone(theirs.ID, 2000); err != nil {
+		t.Fatalf("Failed to mark undone: %v", err)
+	}
+	changed, _ = repo.HasLaterChanges(mine)
+	if changed {
+		t.Error("Expected undone changes to be ignored")
+	}
+
+	// A change after the item moved to another list still counts
+	createTestActivity(t, repo, "list-2", "user-2", models.ActivityItemUpdated, &itemA)
+	changed, _ = repo.HasLaterChanges(mine)
+	if !changed {
+		t.Error("Expected a later change on another list to be detected")
+	}
+
+	rename := createTestActivity(t, repo, "list-1", "user-1", models.ActivityListRenamed, nil)
+	createTestActivity(t, repo, "list-1", "user-2", models.ActivityListRenamed, nil)
+	changed, _ = repo.HasLaterChanges(rename)
+	if !changed {
+		t.Error("Expected a later rename to be detected")
+	}
+}
+
+func TestActivityRepository_UndoGroup(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewActivityRepository(database)
+	group := "group-1"
+	itemA := "item-a"
+	var grouped []*models.Activity
+	// A move records the same item leaving and arriving
+	for _, action := range []string{models.ActivityItemDeleted, models.ActivityItemCreated} {
+		activity := &models.Activity{ListID: "list-1", UserID: "user-1", UserName: "Test User", Action: action, ItemID: &itemA, GroupID: &group, CreatedAt: 1000}
+		if err := repo.Create(activity); err != nil {
+			t.Fatalf("Failed to create activity: %v", err)
+		}
+		grouped = append(grouped, activity)
+	}
+	single := createTestActivity(t, repo, "list-1", "user-1", models.ActivityItemCreated, nil)
+
+	activities, err := repo.GetUndoGroup(grouped[0])
+	if err != nil {
+		t.Fatalf("Failed to get undo group: %v", err)
+	}
+	if len(activities) != 2 || activities[0].ID != grouped[1].ID {
+		t.Errorf("Expected both grouped activities newest first, got %+v", activities)
+	}
+	activities, _ = repo.GetUndoGroup(single)
+	if len(activities) != 1 || activities[0].ID != single.ID {
+		t.Errorf("Expected an ungrouped activity alone, got %+v", activities)
+	}
+
+	// Entries of the same group are one change, not later changes
+	changed, err := repo.HasLaterChanges(grouped[0])
+	if err != nil {
+		t.Fatalf("Failed to check later changes: %v", err)
+	}
+	if changed {
+		t.Error("Expected entries of the same group to be ignored")
+	}
+
+	// Records of undos are not undone in turn
+	createTestActivity(t, repo, "list-1", "user-1", models.ActivityUndone, nil)
+	latest, err := repo.GetLatestUndoable("list-1", "user-1")
+	if err != nil {
+		t.Fatalf("Failed to get latest activity: %v", err)
+	}
+	if latest.ID != single.ID {
+		t.Errorf("Expected the undo record to be skipped, got activity %d", latest.ID)
+	}
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+var ErrBudgetNotFound = errors.New("budget not found")
+var ErrBudgetExists = errors.New("budget already exists")
+
+type BudgetRepository struct {
+	db *db.DB
+}
+
+func NewBudgetRepository(database *db.DB) *BudgetRepository {
+	return &BudgetRepository{db: database}
+}
+
+func (r *BudgetRepository) GetByID(id string) (*models.Budget, error) {
+	var budget models.Budget
+	if err := r.db.First(&budget, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// Create adds a budget. There is at most one overall budget and one for
+// each category; another fails with ErrBudgetExists.
+func (r *BudgetRepository) Create(budget *models.Budget) error {
+	now := auth.GetCurrentTimestamp()
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Budget{})
+		if budget.CategoryID == nil {
+			query = query.Where("category_id IS NULL")
+		} else {
+			query = query.Where("category_id = ?", *budget.CategoryID)
+		}
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBudgetExists
+		}
+		return tx.Create(budget).Error
+	})
+}
+
+// UpdateAmount changes a budget's amount. Alerts start over so a raised
+// budget warns again when it is reached.
+func (r *BudgetRepository) UpdateAmount(id string, amount float64) error {
+	result := r.db.Model(&models.Budget{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"amount":        amount,
+			"alert_month":   "",
+			"alert_percent": 0,
+			"updated_at":    auth.GetCurrentTimestamp(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+func (r *BudgetRepository) Delete(id string) error {
+	result := r.db.Delete(&models.Budget{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// MarkAlerted records that the webhook was sent for budget id reaching
+// percent of its amount in month
+func (r *BudgetRepository) MarkAlerted(id, month string, percent int) error {
+	return r.db.Model(&models.Budget{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"alert_month":   month,
+			"alert_percent": percent,
+		}).Error
+}
+
+// budgetSpendRow is the spending on one category over a month
+type budgetSpendRow struct {
+	CategoryID string
+	Spent      float64
+}
+
+// Status reports every budget against what the household has spent in the
+// calendar month containing now, in now's location: the price times quantity
+// of items bought that month on any list, as they were when checked off, so
+// unchecking, editing, trashing or purging items later doesn't change it. The
+// overall budget comes first, then category budgets by category order.
+func (r *BudgetRepository) Status(now time.Time) ([]models.BudgetStatus, error) {
+	var budgets []struct {
+		models.Budget
+		CategoryName *string
+	}
+	err := r.db.Table("budgets b").
+		Select("b.*, c.name AS category_name").
+		Joins("LEFT JOIN categories c ON c.id = b.category_id").
+		Order("b.category_id IS NOT NULL, c.sort_order ASC, c.name ASC").
+		Scan(&budgets).Error
+	if err != nil {
+		return nil, err
+	}
+	statuses := []models.BudgetStatus{}
+	if len(budgets) == 0 {
+		return statuses, nil
+	}
+
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 1, 0)
+	var rows []budgetSpendRow
+	err = r.db.Model(&models.Purchase{}).
+		Select("category_id, SUM(price * quantity) AS spent").
+		Where("checked_at >= ? AND checked_at < ?", start.UnixMilli(), end.UnixMilli()).
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var total float64
+	byCategory := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		total += row.Spent
+		byCategory[row.CategoryID] = row.Spent
+	}
+
+	month := start.Format("2006-01")
+	for _, budget := range budgets {
+		status := models.BudgetStatus{
+			BudgetID:   budget.ID,
+			CategoryID: budget.CategoryID,
+			Month:      month,
+			Amount:     budget.Amount,
+			Spent:      roundCents(total),
+		}
+		if budget.CategoryID != nil {
+			status.Spent = roundCents(byCategory[*budget.CategoryID])
+			if budget.CategoryName != nil {
+				status.Category = *budget.CategoryName
+			}
+		}
+		status.Remaining = roundCents(status.Amount - status.Spent)
+		if status.Amount > 0 {
+			status.Percent = roundCents(status.Spent / status.Amount * 100)
+		}
+		if budget.AlertMonth == month {
+			status.Alerted = budget.AlertPercent
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func TestBudgetRepository_CRUD(t *testing.T) {
+	itemRepo, _, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewBudgetRepository(itemRepo.db)
+	createTestCategory(t, catRepo, "dairy", "Dairy")
+
+	overall := &models.Budget{ID: "budget-1", Amount: 400}
+	dairy := &models.Budget{ID: "budget-2", CategoryID: strPtr("dairy"), Amount: 50}
+	for _, budget := range []*models.Budget{overall, dairy} {
+		if err := repo.Create(budget); err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+	}
+	if err := repo.Create(&models.Budget{ID: "budget-3", Amount: 500}); err != ErrBudgetExists {
+		t.Errorf("Expected a second overall budget refused, got %v", err)
+	}
+	if err := repo.Create(&models.Budget{ID: "budget-4", CategoryID: strPtr("dairy"), Amount: 60}); err != ErrBudgetExists {
+		t.Errorf("Expected a second dairy budget refused, got %v", err)
+	}
+
+	if err := repo.MarkAlerted("budget-2", "2024-03", 80); err != nil {
+		t.Fatalf("Failed to mark alerted: %v", err)
+	}
+	if err := repo.UpdateAmount("budget-2", 75); err != nil {
+		t.Fatalf("Failed to update budget: %v", err)
+	}
+	updated, _ := repo.GetByID("budget-2")
+	if updated.Amount != 75 || updated.AlertPercent != 0 || updated.AlertMonth != "" {
+		t.Errorf("Expected the amount changed and alerts reset, got %+v", updated)
+	}
+	if err := repo.UpdateAmount("missing", 10); err != ErrBudgetNotFound {
+		t.Errorf("Expected ErrBudgetNotFound, got %v", err)
+	}
+
+	// Deleting the category removes its budget
+	if err := catRepo.Delete("dairy"); err != nil {
+		t.Fatalf("Failed to delete category: %v", err)
+	}
+	if _, err := repo.GetByID("budget-2"); err != ErrBudgetNotFound {
+		t.Errorf("Expected the category budget removed, got %v", err)
+	}
+
+	if err := repo.Delete("budget-1"); err != nil {
+		t.Fatalf("Failed to delete budget: %v", err)
+	}
+	if err := repo.Delete("budget-1"); err != ErrBudgetNotFound {
+		t.Errorf("Expected ErrBudgetNotFound, got %v", err)
+	}
+}
+
+func TestBudgetRepository_Status(t *testing.T) {
+	itemRepo, listRepo, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewBudgetRepository(itemRepo.db)
+	createTestCategory(t, catRepo, "dairy", "Dairy")
+
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	statuses, err := repo.Status(now)
+	if err != nil || len(statuses) != 0 {
+		t.Fatalf("Expected no budgets, got %+v, %v", statuses, err)
+	}
+
+	for _, budget := range []*models.Budget{
+		{ID: "budget-2", CategoryID: strPtr("dairy"), Amount: 20},
+		{ID: "budget-1", Amount: 100},
+	} {
+		if err := repo.Create(budget); err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+	}
+
+	// Private lists count toward the household budget
+	private := &models.List{ID: "list-2", Name: "Private", OwnerID: strPtr("user-1"), CreatedAt: 1000, UpdatedAt: 1000}
+	if err := listRepo.Create(private); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	march := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC).UnixMilli()
+	february := time.Date(2024, 2, 28, 9, 0, 0, 0, time.UTC).UnixMilli()
+	buy := func(id, listID string, quantity int, price float64, categoryID string, checkedAt int64) {
+		item := &models.Item{ID: id, ListID: listID, Name: id, Quantity: quantity, Price: &price, CategoryID: categoryID}
+		if err := itemRepo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+		if _, err := itemRepo.ToggleChecked(id, "user-1", "Test User", nil); err != nil {
+			t.Fatalf("Failed to check item: %v", err)
+		}
+		itemRepo.db.Model(&models.Purchase{}).Where("item_id = ?", id).UpdateColumn("checked_at", checkedAt)
+	}
+	buy("milk", "list-1", 4, 2.5, "dairy", march)
+	buy("cheese", "list-2", 1, 7, "dairy", march)
+	buy("bread", "list-1", 2, 3, "test-cat", march)
+	buy("old", "list-1", 1, 50, "test-cat", february)
+
+	if err := repo.MarkAlerted("budget-2", "2024-03", 80); err != nil {
+		t.Fatalf("Failed to mark alerted: %v", err)
+	}
+	if err := repo.MarkAlerted("budget-1", "2024-02", 100); err != nil {
+		t.Fatalf("Failed to mark alerted: %v", err)
+	}
+
+	statuses, err = repo.Status(now)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses, got %+v", statuses)
+	}
+	if s := statuses[0]; s.BudgetID != "budget-1" || s.Month != "2024-03" || s.Spent != 23 || s.Remaining != 77 || s.Percent != 23 || s.Alerted != 0 {
+		t.Errorf("Expected the overall budget first with last month's alert ignored, got %+v", s)
+	}
+	if s := statuses[1]; s.Category != "Dairy" || s.Spent != 17 || s.Remaining != 3 || s.Percent != 85 || s.Alerted != 80 {
+		t.Errorf("Expected dairy at 85%%, got %+v", s)
+	}
+
+	// Reusing or trashing what was bought doesn't give the money back
+	if _, err := itemRepo.ToggleChecked("milk", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to uncheck item: %v", err)
+	}
+	if err := itemRepo.Delete("cheese"); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := itemRepo.PurgeDeletedBefore(time.Now().Add(time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("Failed to purge items: %v", err)
+	}
+	statuses, _ = repo.Status(now)
+	if statuses[0].Spent != 23 || statuses[1].Spent != 17 {
+		t.Errorf("Expected spending unchanged, got %v and %v", statuses[0].Spent, statuses[1].Spent)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
 )
@@ -22,9 +23,23 @@ func NewCategoryRepository(database *db.DB) *CategoryRepository {
 }
 
 func (r *CategoryRepository) Create(category *models.Category) error {
+	category.UpdatedAt = auth.GetCurrentTimestamp()
 	return r.db.Create(category).Error
 }
 
+// GetChangedSince returns categories updated at or after since
+func (r *CategoryRepository) GetChangedSince(since int64) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Where("updated_at >= ?", since).Order("sort_order ASC").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+	return categories, nil
+}
+
 func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
 	err := r.db.Order("sort_order ASC").Find(&categories).Error
@@ -46,6 +61,15 @@ func (r *CategoryRepository) GetByID(id string) (*models.Category, error) {
 	return &category, nil
 }
 
+// Exists reports whether a category with the ID exists
+func (r *CategoryRepository) Exists(id string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.Category{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (r *CategoryRepository) Update(id string, name, icon, color *string, sortOrder *int) error {
 	// Check if it's a default category
 	cat, err := r.GetByID(id)
@@ -74,6 +98,7 @@ func (r *CategoryRepository) Update(id string, name, icon, color *string, sortOr
 	if len(updates) == 0 {
 		return nil // Nothing to update
 	}
+	updates["updated_at"] = auth.GetCurrentTimestamp()
 
 	result := r.db.Model(&models.Category{}).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
@@ -96,15 +121,20 @@ func (r *CategoryRepository) Delete(id string) error {
 		return ErrCannotDeleteDefault
 	}
 
-	result := r.db.Delete(&models.Category{}, "id = ?", id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrCategoryNotFound
-	}
-
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Whatever used the category falls back to Other
+		if _, err := db.MoveToOtherCategory(tx, "category_id = ?", id); err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Category{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCategoryNotFound
+		}
+		return recordTombstone(tx, models.EntityCategory, id, nil)
+	})
 }
 
 func (r *CategoryRepository) GetMaxSortOrder() (int, error) {
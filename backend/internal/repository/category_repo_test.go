@@ -3,6 +3,9 @@ package repository
 import (
 	"testing"
 
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
 )
 
@@ -297,3 +300,96 @@ func TestCategoryRepository_GetMaxSortOrder(t *testing.T) {
 		t.Errorf("Expected max sort order %d, got %d", initialMax+10, newMax)
 	}
 }
+
+func TestCategoryRepository_Exists(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCategoryRepository(database)
+	createTestCategory(t, repo, "cat-1", "Custom")
+
+	if exists, err := repo.Exists("cat-1"); err != nil || !exists {
+		t.Errorf("Expected cat-1 to exist, got %v, %v", exists, err)
+	}
+	if exists, err := repo.Exists("missing"); err != nil || exists {
+		t.Errorf("Expected missing category not to exist, got %v, %v", exists, err)
+	}
+}
+
+func TestRepairOrphanedCategories(t *testing.T) {
+	repo, _, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createTestCategory(t, catRepo, db.OtherCategoryID, "Other")
+
+	for _, id := range []string{"item-1", "item-2"} {
+		item := &models.Item{ID: id, ListID: "list-1", Name: id, Quantity: 1, CategoryID: "test-cat"}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	// Older versions stored "other", which foreign keys would now refuse
+	err := repo.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+		return conn.Exec("UPDATE items SET category_id = 'other' WHERE id = 'item-1'").Error
+	})
+	if err != nil {
+		t.Fatalf("Failed to orphan item: %v", err)
+	}
+
+	repaired, err := repo.db.RepairOrphanedCategories()
+	if err != nil {
+		t.Fatalf("Failed to repair categories: %v", err)
+	}
+	if repaired != 1 {
+		t.Errorf("Expected 1 repaired item, got %d", repaired)
+	}
+
+	orphan, _ := repo.GetByID("item-1")
+	if orphan.CategoryID != db.OtherCategoryID || orphan.Version != 2 {
+		t.Errorf("Expected item-1 moved to Other at version 2, got %q at %d", orphan.CategoryID, orphan.Version)
+	}
+	untouched, _ := repo.GetByID("item-2")
+	if untouched.CategoryID != "test-cat" || untouched.Version != 1 {
+		t.Errorf("Expected item-2 unchanged, got %q at %d", untouched.CategoryID, untouched.Version)
+	}
+
+	// The repair only runs once
+	repaired, err = repo.db.RepairOrphanedCategories()
+	if err != nil || repaired != 0 {
+		t.Errorf("Expected nothing repaired the second time, got %d, %v", repaired, err)
+	}
+}
+
+func TestCategoryRepository_DeleteMovesToOther(t *testing.T) {
+	itemRepo, _, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createTestCategory(t, catRepo, db.OtherCategoryID, "Other")
+	createTestCategory(t, catRepo, "snacks", "Snacks")
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Chips", Quantity: 1, CategoryID: "snacks"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	pantry := &models.PantryItem{ID: "pantry-1", Name: "Chips", CategoryID: "snacks"}
+	if err := itemRepo.db.Create(pantry).Error; err != nil {
+		t.Fatalf("Failed to create pantry item: %v", err)
+	}
+
+	if err := catRepo.Delete("snacks"); err != nil {
+		t.Fatalf("Failed to delete category: %v", err)
+	}
+
+	moved, _ := itemRepo.GetByID("item-1")
+	if moved.CategoryID != db.OtherCategoryID || moved.Version != 2 {
+		t.Errorf("Expected the item moved to Other at version 2, got %q at %d", moved.CategoryID, moved.Version)
+	}
+	var stored models.PantryItem
+	itemRepo.db.First(&stored, "id = ?", "pantry-1")
+	if stored.CategoryID != db.OtherCategoryID {
+		t.Errorf("Expected the pantry item moved to Other, got %q", stored.CategoryID)
+	}
+}
@@ -2,11 +2,14 @@ package repository
 
 import (
 	"errors"
+	"strings"
 
 	"gorm.io/gorm"
 
+	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/units"
 )
 
 var ErrItemNotFound = errors.New("item not found")
@@ -22,21 +25,168 @@ func NewItemRepository(database *db.DB) *ItemRepository {
 
 func (r *ItemRepository) Create(item *models.Item) error {
 	item.Version = 1 // Initial version
+	item.UpdatedAt = auth.GetCurrentTimestamp()
 	return r.db.Create(item).Error
 }
 
+// MostUsedCategory returns the category most often given to items with this
+// name (ignoring case), preferring the most recent on ties. Items in the
+// catch-all Other category are not counted. It returns "" when there is no
+// such item.
+func (r *ItemRepository) MostUsedCategory(name string) (string, error) {
+	var categoryIDs []string
+	err := r.db.Table("items i").
+		Joins("JOIN categories c ON c.id = i.category_id").
+		Where("LOWER(i.name) = LOWER(?) AND i.category_id <> ?", strings.TrimSpace(name), db.OtherCategoryID).
+		Group("i.category_id").
+		Order("COUNT(*) DESC, MAX(i.updated_at) DESC").
+		Limit(1).
+		Pluck("i.category_id", &categoryIDs).Error
+	if err != nil {
+		return "", err
+	}
+	if len(categoryIDs) == 0 {
+		return "", nil
+	}
+	return categoryIDs[0], nil
+}
+
+// suggestionHalfLife is how long a use takes to count half as much in
+// suggestion ranking
+const suggestionHalfLife = 30 * 24 * 60 * 60 * 1000
+
+// Suggest returns item names from the lists the user can see, trash
+// included, whose words start with the words of query. Names are matched
+// case-insensitively and ranked by how often and how recently they were used;
+// each carries the fields of its latest use.
+func (r *ItemRepository) Suggest(userID, query string, now int64, limit int) ([]models.ItemSuggestion, error) {
+	suggestions := []models.ItemSuggestion{}
+	match := ftsPrefixQuery(query)
+	if match == "" {
+		return suggestions, nil
+	}
+
+	err := r.db.Raw(`
+		WITH matched AS (
+			SELECT i.name, i.category_id, i.unit, i.store, i.price, i.updated_at,
+				LOWER(TRIM(i.name)) AS name_key,
+				ROW_NUMBER() OVER (PARTITION BY LOWER(TRIM(i.name)) ORDER BY i.updated_at DESC, i.id DESC) AS position
+			FROM items_fts
+			JOIN items i ON i.id = items_fts.id
+			JOIN lists l ON l.id = i.list_id
+			LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = ?
+			WHERE items_fts MATCH ? AND m.id IS NOT NULL
+		),
+		stats AS (
+			SELECT name_key, COUNT(*) AS uses, MAX(updated_at) AS last_used_at,
+				SUM(1.0 / (1 + MAX(? - updated_at, 0) / ?)) AS score
+			FROM matched
+			GROUP BY name_key
+		)
+		SELECT latest.name, latest.category_id, latest.unit, latest.store, latest.price,
+			stats.uses, stats.last_used_at
+		FROM matched latest
+		JOIN stats ON stats.name_key = latest.name_key
+		WHERE latest.position = 1
+		ORDER BY stats.score DESC, stats.last_used_at DESC
+		LIMIT ?`,
+		userID, match, now, float64(suggestionHalfLife), limit).
+		Scan(&suggestions).Error
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// GetExpiring returns checked items, outside the trash, whose best-before
+// date is by until, soonest first
+func (r *ItemRepository) GetExpiring(until int64) ([]models.Item, error) {
+	return r.expiring(r.db.Table("items i").Joins("JOIN lists l ON l.id = i.list_id"), until)
+}
+
+// GetExpiringForUser is GetExpiring limited to the lists the user can see
+func (r *ItemRepository) GetExpiringForUser(userID string, until int64) ([]models.Item, error) {
+	query := r.db.Table("items i").
+		Joins("JOIN lists l ON l.id = i.list_id").
+		Joins("LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = ?", userID).
+		Where("m.id IS NOT NULL")
+	return r.expiring(query, until)
+}
+
+func (r *ItemRepository) expiring(query *gorm.DB, until int64) ([]models.Item, error) {
+	items := []models.Item{}
+	err := query.Select("i.*").
+		Where("i.checked = ? AND i.deleted_at IS NULL AND l.deleted_at IS NULL", true).
+		Where("i.expires_at IS NOT NULL AND i.expires_at <= ?", until).
+		Order("i.expires_at ASC, LOWER(i.name) ASC").
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreateMany appends items to a list in one transaction, in the given order,
+// and bumps the list version once
+func (r *ItemRepository) CreateMany(listID string, items []models.Item) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		txRepo := &ItemRepository{db: txDB}
+		maxOrder, err := txRepo.GetMaxSortOrder(listID)
+		if err != nil {
+			return err
+		}
+
+		for i := range items {
+			maxOrder++
+			items[i].ListID = listID
+			items[i].SortOrder = maxOrder
+			if err := txRepo.Create(&items[i]); err != nil {
+				return err
+			}
+		}
+
+		return NewListRepository(txDB).TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
+	})
+}
+
 func (r *ItemRepository) GetByListID(listID string) ([]models.Item, error) {
 	var items []models.Item
-	err := r.db.Where("list_id = ?", listID).Order("sort_order ASC").Find(&items).Error
+	err := r.db.Where("list_id = ? AND deleted_at IS NULL", listID).Order("sort_order ASC").Find(&items).Error
 	if err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+// GetChangedSinceForUser returns items changed at or after since in lists
+// the user can see
+func (r *ItemRepository) GetChangedSinceForUser(userID string, since int64) ([]models.Item, error) {
+	var items []models.Item
+	err := r.db.Table("items i").
+		Select("i.*").
+		Joins("JOIN lists l ON l.id = i.list_id").
+		Joins("LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = ?", userID).
+		Where("i.updated_at >= ? AND i.deleted_at IS NULL AND l.deleted_at IS NULL", since).
+		Where("m.id IS NOT NULL").
+		Order("i.updated_at ASC").
+		Scan(&items).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if items == nil {
+		items = []models.Item{}
+	}
+
+	return items, nil
+}
+
+// GetByID returns an item that is not in the trash
 func (r *ItemRepository) GetByID(id string) (*models.Item, error) {
 	var item models.Item
-	err := r.db.First(&item, "id = ?", id).Error
+	err := r.db.First(&item, "id = ? AND deleted_at IS NULL", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrItemNotFound
@@ -47,16 +197,20 @@ func (r *ItemRepository) GetByID(id string) (*models.Item, error) {
 }
 
 func (r *ItemRepository) Update(item *models.Item) error {
+	now := auth.GetCurrentTimestamp()
 	result := r.db.Model(&models.Item{}).
-		Where("id = ?", item.ID).
+		Where("id = ? AND deleted_at IS NULL", item.ID).
 		Updates(map[string]interface{}{
-			"name":        item.Name,
-			"quantity":    item.Quantity,
-			"unit":        item.Unit,
-			"category_id": item.CategoryID,
-			"price":       item.Price,
-			"store":       item.Store,
-			"version":     gorm.Expr("version + 1"),
+			"name":         item.Name,
+			"quantity":     item.Quantity,
+			"unit":         item.Unit,
+			"category_id":  item.CategoryID,
+			"price":        item.Price,
+			"package_size": item.PackageSize,
+			"package_unit": item.PackageUnit,
+			"store":        item.Store,
+			"version":      gorm.Expr("version + 1"),
+			"updated_at":   now,
 		})
 
 	if result.Error != nil {
@@ -66,21 +220,28 @@ func (r *ItemRepository) Update(item *models.Item) error {
 		return ErrItemNotFound
 	}
 
+	item.Version++
+	item.UpdatedAt = now
+
 	return nil
 }
 
 // UpdateWithVersion updates an item only if the version matches (optimistic locking)
 func (r *ItemRepository) UpdateWithVersion(item *models.Item, expectedVersion int) error {
+	now := auth.GetCurrentTimestamp()
 	result := r.db.Model(&models.Item{}).
-		Where("id = ? AND version = ?", item.ID, expectedVersion).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", item.ID, expectedVersion).
 		Updates(map[string]interface{}{
-			"name":        item.Name,
-			"quantity":    item.Quantity,
-			"unit":        item.Unit,
-			"category_id": item.CategoryID,
-			"price":       item.Price,
-			"store":       item.Store,
-			"version":     gorm.Expr("version + 1"),
+			"name":         item.Name,
+			"quantity":     item.Quantity,
+			"unit":         item.Unit,
+			"category_id":  item.CategoryID,
+			"price":        item.Price,
+			"package_size": item.PackageSize,
+			"package_unit": item.PackageUnit,
+			"store":        item.Store,
+			"version":      gorm.Expr("version + 1"),
+			"updated_at":   now,
 		})
 
 	if result.Error != nil {
@@ -89,17 +250,72 @@ func (r *ItemRepository) UpdateWithVersion(item *models.Item, expectedVersion in
 	if result.RowsAffected == 0 {
 		// Check if the item exists
 		var count int64
-		r.db.Model(&models.Item{}).Where("id = ?", item.ID).Count(&count)
+		r.db.Model(&models.Item{}).Where("id = ? AND deleted_at IS NULL", item.ID).Count(&count)
 		if count > 0 {
 			return ErrItemVersionConflict
 		}
 		return ErrItemNotFound
 	}
 
+	item.Version = expectedVersion + 1
+	item.UpdatedAt = now
+
 	return nil
 }
 
-func (r *ItemRepository) ToggleChecked(id string, userID string, userName string) (*models.Item, error) {
+// RestoreSnapshot writes back the editable fields and checked state of an
+// earlier copy of an item, bumping its version. Used to undo changes, so
+// taking back a check also takes back the purchase it recorded.
+func (r *ItemRepository) RestoreSnapshot(snapshot *models.Item) (*models.Item, error) {
+	current, err := r.GetByID(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Item{}).
+			Where("id = ? AND deleted_at IS NULL", snapshot.ID).
+			Updates(map[string]interface{}{
+				"name":            snapshot.Name,
+				"quantity":        snapshot.Quantity,
+				"unit":            snapshot.Unit,
+				"category_id":     snapshot.CategoryID,
+				"price":           snapshot.Price,
+				"package_size":    snapshot.PackageSize,
+				"package_unit":    snapshot.PackageUnit,
+				"store":           snapshot.Store,
+				"checked":         snapshot.Checked,
+				"checked_by":      snapshot.CheckedBy,
+				"checked_by_name": snapshot.CheckedByName,
+				"checked_at":      snapshot.CheckedAt,
+				"expires_at":      snapshot.ExpiresAt,
+				"version":         gorm.Expr("version + 1"),
+				"updated_at":      auth.GetCurrentTimestamp(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrItemNotFound
+		}
+
+		if current.CheckedAt != nil && (snapshot.CheckedAt == nil || *snapshot.CheckedAt != *current.CheckedAt) {
+			return tx.Where("item_id = ? AND checked_at = ?", current.ID, *current.CheckedAt).Delete(&models.Purchase{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(snapshot.ID)
+}
+
+// ToggleChecked checks or unchecks an item. Checking records who checked it
+// and when, and the best-before date, if any, and adds the item's price to
+// the price history and, as bought, to the purchases; unchecking clears the
+// item's fields but keeps what was bought.
+func (r *ItemRepository) ToggleChecked(id string, userID string, userName string, expiresAt *int64) (*models.Item, error) {
 	// First get the current state
 	item, err := r.GetByID(id)
 	if err != nil {
@@ -107,28 +323,46 @@ func (r *ItemRepository) ToggleChecked(id string, userID string, userName string
 	}
 
 	newChecked := !item.Checked
+	now := auth.GetCurrentTimestamp()
 
 	// Prepare updates
 	updates := map[string]interface{}{
-		"checked": newChecked,
-		"version": gorm.Expr("version + 1"),
+		"checked":    newChecked,
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": now,
 	}
 
 	// If checking, set the user info; if unchecking, clear it
 	if newChecked {
 		updates["checked_by"] = userID
 		updates["checked_by_name"] = userName
+		updates["checked_at"] = now
+		updates["expires_at"] = expiresAt
 	} else {
 		updates["checked_by"] = nil
 		updates["checked_by_name"] = nil
+		updates["checked_at"] = nil
+		updates["expires_at"] = nil
 	}
 
-	result := r.db.Model(&models.Item{}).Where("id = ?", id).Updates(updates)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	if result.RowsAffected == 0 {
-		return nil, ErrItemNotFound
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Item{}).Where("id = ?", id).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrItemNotFound
+		}
+		if !newChecked {
+			return nil
+		}
+		if err := recordCheckedPrice(tx, item, now); err != nil {
+			return err
+		}
+		return recordPurchase(tx, item, userID, userName, now)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Update the item struct and return it
@@ -136,24 +370,477 @@ func (r *ItemRepository) ToggleChecked(id string, userID string, userName string
 	if newChecked {
 		item.CheckedBy = &userID
 		item.CheckedByName = &userName
+		item.CheckedAt = &now
+		item.ExpiresAt = expiresAt
 	} else {
 		item.CheckedBy = nil
 		item.CheckedByName = nil
+		item.CheckedAt = nil
+		item.ExpiresAt = nil
 	}
 	item.Version++
+	item.UpdatedAt = now
 
 	return item, nil
 }
 
+// Delete moves an item to the trash and leaves a tombstone for delta sync
 func (r *ItemRepository) Delete(id string) error {
-	result := r.db.Delete(&models.Item{}, "id = ?", id)
-	if result.Error != nil {
-		return result.Error
+	now := auth.GetCurrentTimestamp()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var item models.Item
+		if err := tx.Select("id", "list_id").First(&item, "id = ? AND deleted_at IS NULL", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrItemNotFound
+			}
+			return err
+		}
+
+		err := tx.Model(&models.Item{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"deleted_at": now,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return recordTombstone(tx, models.EntityItem, id, &item.ListID)
+	})
+}
+
+// GetTrashedByID returns an item that is in the trash
+func (r *ItemRepository) GetTrashedByID(id string) (*models.Item, error) {
+	var item models.Item
+	err := r.db.First(&item, "id = ? AND deleted_at IS NOT NULL", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrItemNotFound
+		}
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return ErrItemNotFound
+	return &item, nil
+}
+
+// GetTrashedForUser returns trashed items in lists the user can edit that
+// are not themselves in the trash, most recently deleted first
+func (r *ItemRepository) GetTrashedForUser(userID string) ([]models.Item, error) {
+	var items []models.Item
+	err := r.db.Table("items i").
+		Select("i.*").
+		Joins("JOIN lists l ON l.id = i.list_id AND l.deleted_at IS NULL").
+		Joins("LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = ?", userID).
+		Where("i.deleted_at IS NOT NULL").
+		Where("m.role IN ?", []models.ListRole{models.ListRoleEditor, models.ListRoleOwner}).
+		Order("i.deleted_at DESC").
+		Scan(&items).Error
+
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	if items == nil {
+		items = []models.Item{}
+	}
+
+	return items, nil
+}
+
+// Restore takes an item out of the trash
+func (r *ItemRepository) Restore(id string) (*models.Item, error) {
+	now := auth.GetCurrentTimestamp()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Item{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrItemNotFound
+		}
+		return clearTombstones(tx, models.EntityItem, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// PurgeDeletedBefore permanently deletes items trashed before the cutoff and
+// returns how many were removed
+func (r *ItemRepository) PurgeDeletedBefore(cutoff int64) (int64, error) {
+	result := r.db.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Item{})
+	return result.RowsAffected, result.Error
+}
+
+// MergedItem is an item already on a list that another was merged into, as
+// it was before and after
+type MergedItem struct {
+	Before models.Item
+	After  models.Item
+}
+
+// MergeIntoList adds items to a list in one transaction. An item whose name
+// matches an unchecked item already on the list (ignoring case) and whose
+// unit is compatible, such as g and kg, adds its quantity to that item; the
+// rest are appended after the existing items. When the sum moves the item to
+// another unit its price and package size, which are per unit, move with it.
+// IDs, versions and sort orders of the given items are assigned here.
+func (r *ItemRepository) MergeIntoList(listID string, items []models.Item) (created []models.Item, merged []MergedItem, err error) {
+	now := auth.GetCurrentTimestamp()
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := &ItemRepository{db: &db.DB{DB: tx}}
+		maxOrder, err := txRepo.GetMaxSortOrder(listID)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			var candidates []models.Item
+			if err := tx.Where("list_id = ? AND deleted_at IS NULL AND checked = ? AND LOWER(name) = LOWER(?)", listID, false, item.Name).
+				Order("sort_order ASC").
+				Find(&candidates).Error; err != nil {
+				return err
+			}
+
+			if existing, ok := mergeTarget(candidates, &item); ok {
+				before := existing
+				quantity, unit, _ := units.Add(float64(existing.Quantity), unitOf(existing.Unit), float64(item.Quantity), unitOf(item.Unit))
+				rescalePerUnit(&existing, unit)
+				existing.Quantity = quantity
+				existing.Unit = nilIfEmpty(unit)
+				existing.Version++
+				existing.UpdatedAt = now
+				if err := tx.Model(&existing).Updates(map[string]interface{}{
+					"quantity":     existing.Quantity,
+					"unit":         existing.Unit,
+					"price":        existing.Price,
+					"package_size": existing.PackageSize,
+					"version":      existing.Version,
+					"updated_at":   now,
+				}).Error; err != nil {
+					return err
+				}
+				merged = append(merged, MergedItem{Before: before, After: existing})
+				continue
+			}
+
+			maxOrder++
+			item.ID = auth.GenerateID()
+			item.ListID = listID
+			item.Checked = false
+			item.CheckedBy = nil
+			item.CheckedByName = nil
+			item.CheckedAt = nil
+			item.SortOrder = maxOrder
+			item.DeletedAt = nil
+			if err := txRepo.Create(&item); err != nil {
+				return err
+			}
+			created = append(created, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, merged, nil
+}
+
+// mergeTarget picks the first candidate whose unit item can be added to
+func mergeTarget(candidates []models.Item, item *models.Item) (models.Item, bool) {
+	for _, candidate := range candidates {
+		if units.Compatible(unitOf(candidate.Unit), unitOf(item.Unit)) {
+			return candidate, true
+		}
+	}
+	return models.Item{}, false
+}
+
+// rescalePerUnit expresses item's price and package size, which are per unit
+// of its quantity, per unit of to instead, so 3 per kg becomes 0.003 per g
+func rescalePerUnit(item *models.Item, to string) {
+	factor, ok := units.Convert(1, to, unitOf(item.Unit))
+	if !ok || factor == 1 {
+		return
+	}
+	if item.Price != nil {
+		price := *item.Price * factor
+		item.Price = &price
+	}
+	if item.PackageSize != nil {
+		size := *item.PackageSize * factor
+		item.PackageSize = &size
+	}
+}
+
+func unitOf(unit *string) string {
+	if unit == nil {
+		return ""
+	}
+	return *unit
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// MoveUnchecked moves the unchecked items of one list to the end of another
+// in one transaction, keeping their relative order, and bumps both list
+// versions once. It returns the moved items.
+func (r *ItemRepository) MoveUnchecked(fromListID, toListID string) ([]models.Item, error) {
+	if fromListID == toListID {
+		return []models.Item{}, nil
+	}
+
+	now := auth.GetCurrentTimestamp()
+	var moved []models.Item
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		lists := NewListRepository(txDB)
+		if _, err := lists.GetByID(toListID); err != nil {
+			return err
+		}
+
+		if err := tx.Where("list_id = ? AND deleted_at IS NULL AND checked = ?", fromListID, false).
+			Order("sort_order ASC").
+			Find(&moved).Error; err != nil {
+			return err
+		}
+
+		maxOrder, err := (&ItemRepository{db: txDB}).GetMaxSortOrder(toListID)
+		if err != nil {
+			return err
+		}
+
+		for i := range moved {
+			maxOrder++
+			moved[i].ListID = toListID
+			moved[i].SortOrder = maxOrder
+			moved[i].Version++
+			moved[i].UpdatedAt = now
+			if err := tx.Model(&models.Item{}).Where("id = ?", moved[i].ID).Updates(map[string]interface{}{
+				"list_id":    toListID,
+				"sort_order": maxOrder,
+				"version":    moved[i].Version,
+				"updated_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(moved) > 0 {
+			ids := make([]string, len(moved))
+			for i := range moved {
+				ids[i] = moved[i].ID
+			}
+			if err := recordMoveTombstones(tx, ids, fromListID, toListID); err != nil {
+				return err
+			}
+		}
+
+		if err := lists.TouchUpdatedAt(fromListID, now); err != nil {
+			return err
+		}
+		return lists.TouchUpdatedAt(toListID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// BulkSetChecked checks or unchecks the selected items that are not already in
+// that state, recording the prices and purchases of checked items like
+// ToggleChecked. It
+// returns the changed items.
+func (r *ItemRepository) BulkSetChecked(listID string, sel models.ItemSelection, checked bool, userID, userName string) ([]models.Item, error) {
+	return r.bulk(listID, sel.ItemIDs, itemFilter(sel.Filter, "checked = ?", !checked), func(tx *gorm.DB, ids []string, now int64) error {
+		updates := map[string]interface{}{
+			"checked":         checked,
+			"checked_by":      nil,
+			"checked_by_name": nil,
+			"checked_at":      nil,
+			"expires_at":      nil,
+			"version":         gorm.Expr("version + 1"),
+			"updated_at":      now,
+		}
+		if checked {
+			updates["checked_by"] = userID
+			updates["checked_by_name"] = userName
+			updates["checked_at"] = now
+		}
+		if err := tx.Model(&models.Item{}).Where("id IN ?", ids).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !checked {
+			return nil
+		}
+
+		var priced []models.Item
+		if err := tx.Where("id IN ? AND price IS NOT NULL", ids).Find(&priced).Error; err != nil {
+			return err
+		}
+		for i := range priced {
+			if err := recordCheckedPrice(tx, &priced[i], now); err != nil {
+				return err
+			}
+			if err := recordPurchase(tx, &priced[i], userID, userName, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BulkDelete moves the selected items to the trash, leaving tombstones for
+// delta sync. It returns the trashed items.
+func (r *ItemRepository) BulkDelete(listID string, sel models.ItemSelection) ([]models.Item, error) {
+	return r.bulk(listID, sel.ItemIDs, itemFilter(sel.Filter, "", nil), func(tx *gorm.DB, ids []string, now int64) error {
+		err := tx.Model(&models.Item{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"deleted_at": now,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := recordTombstone(tx, models.EntityItem, id, &listID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BulkMove moves the selected items to the end of another list, keeping their
+// order. It returns the moved items.
+func (r *ItemRepository) BulkMove(listID string, sel models.ItemSelection, toListID string) ([]models.Item, error) {
+	return r.bulk(listID, sel.ItemIDs, itemFilter(sel.Filter, "", nil), func(tx *gorm.DB, ids []string, now int64) error {
+		txDB := &db.DB{DB: tx}
+		if _, err := NewListRepository(txDB).GetByID(toListID); err != nil {
+			return err
+		}
+		maxOrder, err := (&ItemRepository{db: txDB}).GetMaxSortOrder(toListID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			maxOrder++
+			err := tx.Model(&models.Item{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"list_id":    toListID,
+				"sort_order": maxOrder,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return recordMoveTombstones(tx, ids, listID, toListID)
+	}, toListID)
+}
+
+// BulkSetCategory moves the selected items into a category. It returns the
+// items whose category changed.
+func (r *ItemRepository) BulkSetCategory(listID string, sel models.ItemSelection, categoryID string) ([]models.Item, error) {
+	return r.bulk(listID, sel.ItemIDs, itemFilter(sel.Filter, "category_id <> ?", categoryID), func(tx *gorm.DB, ids []string, now int64) error {
+		var count int64
+		if err := tx.Model(&models.Category{}).Where("id = ?", categoryID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrCategoryNotFound
+		}
+		return tx.Model(&models.Item{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"category_id": categoryID,
+			"version":     gorm.Expr("version + 1"),
+			"updated_at":  now,
+		}).Error
+	})
+}
+
+// itemScope narrows an item query
+type itemScope func(tx *gorm.DB) *gorm.DB
+
+// itemFilter scopes a bulk selection to a named filter plus an optional
+// extra condition that skips items the operation would not change
+func itemFilter(filter string, condition string, arg interface{}) itemScope {
+	return func(tx *gorm.DB) *gorm.DB {
+		switch filter {
+		case models.ItemFilterChecked:
+			tx = tx.Where("checked = ?", true)
+		case models.ItemFilterUnchecked:
+			tx = tx.Where("checked = ?", false)
+		}
+		if condition != "" {
+			tx = tx.Where(condition, arg)
+		}
+		return tx
+	}
+}
+
+// bulk runs apply on the selected items of a list in one transaction, then
+// bumps the list version once, along with any other touched lists. The
+// selection is limited to itemIDs when given. It returns the selected items
+// as they are after apply.
+func (r *ItemRepository) bulk(listID string, itemIDs []string, scope itemScope, apply func(tx *gorm.DB, ids []string, now int64) error, touched ...string) ([]models.Item, error) {
+	now := auth.GetCurrentTimestamp()
+	items := []models.Item{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Item{}).Where("list_id = ? AND deleted_at IS NULL", listID)
+		if len(itemIDs) > 0 {
+			query = query.Where("id IN ?", itemIDs)
+		}
+
+		var ids []string
+		if err := scope(query).Order("sort_order ASC").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := apply(tx, ids, now); err != nil {
+			return err
+		}
+
+		lists := NewListRepository(&db.DB{DB: tx})
+		for _, id := range append([]string{listID}, touched...) {
+			if err := lists.TouchUpdatedAt(id, now); err != nil {
+				return err
+			}
+		}
+
+		var changed []models.Item
+		if err := tx.Where("id IN ?", ids).Find(&changed).Error; err != nil {
+			return err
+		}
+
+		// Keep the selection order
+		byID := make(map[string]models.Item, len(changed))
+		for _, item := range changed {
+			byID[item.ID] = item
+		}
+		for _, id := range ids {
+			items = append(items, byID[id])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 func (r *ItemRepository) GetMaxSortOrder(listID string) (int, error) {
@@ -172,10 +859,16 @@ func (r *ItemRepository) GetMaxSortOrder(listID string) (int, error) {
 	return *maxOrder, nil
 }
 
-func (r *ItemRepository) Reorder(itemIDs []string) error {
+// Reorder sets the sort order of the given items; IDs that do not belong
+// to the list are ignored
+func (r *ItemRepository) Reorder(listID string, itemIDs []string) error {
+	now := auth.GetCurrentTimestamp()
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		for i, id := range itemIDs {
-			if err := tx.Model(&models.Item{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+			err := tx.Model(&models.Item{}).
+				Where("id = ? AND list_id = ?", id, listID).
+				Updates(map[string]interface{}{"sort_order": i, "updated_at": now}).Error
+			if err != nil {
 				return err
 			}
 		}
@@ -1,12 +1,17 @@
 package repository
 
 import (
+	"math"
 	"testing"
 
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
 )
 
-func createTestList(t *testing.T, listRepo *ListRepository, id, name string) {
+// createTestList creates a list owned by ownerID, or by nobody when it is
+// empty
+func createTestList(t *testing.T, listRepo *ListRepository, id, name, ownerID string) {
 	list := &models.List{
 		ID:        id,
 		Name:      name,
@@ -14,6 +19,9 @@ func createTestList(t *testing.T, listRepo *ListRepository, id, name string) {
 		CreatedAt: 1000,
 		UpdatedAt: 1000,
 	}
+	if ownerID != "" {
+		list.OwnerID = &ownerID
+	}
 	if err := listRepo.Create(list); err != nil {
 		t.Fatalf("Failed to create test list: %v", err)
 	}
@@ -42,7 +50,7 @@ func setupItemTestDB(t *testing.T) (*ItemRepository, *ListRepository, *CategoryR
 
 	// Create test user, list, and category that items require
 	createTestUser(t, userRepo, "user-1", "testuser", "Test User")
-	createTestList(t, listRepo, "list-1", "Test List")
+	createTestList(t, listRepo, "list-1", "Test List", "user-1")
 	createTestCategory(t, catRepo, "test-cat", "Test Category")
 
 	return itemRepo, listRepo, catRepo, userRepo, cleanup
@@ -104,7 +112,7 @@ func TestItemRepository_GetByListID(t *testing.T) {
 	defer cleanup()
 
 	// Create additional list
-	createTestList(t, listRepo, "list-2", "Other List")
+	createTestList(t, listRepo, "list-2", "Other List", "user-1")
 
 	// Create items for list-1
 	for i := 0; i < 3; i++ {
@@ -218,6 +226,8 @@ func TestItemRepository_Update(t *testing.T) {
 	item.Name = "New Name"
 	item.Quantity = 5
 	item.Price = floatPtr(9.99)
+	item.PackageSize = floatPtr(750)
+	item.PackageUnit = strPtr("ml")
 
 	err := repo.Update(item)
 	if err != nil {
@@ -242,6 +252,11 @@ func TestItemRepository_Update(t *testing.T) {
 		t.Errorf("Expected version 2, got %d", updated.Version)
 	}
 
+	updated.SetUnitPrice()
+	if updated.UnitPrice == nil || updated.UnitPrice.Price != 13.32 || updated.UnitPrice.Per != "l" {
+		t.Errorf("Expected 13.32 per l, got %+v", updated.UnitPrice)
+	}
+
 	// Update non-existing item
 	item.ID = "non-existent"
 	err = repo.Update(item)
@@ -250,6 +265,51 @@ func TestItemRepository_Update(t *testing.T) {
 	}
 }
 
+func TestItemRepository_UpdateWithVersion(t *testing.T) {
+	repo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{
+		ID:         "item-1",
+		ListID:     "list-1",
+		Name:       "Original",
+		Quantity:   1,
+		CategoryID: "test-cat",
+	}
+	if err := repo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	// Matching version succeeds and advances the struct version
+	item.Name = "First Edit"
+	if err := repo.UpdateWithVersion(item, 1); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	if item.Version != 2 {
+		t.Errorf("Expected version 2 on struct, got %d", item.Version)
+	}
+
+	// A second writer still holding version 1 conflicts
+	stale := &models.Item{ID: "item-1", ListID: "list-1", Name: "Second Edit", Quantity: 1, CategoryID: "test-cat"}
+	if err := repo.UpdateWithVersion(stale, 1); err != ErrItemVersionConflict {
+		t.Errorf("Expected ErrItemVersionConflict, got %v", err)
+	}
+
+	current, err := repo.GetByID("item-1")
+	if err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+	if current.Name != "First Edit" {
+		t.Errorf("Expected name 'First Edit', got %s", current.Name)
+	}
+
+	// Non-existing item
+	stale.ID = "non-existent"
+	if err := repo.UpdateWithVersion(stale, 1); err != ErrItemNotFound {
+		t.Errorf("Expected ErrItemNotFound, got %v", err)
+	}
+}
+
 func TestItemRepository_ToggleChecked(t *testing.T) {
 	repo, _, _, _, cleanup := setupItemTestDB(t)
 	defer cleanup()
@@ -269,7 +329,7 @@ func TestItemRepository_ToggleChecked(t *testing.T) {
 	}
 
 	// Toggle to checked
-	toggled, err := repo.ToggleChecked("item-1", "user-1", "Test User")
+	toggled, err := repo.ToggleChecked("item-1", "user-1", "Test User", nil)
 	if err != nil {
 		t.Fatalf("Failed to toggle item: %v", err)
 	}
@@ -288,7 +348,7 @@ func TestItemRepository_ToggleChecked(t *testing.T) {
 	}
 
 	// Toggle back to unchecked
-	toggled, err = repo.ToggleChecked("item-1", "user-1", "Test User")
+	toggled, err = repo.ToggleChecked("item-1", "user-1", "Test User", nil)
 	if err != nil {
 		t.Fatalf("Failed to toggle item: %v", err)
 	}
@@ -304,12 +364,55 @@ func TestItemRepository_ToggleChecked(t *testing.T) {
 	}
 
 	// Toggle non-existing item
-	_, err = repo.ToggleChecked("non-existent", "user-1", "Test User")
+	_, err = repo.ToggleChecked("non-existent", "user-1", "Test User", nil)
 	if err != ErrItemNotFound {
 		t.Errorf("Expected ErrItemNotFound, got %v", err)
 	}
 }
 
+func TestItemRepository_GetChangedSinceForUser(t *testing.T) {
+	repo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	first := &models.Item{ID: "item-1", ListID: "list-1", Name: "Old", Quantity: 1, CategoryID: "test-cat"}
+	if err := repo.Create(first); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	// Backdate the first item so only the second counts as changed
+	if err := repo.db.Model(&models.Item{}).Where("id = ?", "item-1").Update("updated_at", 1000).Error; err != nil {
+		t.Fatalf("Failed to backdate item: %v", err)
+	}
+
+	second := &models.Item{ID: "item-2", ListID: "list-1", Name: "New", Quantity: 1, CategoryID: "test-cat"}
+	if err := repo.Create(second); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if second.UpdatedAt == 0 {
+		t.Error("Expected UpdatedAt to be set on create")
+	}
+
+	items, err := repo.GetChangedSinceForUser("user-1", 2000)
+	if err != nil {
+		t.Fatalf("Failed to get changed items: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "item-2" {
+		t.Fatalf("Expected only item-2, got %+v", items)
+	}
+
+	// Toggling bumps updated_at
+	if _, err := repo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+	items, err = repo.GetChangedSinceForUser("user-1", 2000)
+	if err != nil {
+		t.Fatalf("Failed to get changed items: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("Expected 2 changed items, got %d", len(items))
+	}
+}
+
 func TestItemRepository_Delete(t *testing.T) {
 	repo, _, _, _, cleanup := setupItemTestDB(t)
 	defer cleanup()
@@ -346,6 +449,63 @@ func TestItemRepository_Delete(t *testing.T) {
 	}
 }
 
+func TestItemRepository_TrashAndRestore(t *testing.T) {
+	repo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := repo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := repo.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to trash item: %v", err)
+	}
+
+	items, err := repo.GetByListID("list-1")
+	if err != nil {
+		t.Fatalf("Failed to get items: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Expected trashed item to be hidden, got %d items", len(items))
+	}
+
+	trashed, err := repo.GetTrashedForUser("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get trash: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].ID != "item-1" {
+		t.Fatalf("Expected item-1 in trash, got %+v", trashed)
+	}
+
+	restored, err := repo.Restore("item-1")
+	if err != nil {
+		t.Fatalf("Failed to restore item: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("Expected restored item to have no DeletedAt")
+	}
+	if restored.Version != 3 {
+		t.Errorf("Expected version 3 after trash and restore, got %d", restored.Version)
+	}
+
+	if _, err := repo.Restore("item-1"); err != ErrItemNotFound {
+		t.Errorf("Expected ErrItemNotFound restoring an untrashed item, got %v", err)
+	}
+
+	// Purge only removes trashed items
+	if err := repo.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to trash item: %v", err)
+	}
+	purged, err := repo.PurgeDeletedBefore(auth.GetCurrentTimestamp() + 1000)
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged item, got %d", purged)
+	}
+}
+
 func TestItemRepository_GetMaxSortOrder(t *testing.T) {
 	repo, _, _, _, cleanup := setupItemTestDB(t)
 	defer cleanup()
@@ -403,7 +563,7 @@ func TestItemRepository_Reorder(t *testing.T) {
 	}
 
 	// Reorder: c, a, b
-	err := repo.Reorder([]string{"item-c", "item-a", "item-b"})
+	err := repo.Reorder("list-1", []string{"item-c", "item-a", "item-b"})
 	if err != nil {
 		t.Fatalf("Failed to reorder: %v", err)
 	}
@@ -429,3 +589,467 @@ func strPtr(s string) *string {
 func floatPtr(f float64) *float64 {
 	return &f
 }
+
+func TestItemRepository_RestoreSnapshot(t *testing.T) {
+	repo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{
+		ID:         "item-1",
+		ListID:     "list-1",
+		Name:       "Milk",
+		Quantity:   1,
+		CategoryID: "test-cat",
+	}
+	if err := repo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	snapshot := *item
+
+	if _, err := repo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+	item.Name = "Oat Milk"
+	item.Quantity = 3
+	if err := repo.Update(item); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+
+	restored, err := repo.RestoreSnapshot(&snapshot)
+	if err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+	if restored.Name != "Milk" || restored.Quantity != 1 {
+		t.Errorf("Expected Milk x1, got %s x%d", restored.Name, restored.Quantity)
+	}
+	if restored.Checked || restored.CheckedBy != nil {
+		t.Error("Expected checked state to be restored")
+	}
+	if restored.Version <= item.Version {
+		t.Errorf("Expected version to move past %d, got %d", item.Version, restored.Version)
+	}
+
+	if err := repo.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := repo.RestoreSnapshot(&snapshot); err != ErrItemNotFound {
+		t.Errorf("Expected ErrItemNotFound for trashed item, got %v", err)
+	}
+}
+
+func TestItemRepository_MoveUnchecked(t *testing.T) {
+	repo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	createTestList(t, listRepo, "list-2", "Next trip", "user-1")
+	existing := &models.Item{ID: "item-0", ListID: "list-2", Name: "Coffee", Quantity: 1, CategoryID: "test-cat", SortOrder: 5}
+	if err := repo.Create(existing); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	for i, name := range []string{"Milk", "Bread"} {
+		item := &models.Item{ID: "item-" + name, ListID: "list-1", Name: name, Quantity: 1, CategoryID: "test-cat", SortOrder: i}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	source, _ := listRepo.GetByID("list-1")
+
+	moved, err := repo.MoveUnchecked("list-1", "list-2")
+	if err != nil {
+		t.Fatalf("Failed to move items: %v", err)
+	}
+	if len(moved) != 2 {
+		t.Fatalf("Expected 2 moved items, got %d", len(moved))
+	}
+
+	items, _ := repo.GetByListID("list-2")
+	if len(items) != 3 || items[0].Name != "Coffee" || items[1].Name != "Milk" || items[2].Name != "Bread" {
+		t.Errorf("Expected moved items after existing ones, got %+v", items)
+	}
+
+	after, _ := listRepo.GetByID("list-1")
+	if after.Version != source.Version+1 {
+		t.Errorf("Expected source version to bump once, got %d -> %d", source.Version, after.Version)
+	}
+
+	if _, err := repo.MoveUnchecked("list-1", "missing"); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func createBulkTestItems(t *testing.T, repo *ItemRepository) {
+	for i, name := range []string{"Milk", "Bread", "Eggs"} {
+		item := &models.Item{ID: "item-" + name, ListID: "list-1", Name: name, Quantity: 1, CategoryID: "test-cat", SortOrder: i}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	if _, err := repo.ToggleChecked("item-Bread", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+}
+
+func TestItemRepository_BulkSetChecked(t *testing.T) {
+	repo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createBulkTestItems(t, repo)
+
+	before, _ := listRepo.GetByID("list-1")
+
+	changed, err := repo.BulkSetChecked("list-1", models.ItemSelection{Filter: models.ItemFilterAll}, true, "user-1", "Test User")
+	if err != nil {
+		t.Fatalf("Failed to check items: %v", err)
+	}
+	// Bread was already checked and is left alone
+	if len(changed) != 2 || changed[0].Name != "Milk" || changed[1].Name != "Eggs" {
+		t.Fatalf("Expected Milk and Eggs to change, got %+v", changed)
+	}
+	if !changed[0].Checked || changed[0].CheckedBy == nil || *changed[0].CheckedBy != "user-1" {
+		t.Error("Expected items to be checked by user-1")
+	}
+
+	after, _ := listRepo.GetByID("list-1")
+	if after.Version != before.Version+1 {
+		t.Errorf("Expected list version to bump once, got %d -> %d", before.Version, after.Version)
+	}
+	if after.CheckedItems != 3 {
+		t.Errorf("Expected 3 checked items, got %d", after.CheckedItems)
+	}
+
+	changed, err = repo.BulkSetChecked("list-1", models.ItemSelection{ItemIDs: []string{"item-Milk"}}, false, "user-1", "Test User")
+	if err != nil {
+		t.Fatalf("Failed to uncheck items: %v", err)
+	}
+	if len(changed) != 1 || changed[0].Checked || changed[0].CheckedBy != nil {
+		t.Errorf("Expected Milk to be unchecked, got %+v", changed)
+	}
+
+	// Nothing to change leaves the list version alone
+	unchanged, _ := listRepo.GetByID("list-1")
+	changed, _ = repo.BulkSetChecked("list-1", models.ItemSelection{ItemIDs: []string{"item-Milk"}}, false, "user-1", "Test User")
+	final, _ := listRepo.GetByID("list-1")
+	if len(changed) != 0 || final.Version != unchanged.Version {
+		t.Error("Expected a no-op bulk change to leave the list alone")
+	}
+}
+
+func TestItemRepository_BulkDeleteChecked(t *testing.T) {
+	repo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createBulkTestItems(t, repo)
+
+	deleted, err := repo.BulkDelete("list-1", models.ItemSelection{Filter: models.ItemFilterChecked})
+	if err != nil {
+		t.Fatalf("Failed to delete items: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].Name != "Bread" || deleted[0].DeletedAt == nil {
+		t.Fatalf("Expected Bread to be trashed, got %+v", deleted)
+	}
+
+	items, _ := repo.GetByListID("list-1")
+	if len(items) != 2 {
+		t.Errorf("Expected 2 remaining items, got %d", len(items))
+	}
+
+	var tombstones int64
+	repo.db.Model(&models.Tombstone{}).Where("entity_id = ?", "item-Bread").Count(&tombstones)
+	if tombstones != 1 {
+		t.Errorf("Expected a tombstone for the deleted item, got %d", tombstones)
+	}
+}
+
+func TestItemRepository_BulkMove(t *testing.T) {
+	repo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createBulkTestItems(t, repo)
+	createTestList(t, listRepo, "list-2", "Other", "user-1")
+
+	moved, err := repo.BulkMove("list-1", models.ItemSelection{ItemIDs: []string{"item-Eggs", "item-Milk"}}, "list-2")
+	if err != nil {
+		t.Fatalf("Failed to move items: %v", err)
+	}
+	if len(moved) != 2 || moved[0].ListID != "list-2" {
+		t.Fatalf("Expected 2 items moved to list-2, got %+v", moved)
+	}
+
+	items, _ := repo.GetByListID("list-2")
+	if len(items) != 2 || items[0].Name != "Milk" || items[1].Name != "Eggs" {
+		t.Errorf("Expected Milk then Eggs in list-2, got %+v", items)
+	}
+
+	if _, err := repo.BulkMove("list-1", models.ItemSelection{Filter: models.ItemFilterAll}, "missing"); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+	left, _ := repo.GetByListID("list-1")
+	if len(left) != 1 {
+		t.Errorf("Expected a failed move to change nothing, got %d items left", len(left))
+	}
+}
+
+func TestItemRepository_BulkSetCategory(t *testing.T) {
+	repo, _, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createBulkTestItems(t, repo)
+	createTestCategory(t, catRepo, "dairy", "Dairy")
+
+	changed, err := repo.BulkSetCategory("list-1", models.ItemSelection{ItemIDs: []string{"item-Milk", "item-Eggs"}}, "dairy")
+	if err != nil {
+		t.Fatalf("Failed to set category: %v", err)
+	}
+	if len(changed) != 2 || changed[0].CategoryID != "dairy" || changed[0].Version != 2 {
+		t.Errorf("Expected items moved to dairy at version 2, got %+v", changed)
+	}
+
+	if _, err := repo.BulkSetCategory("list-1", models.ItemSelection{Filter: models.ItemFilterAll}, "missing"); err != ErrCategoryNotFound {
+		t.Errorf("Expected ErrCategoryNotFound, got %v", err)
+	}
+}
+
+func TestItemRepository_CreateMany(t *testing.T) {
+	repo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	existing := &models.Item{ID: "item-0", ListID: "list-1", Name: "Coffee", Quantity: 1, CategoryID: "test-cat", SortOrder: 3}
+	if err := repo.Create(existing); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	before, _ := listRepo.GetByID("list-1")
+
+	items := []models.Item{
+		{ID: "item-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"},
+		{ID: "item-2", Name: "Bread", Quantity: 2, CategoryID: "test-cat"},
+	}
+	if err := repo.CreateMany("list-1", items); err != nil {
+		t.Fatalf("Failed to create items: %v", err)
+	}
+	if items[0].SortOrder != 4 || items[1].SortOrder != 5 || items[0].Version != 1 {
+		t.Errorf("Expected items appended at 4 and 5, got %+v", items)
+	}
+
+	after, _ := listRepo.GetByID("list-1")
+	if after.TotalItems != 3 || after.Version != before.Version+1 {
+		t.Errorf("Expected 3 items and one version bump, got %d items, version %d -> %d", after.TotalItems, before.Version, after.Version)
+	}
+
+	// A failing item rolls back the whole batch
+	bad := []models.Item{
+		{ID: "item-3", Name: "Eggs", Quantity: 1, CategoryID: "test-cat"},
+		{ID: "item-1", Name: "Duplicate", Quantity: 1, CategoryID: "test-cat"},
+	}
+	if err := repo.CreateMany("list-1", bad); err == nil {
+		t.Fatal("Expected duplicate ID to fail")
+	}
+	if _, err := repo.GetByID("item-3"); err != ErrItemNotFound {
+		t.Errorf("Expected rolled back item to be missing, got %v", err)
+	}
+}
+
+func TestItemRepository_MostUsedCategory(t *testing.T) {
+	repo, _, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createTestCategory(t, catRepo, "dairy", "Dairy")
+	createTestCategory(t, catRepo, db.OtherCategoryID, "Other")
+
+	for i, c := range []struct{ name, category string }{
+		{"Oat Milk", "dairy"},
+		{"oat milk", "dairy"},
+		{"oat milk", "test-cat"},
+		{"Oat milk", db.OtherCategoryID},
+		{"Oat milk", db.OtherCategoryID},
+		{"Oat milk", db.OtherCategoryID},
+	} {
+		item := &models.Item{ID: "item-" + string(rune('a'+i)), ListID: "list-1", Name: c.name, Quantity: 1, CategoryID: c.category}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	categoryID, err := repo.MostUsedCategory("OAT MILK ")
+	if err != nil {
+		t.Fatalf("Failed to get category: %v", err)
+	}
+	if categoryID != "dairy" {
+		t.Errorf("Expected dairy, got %q", categoryID)
+	}
+
+	categoryID, _ = repo.MostUsedCategory("bread")
+	if categoryID != "" {
+		t.Errorf("Expected no category for an unknown name, got %q", categoryID)
+	}
+}
+
+func TestItemRepository_Suggest(t *testing.T) {
+	repo, listRepo, _, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createTestUser(t, userRepo, "user-2", "other", "Other User")
+	owner := "user-2"
+	if err := listRepo.Create(&models.List{ID: "private", Name: "Private", OwnerID: &owner, CreatedAt: 1000, UpdatedAt: 1000}); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	day := int64(24 * 60 * 60 * 1000)
+	now := 100 * day
+	store := "Aldi"
+	price := 1.29
+	for i, c := range []struct {
+		listID, name string
+		updatedAt    int64
+	}{
+		{"list-1", "Oat milk", 10 * day},
+		{"list-1", "oat milk", 20 * day},
+		{"list-1", "Oat Milk", 30 * day},
+		{"list-1", "Milk", 99 * day},
+		{"list-1", "Oatmeal", 50 * day},
+		{"list-1", "Bread", 99 * day},
+		{"private", "Milk chocolate", 99 * day},
+	} {
+		item := &models.Item{ID: "item-" + string(rune('a'+i)), ListID: c.listID, Name: c.name, Quantity: 1, CategoryID: "test-cat"}
+		if c.name == "Oat Milk" {
+			item.Store = &store
+			item.Price = &price
+		}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+		repo.db.Exec("UPDATE items SET updated_at = ? WHERE id = ?", c.updatedAt, item.ID)
+	}
+
+	suggestions, err := repo.Suggest("user-1", "mil", now, 10)
+	if err != nil {
+		t.Fatalf("Failed to suggest: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("Expected Milk and Oat Milk, got %+v", suggestions)
+	}
+	// One use yesterday outranks three uses months ago
+	if suggestions[0].Name != "Milk" || suggestions[1].Name != "Oat Milk" {
+		t.Errorf("Expected Milk then Oat Milk, got %q then %q", suggestions[0].Name, suggestions[1].Name)
+	}
+	latest := suggestions[1]
+	if latest.Uses != 3 || latest.LastUsedAt != 30*day || latest.Store == nil || *latest.Store != "Aldi" || latest.Price == nil || *latest.Price != 1.29 {
+		t.Errorf("Expected 3 uses with the latest store and price, got %+v", latest)
+	}
+
+	suggestions, _ = repo.Suggest("user-1", "oat", now, 10)
+	if len(suggestions) != 2 || suggestions[0].Name != "Oat Milk" || suggestions[1].Name != "Oatmeal" {
+		t.Errorf("Expected Oat Milk then Oatmeal, got %+v", suggestions)
+	}
+
+	// Renames are indexed
+	if err := repo.db.Model(&models.Item{}).Where("id = ?", "item-f").Update("name", "Milk bread").Error; err != nil {
+		t.Fatalf("Failed to rename item: %v", err)
+	}
+	suggestions, _ = repo.Suggest("user-1", "MILK BR", now, 10)
+	if len(suggestions) != 1 || suggestions[0].Name != "Milk bread" {
+		t.Errorf("Expected the renamed item, got %+v", suggestions)
+	}
+
+	suggestions, _ = repo.Suggest("user-1", `"*`, now, 10)
+	if len(suggestions) != 0 {
+		t.Errorf("Expected no suggestions for punctuation, got %+v", suggestions)
+	}
+}
+
+func TestItemRepository_GetExpiring(t *testing.T) {
+	repo, listRepo, _, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	createTestUser(t, userRepo, "user-2", "other", "Other User")
+	owner := "user-2"
+	if err := listRepo.Create(&models.List{ID: "private", Name: "Private", OwnerID: &owner, CreatedAt: 1000, UpdatedAt: 1000}); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	expiry := func(at int64) *int64 { return &at }
+	for _, c := range []struct {
+		id, listID string
+		expiresAt  *int64
+	}{
+		{"item-1", "list-1", expiry(3000)},
+		{"item-2", "list-1", expiry(2000)},
+		{"item-3", "list-1", expiry(9000)},
+		{"item-4", "list-1", nil},
+		{"item-5", "private", expiry(2500)},
+	} {
+		item := &models.Item{ID: c.id, ListID: c.listID, Name: c.id, Quantity: 1, CategoryID: "test-cat"}
+		if err := repo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+		toggled, err := repo.ToggleChecked(c.id, "user-1", "Test User", c.expiresAt)
+		if err != nil {
+			t.Fatalf("Failed to check item: %v", err)
+		}
+		if (toggled.ExpiresAt == nil) != (c.expiresAt == nil) {
+			t.Errorf("Expected %s to keep its best-before date, got %v", c.id, toggled.ExpiresAt)
+		}
+	}
+
+	items, err := repo.GetExpiringForUser("user-1", 5000)
+	if err != nil {
+		t.Fatalf("Failed to get expiring items: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "item-2" || items[1].ID != "item-1" {
+		t.Errorf("Expected item-2 then item-1, got %+v", items)
+	}
+
+	items, _ = repo.GetExpiring(5000)
+	if len(items) != 3 || items[1].ID != "item-5" {
+		t.Errorf("Expected every list's expiring items, got %+v", items)
+	}
+
+	// Unchecking clears the date
+	toggled, _ := repo.ToggleChecked("item-2", "user-1", "Test User", nil)
+	if toggled.ExpiresAt != nil {
+		t.Errorf("Expected unchecking to clear the date, got %v", *toggled.ExpiresAt)
+	}
+	items, _ = repo.GetExpiringForUser("user-1", 5000)
+	if len(items) != 1 || items[0].ID != "item-1" {
+		t.Errorf("Expected only item-1, got %+v", items)
+	}
+}
+
+func TestItemRepository_MergeIntoListRescalesPrice(t *testing.T) {
+	repo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	kg := "kg"
+	rice := &models.Item{ID: "item-1", ListID: "list-1", Name: "Rice", Quantity: 1, Unit: &kg, Price: floatPtr(3), PackageSize: floatPtr(2), PackageUnit: &kg, CategoryID: "test-cat"}
+	if err := repo.Create(rice); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	rice.SetUnitPrice()
+
+	g := "g"
+	_, merged, err := repo.MergeIntoList("list-1", []models.Item{{Name: "rice", Quantity: 500, Unit: &g, CategoryID: "test-cat"}})
+	if err != nil {
+		t.Fatalf("Failed to merge items: %v", err)
+	}
+	if len(merged) != 1 {
+		t.Fatalf("Expected 1 merged item, got %+v", merged)
+	}
+
+	// 1.5 kg at 3 per kg, now counted in grams
+	stored, _ := repo.GetByID("item-1")
+	if stored.Quantity != 1500 || unitOf(stored.Unit) != "g" {
+		t.Fatalf("Expected 1500 g, got %d %s", stored.Quantity, unitOf(stored.Unit))
+	}
+	if total := *stored.Price * float64(stored.Quantity); math.Abs(total-4.5) > 1e-9 {
+		t.Errorf("Expected price x quantity 4.5, got %v", total)
+	}
+	if kept := *stored.Price * 1000; math.Abs(kept-3) > 1e-9 {
+		t.Errorf("Expected the original kilo still to cost 3, got %v", kept)
+	}
+	stored.SetUnitPrice()
+	if stored.UnitPrice == nil || rice.UnitPrice == nil || math.Abs(stored.UnitPrice.Price-rice.UnitPrice.Price) > 1e-9 {
+		t.Errorf("Expected the unit price unchanged, got %+v, was %+v", stored.UnitPrice, rice.UnitPrice)
+	}
+
+	// Merging in the same unit leaves the price alone
+	if _, _, err := repo.MergeIntoList("list-1", []models.Item{{Name: "Rice", Quantity: 500, Unit: &g, CategoryID: "test-cat"}}); err != nil {
+		t.Fatalf("Failed to merge items: %v", err)
+	}
+	again, _ := repo.GetByID("item-1")
+	if again.Quantity != 2000 || *again.Price != *stored.Price {
+		t.Errorf("Expected 2000 g at the same price, got %d at %v", again.Quantity, *again.Price)
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+type JobStateRepository struct {
+	db *db.DB
+}
+
+func NewJobStateRepository(database *db.DB) *JobStateRepository {
+	return &JobStateRepository{db: database}
+}
+
+// Get returns the value stored under name, or "" when there is none
+func (r *JobStateRepository) Get(name string) (string, error) {
+	var state models.JobState
+	if err := r.db.First(&state, "name = ?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return state.Value, nil
+}
+
+// Set stores value under name, replacing what was there
+func (r *JobStateRepository) Set(name, value string) error {
+	return r.db.Save(&models.JobState{Name: name, Value: value, UpdatedAt: auth.GetCurrentTimestamp()}).Error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import "testing"
+
+func TestJobStateRepository_GetSet(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewJobStateRepository(database)
+
+	value, err := repo.Get("expiry.last_sent")
+	if err != nil || value != "" {
+		t.Fatalf("Expected no value, got %q, %v", value, err)
+	}
+
+	for _, day := range []string{"2024-03-01", "2024-03-02"} {
+		if err := repo.Set("expiry.last_sent", day); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	value, err = repo.Get("expiry.last_sent")
+	if err != nil || value != "2024-03-02" {
+		t.Errorf("Expected the latest value, got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+var ErrMemberNotFound = errors.New("list member not found")
+var ErrAlreadyMember = errors.New("user is already a member of this list")
+
+type ListMemberRepository struct {
+	db *db.DB
+}
+
+func NewListMemberRepository(database *db.DB) *ListMemberRepository {
+	return &ListMemberRepository{db: database}
+}
+
+// Create adds a member to a list. The list and its items are touched so the
+// new member's delta sync picks them up, and deletions recorded when they
+// last lost the list are forgotten.
+func (r *ListMemberRepository) Create(member *models.ListMember) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		if err := clearUserTombstones(tx, member.ListID, member.UserID); err != nil {
+			return err
+		}
+
+		now := auth.GetCurrentTimestamp()
+		if err := tx.Model(&models.List{}).Where("id = ?", member.ListID).Update("updated_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Item{}).
+			Where("list_id = ? AND deleted_at IS NULL", member.ListID).
+			Update("updated_at", now).Error
+	})
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return ErrAlreadyMember
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *ListMemberRepository) GetByListID(listID string) ([]models.ListMemberWithUser, error) {
+	var members []models.ListMemberWithUser
+	err := r.db.Table("list_members m").
+		Select("m.id, m.list_id, m.user_id, m.role, m.created_at, u.username, u.name").
+		Joins("JOIN users u ON u.id = m.user_id").
+		Where("m.list_id = ?", listID).
+		Order("m.created_at ASC").
+		Scan(&members).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if members == nil {
+		members = []models.ListMemberWithUser{}
+	}
+
+	return members, nil
+}
+
+func (r *ListMemberRepository) Get(listID, userID string) (*models.ListMember, error) {
+	var member models.ListMember
+	err := r.db.First(&member, "list_id = ? AND user_id = ?", listID, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetRole returns the user's role on a list that is not in the trash
+func (r *ListMemberRepository) GetRole(listID, userID string) (models.ListRole, error) {
+	return r.getRole(listID, userID, false)
+}
+
+// GetRoleIncludingTrashed is like GetRole but also finds trashed lists
+func (r *ListMemberRepository) GetRoleIncludingTrashed(listID, userID string) (models.ListRole, error) {
+	return r.getRole(listID, userID, true)
+}
+
+func (r *ListMemberRepository) getRole(listID, userID string, includeTrashed bool) (models.ListRole, error) {
+	query := r.db.Select("id", "owner_id").Where("id = ?", listID)
+	if !includeTrashed {
+		query = query.Where("deleted_at IS NULL")
+	}
+
+	var list models.List
+	err := query.First(&list).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrListNotFound
+		}
+		return "", err
+	}
+
+	member, err := r.Get(listID, userID)
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+func (r *ListMemberRepository) UpdateRole(listID, userID string, role models.ListRole) error {
+	result := r.db.Model(&models.ListMember{}).
+		Where("list_id = ? AND user_id = ?", listID, userID).
+		Update("role", role)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a member from a list, leaving them a tombstone for delta
+// sync
+func (r *ListMemberRepository) Delete(listID, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.ListMember{}, "list_id = ? AND user_id = ?", listID, userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMemberNotFound
+		}
+		return recordUserTombstone(tx, models.EntityList, listID, &listID, userID)
+	})
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func setupMemberTestDB(t *testing.T) (*ListMemberRepository, *ListRepository, func()) {
+	database, cleanup := setupTestDB(t)
+	userRepo := NewUserRepository(database)
+	listRepo := NewListRepository(database)
+	memberRepo := NewListMemberRepository(database)
+
+	createTestUser(t, userRepo, "user-1", "alice", "Alice")
+	createTestUser(t, userRepo, "user-2", "bob", "Bob")
+
+	owner := "user-1"
+	list := &models.List{ID: "list-1", Name: "Private", OwnerID: &owner, CreatedAt: 1000, UpdatedAt: 1000}
+	if err := listRepo.Create(list); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	return memberRepo, listRepo, cleanup
+}
+
+func TestListMemberRepository_CreateAddsOwner(t *testing.T) {
+	repo, _, cleanup := setupMemberTestDB(t)
+	defer cleanup()
+
+	members, err := repo.GetByListID("list-1")
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	if len(members) != 1 {
+		t.Fatalf("Expected 1 member, got %d", len(members))
+	}
+	if members[0].UserID != "user-1" || members[0].Role != models.ListRoleOwner {
+		t.Errorf("Expected user-1 as owner, got %s as %s", members[0].UserID, members[0].Role)
+	}
+	if members[0].Username != "alice" {
+		t.Errorf("Expected username alice, got %s", members[0].Username)
+	}
+}
+
+func TestListMemberRepository_GetRole(t *testing.T) {
+	repo, listRepo, cleanup := setupMemberTestDB(t)
+	defer cleanup()
+
+	role, err := repo.GetRole("list-1", "user-1")
+	if err != nil || role != models.ListRoleOwner {
+		t.Errorf("Expected owner role, got %s (%v)", role, err)
+	}
+
+	// Non-member
+	_, err = repo.GetRole("list-1", "user-2")
+	if err != ErrMemberNotFound {
+		t.Errorf("Expected ErrMemberNotFound, got %v", err)
+	}
+
+	// Lists without an owner are not open to everyone
+	createTestList(t, listRepo, "list-2", "Household", "")
+	if _, err = repo.GetRole("list-2", "user-2"); err != ErrMemberNotFound {
+		t.Errorf("Expected ErrMemberNotFound on a list without an owner, got %v", err)
+	}
+
+	// Non-existing list
+	_, err = repo.GetRole("non-existent", "user-1")
+	if err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestListMemberRepository_UpdateAndDelete(t *testing.T) {
+	repo, _, cleanup := setupMemberTestDB(t)
+	defer cleanup()
+
+	member := &models.ListMember{ID: "member-2", ListID: "list-1", UserID: "user-2", Role: models.ListRoleViewer, CreatedAt: 2000}
+	if err := repo.Create(member); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	// Duplicate membership
+	duplicate := &models.ListMember{ID: "member-3", ListID: "list-1", UserID: "user-2", Role: models.ListRoleEditor, CreatedAt: 2000}
+	if err := repo.Create(duplicate); err != ErrAlreadyMember {
+		t.Errorf("Expected ErrAlreadyMember, got %v", err)
+	}
+
+	if err := repo.UpdateRole("list-1", "user-2", models.ListRoleEditor); err != nil {
+		t.Fatalf("Failed to update role: %v", err)
+	}
+	role, _ := repo.GetRole("list-1", "user-2")
+	if role != models.ListRoleEditor {
+		t.Errorf("Expected editor role, got %s", role)
+	}
+
+	if err := repo.Delete("list-1", "user-2"); err != nil {
+		t.Fatalf("Failed to remove member: %v", err)
+	}
+	if err := repo.Delete("list-1", "user-2"); err != ErrMemberNotFound {
+		t.Errorf("Expected ErrMemberNotFound, got %v", err)
+	}
+}
+
+func TestListRole_AtLeast(t *testing.T) {
+	if !models.ListRoleOwner.AtLeast(models.ListRoleEditor) {
+		t.Error("Owner should have editor permissions")
+	}
+	if models.ListRoleViewer.AtLeast(models.ListRoleEditor) {
+		t.Error("Viewer should not have editor permissions")
+	}
+	if models.ListRole("admin").AtLeast(models.ListRoleViewer) {
+		t.Error("Unknown roles should not grant permissions")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
 )
@@ -20,22 +21,44 @@ func NewListRepository(database *db.DB) *ListRepository {
 	return &ListRepository{db: database}
 }
 
+// Create inserts a list. When the list has an owner, the owner is also
+// added as a member with the owner role.
 func (r *ListRepository) Create(list *models.List) error {
 	list.Version = 1 // Initial version
-	return r.db.Create(list).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(list).Error; err != nil {
+			return err
+		}
+		if list.OwnerID == nil {
+			return nil
+		}
+		return tx.Create(&models.ListMember{
+			ID:        auth.GenerateID(),
+			ListID:    list.ID,
+			UserID:    *list.OwnerID,
+			Role:      models.ListRoleOwner,
+			CreatedAt: list.CreatedAt,
+		}).Error
+	})
 }
 
+// listWithCountsColumns selects a list with statistics over its items that
+// are not in the trash. Queries using it join items as i.
+const listWithCountsColumns = `
+	l.id, l.name, l.owner_id, l.version, l.created_at, l.updated_at, l.deleted_at,
+	COUNT(i.id) as total_items,
+	SUM(CASE WHEN i.checked = 1 THEN 1 ELSE 0 END) as checked_items,
+	COALESCE(SUM(CASE WHEN i.price IS NOT NULL THEN i.price * i.quantity ELSE 0 END), 0) as total_price`
+
+const listItemsJoin = "LEFT JOIN items i ON l.id = i.list_id AND i.deleted_at IS NULL"
+
 func (r *ListRepository) GetAll() ([]models.ListWithCounts, error) {
 	var lists []models.ListWithCounts
 
 	err := r.db.Table("lists l").
-		Select(`
-			l.id, l.name, l.version, l.created_at, l.updated_at,
-			COUNT(i.id) as total_items,
-			SUM(CASE WHEN i.checked = 1 THEN 1 ELSE 0 END) as checked_items,
-			COALESCE(SUM(CASE WHEN i.price IS NOT NULL THEN i.price * i.quantity ELSE 0 END), 0) as total_price
-		`).
-		Joins("LEFT JOIN items i ON l.id = i.list_id").
+		Select(listWithCountsColumns).
+		Joins(listItemsJoin).
+		Where("l.deleted_at IS NULL").
 		Group("l.id").
 		Order("l.updated_at DESC").
 		Scan(&lists).Error
@@ -51,18 +74,87 @@ func (r *ListRepository) GetAll() ([]models.ListWithCounts, error) {
 	return lists, nil
 }
 
+// visibleToUser selects the lists the user is a member of, along with the
+// user's role on each. Trashed lists are included; callers filter on
+// l.deleted_at.
+func (r *ListRepository) visibleToUser(userID string) *gorm.DB {
+	return r.db.Table("lists l").
+		Select(listWithCountsColumns+", m.role as role").
+		Joins("JOIN list_members m ON m.list_id = l.id AND m.user_id = ?", userID).
+		Joins(listItemsJoin).
+		Group("l.id")
+}
+
+// GetAllForUser returns the lists visible to the user
+func (r *ListRepository) GetAllForUser(userID string) ([]models.ListWithCounts, error) {
+	var lists []models.ListWithCounts
+
+	err := r.visibleToUser(userID).
+		Where("l.deleted_at IS NULL").
+		Order("l.updated_at DESC").
+		Scan(&lists).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lists == nil {
+		lists = []models.ListWithCounts{}
+	}
+
+	return lists, nil
+}
+
+// GetChangedSinceForUser returns the visible lists updated at or after since
+func (r *ListRepository) GetChangedSinceForUser(userID string, since int64) ([]models.ListWithCounts, error) {
+	var lists []models.ListWithCounts
+
+	err := r.visibleToUser(userID).
+		Where("l.deleted_at IS NULL").
+		Where("l.updated_at >= ?", since).
+		Order("l.updated_at ASC").
+		Scan(&lists).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lists == nil {
+		lists = []models.ListWithCounts{}
+	}
+
+	return lists, nil
+}
+
+// GetTrashedForUser returns trashed lists the user owns, most recently deleted first
+func (r *ListRepository) GetTrashedForUser(userID string) ([]models.ListWithCounts, error) {
+	var lists []models.ListWithCounts
+
+	err := r.visibleToUser(userID).
+		Where("l.deleted_at IS NOT NULL").
+		Where("m.role = ?", models.ListRoleOwner).
+		Order("l.deleted_at DESC").
+		Scan(&lists).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lists == nil {
+		lists = []models.ListWithCounts{}
+	}
+
+	return lists, nil
+}
+
+// GetByID returns a list that is not in the trash
 func (r *ListRepository) GetByID(id string) (*models.ListWithCounts, error) {
 	var list models.ListWithCounts
 
 	err := r.db.Table("lists l").
-		Select(`
-			l.id, l.name, l.version, l.created_at, l.updated_at,
-			COUNT(i.id) as total_items,
-			SUM(CASE WHEN i.checked = 1 THEN 1 ELSE 0 END) as checked_items,
-			COALESCE(SUM(CASE WHEN i.price IS NOT NULL THEN i.price * i.quantity ELSE 0 END), 0) as total_price
-		`).
-		Joins("LEFT JOIN items i ON l.id = i.list_id").
-		Where("l.id = ?", id).
+		Select(listWithCountsColumns).
+		Joins(listItemsJoin).
+		Where("l.id = ? AND l.deleted_at IS NULL", id).
 		Group("l.id").
 		Scan(&list).Error
 
@@ -79,7 +171,7 @@ func (r *ListRepository) GetByID(id string) (*models.ListWithCounts, error) {
 
 func (r *ListRepository) Update(id string, name string, updatedAt int64) error {
 	result := r.db.Model(&models.List{}).
-		Where("id = ?", id).
+		Where("id = ? AND deleted_at IS NULL", id).
 		Updates(map[string]interface{}{
 			"name":       name,
 			"version":    gorm.Expr("version + 1"),
@@ -99,7 +191,7 @@ func (r *ListRepository) Update(id string, name string, updatedAt int64) error {
 // UpdateWithVersion updates a list only if the version matches (optimistic locking)
 func (r *ListRepository) UpdateWithVersion(id string, name string, expectedVersion int, updatedAt int64) error {
 	result := r.db.Model(&models.List{}).
-		Where("id = ? AND version = ?", id, expectedVersion).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", id, expectedVersion).
 		Updates(map[string]interface{}{
 			"name":       name,
 			"version":    gorm.Expr("version + 1"),
@@ -112,7 +204,7 @@ func (r *ListRepository) UpdateWithVersion(id string, name string, expectedVersi
 	if result.RowsAffected == 0 {
 		// Check if the list exists
 		var count int64
-		r.db.Model(&models.List{}).Where("id = ?", id).Count(&count)
+		r.db.Model(&models.List{}).Where("id = ? AND deleted_at IS NULL", id).Count(&count)
 		if count > 0 {
 			return ErrVersionConflict
 		}
@@ -122,20 +214,168 @@ func (r *ListRepository) UpdateWithVersion(id string, name string, expectedVersi
 	return nil
 }
 
-func (r *ListRepository) Delete(id string) error {
-	result := r.db.Delete(&models.List{}, "id = ?", id)
-	if result.Error != nil {
-		return result.Error
+// Duplicate creates list as a copy of the source list's items, keeping their
+// details and order but not their checked state, and returns the copies
+func (r *ListRepository) Duplicate(sourceID string, list *models.List) ([]models.Item, error) {
+	var copies []models.Item
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewListRepository(&db.DB{DB: tx})
+		if _, err := txRepo.GetByID(sourceID); err != nil {
+			return err
+		}
+		if err := txRepo.Create(list); err != nil {
+			return err
+		}
+
+		var items []models.Item
+		if err := tx.Where("list_id = ? AND deleted_at IS NULL", sourceID).Order("sort_order ASC").Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		now := auth.GetCurrentTimestamp()
+		copies = make([]models.Item, len(items))
+		for i, item := range items {
+			copies[i] = models.Item{
+				ID:          auth.GenerateID(),
+				ListID:      list.ID,
+				Name:        item.Name,
+				Quantity:    item.Quantity,
+				Unit:        item.Unit,
+				CategoryID:  item.CategoryID,
+				Price:       item.Price,
+				PackageSize: item.PackageSize,
+				PackageUnit: item.PackageUnit,
+				Store:       item.Store,
+				SortOrder:   item.SortOrder,
+				Version:     1,
+				UpdatedAt:   now,
+			}
+		}
+		return tx.Create(&copies).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return ErrListNotFound
+	return copies, nil
+}
+
+// CarryOverToNew creates list and moves the source list's unchecked items
+// into it in one transaction
+func (r *ListRepository) CarryOverToNew(sourceID string, list *models.List) ([]models.Item, error) {
+	var moved []models.Item
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		if _, err := NewListRepository(txDB).GetByID(sourceID); err != nil {
+			return err
+		}
+		if err := NewListRepository(txDB).Create(list); err != nil {
+			return err
+		}
+
+		var err error
+		moved, err = NewItemRepository(txDB).MoveUnchecked(sourceID, list.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return moved, nil
+}
+
+// Delete moves a list to the trash, leaving a tombstone for delta sync.
+// Its items stay untouched so a restore brings the list back as it was.
+func (r *ListRepository) Delete(id string) error {
+	now := auth.GetCurrentTimestamp()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.List{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"deleted_at": now,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrListNotFound
+		}
+		return recordTombstone(tx, models.EntityList, id, &id)
+	})
+}
+
+// Restore takes a list out of the trash
+func (r *ListRepository) Restore(id string) error {
+	now := auth.GetCurrentTimestamp()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.List{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrListNotFound
+		}
+
+		// Touch the items so delta sync clients that dropped the list get them back
+		if err := tx.Model(&models.Item{}).
+			Where("list_id = ? AND deleted_at IS NULL", id).
+			Update("updated_at", now).Error; err != nil {
+			return err
+		}
+		return clearTombstones(tx, models.EntityList, id)
+	})
+}
+
+// PurgeDeletedBefore permanently deletes lists trashed before the cutoff,
+// along with their items, and returns how many lists were removed
+func (r *ListRepository) PurgeDeletedBefore(cutoff int64) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&models.List{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+
+		if err := tx.Where("list_id IN (?)", expired).Delete(&models.Item{}).Error; err != nil {
+			return err
+		}
+		// Members lose the list's tombstone along with their membership, so
+		// each gets one of their own
+		var members []models.ListMember
+		if err := tx.Where("list_id IN (?)", expired).Find(&members).Error; err != nil {
+			return err
+		}
+		for _, member := range members {
+			if err := recordUserTombstone(tx, models.EntityList, member.ListID, &member.ListID, member.UserID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("list_id IN (?)", expired).Delete(&models.ListMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("list_id IN (?)", expired).Delete(&models.Activity{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.List{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+		return nil
+	})
+	return purged, err
 }
 
 func (r *ListRepository) TouchUpdatedAt(id string, updatedAt int64) error {
 	return r.db.Model(&models.List{}).
-		Where("id = ?", id).
+		Where("id = ? AND deleted_at IS NULL", id).
 		Updates(map[string]interface{}{
 			"version":    gorm.Expr("version + 1"),
 			"updated_at": updatedAt,
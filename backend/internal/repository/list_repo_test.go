@@ -192,3 +192,287 @@ func TestListRepository_Delete(t *testing.T) {
 		t.Errorf("Expected ErrListNotFound, got %v", err)
 	}
 }
+
+func TestListRepository_GetAllForUser(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewListRepository(database)
+	userRepo := NewUserRepository(database)
+	memberRepo := NewListMemberRepository(database)
+
+	createTestUser(t, userRepo, "user-1", "alice", "Alice")
+	createTestUser(t, userRepo, "user-2", "bob", "Bob")
+
+	owner1, owner2 := "user-1", "user-2"
+	now := time.Now().UnixMilli()
+	lists := []*models.List{
+		{ID: "shared", Name: "Household", CreatedAt: now, UpdatedAt: now},
+		{ID: "alice-private", Name: "Alice", OwnerID: &owner1, CreatedAt: now, UpdatedAt: now + 1},
+		{ID: "bob-private", Name: "Bob", OwnerID: &owner2, CreatedAt: now, UpdatedAt: now + 2},
+	}
+	for _, list := range lists {
+		if err := repo.Create(list); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+	}
+
+	visible, err := repo.GetAllForUser("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+	// A list without an owner is not open to everyone
+	if len(visible) != 1 || visible[0].ID != "alice-private" {
+		t.Fatalf("Expected only alice-private, got %+v", visible)
+	}
+	for _, list := range visible {
+		if list.Role != models.ListRoleOwner {
+			t.Errorf("Expected owner role on %s, got %s", list.ID, list.Role)
+		}
+	}
+
+	// Sharing Bob's list makes it visible with the granted role
+	err = memberRepo.Create(&models.ListMember{
+		ID:        "member-1",
+		ListID:    "bob-private",
+		UserID:    "user-1",
+		Role:      models.ListRoleViewer,
+		CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	visible, err = repo.GetAllForUser("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+	if len(visible) != 2 {
+		t.Fatalf("Expected 2 visible lists, got %d", len(visible))
+	}
+	if visible[0].ID != "bob-private" || visible[0].Role != models.ListRoleViewer {
+		t.Errorf("Expected viewer role on bob-private, got %s on %s", visible[0].Role, visible[0].ID)
+	}
+}
+
+func TestListRepository_UpdateWithVersion(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewListRepository(database)
+	createTestList(t, repo, "list-1", "Old Name", "")
+
+	// Matching version succeeds and bumps the version
+	if err := repo.UpdateWithVersion("list-1", "New Name", 1, time.Now().UnixMilli()); err != nil {
+		t.Fatalf("Failed to update list: %v", err)
+	}
+
+	// Stale version conflicts
+	err := repo.UpdateWithVersion("list-1", "Stale Name", 1, time.Now().UnixMilli())
+	if err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	list, err := repo.GetByID("list-1")
+	if err != nil {
+		t.Fatalf("Failed to get list: %v", err)
+	}
+	if list.Name != "New Name" || list.Version != 2 {
+		t.Errorf("Expected 'New Name' at version 2, got %s at %d", list.Name, list.Version)
+	}
+
+	// Non-existing list
+	err = repo.UpdateWithVersion("non-existent", "Name", 1, time.Now().UnixMilli())
+	if err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestListRepository_TrashAndRestore(t *testing.T) {
+	itemRepo, repo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := repo.Delete("list-1"); err != nil {
+		t.Fatalf("Failed to trash list: %v", err)
+	}
+
+	// Trashed lists are hidden from normal queries
+	lists, err := repo.GetAllForUser("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get lists: %v", err)
+	}
+	if len(lists) != 0 {
+		t.Errorf("Expected no lists, got %d", len(lists))
+	}
+
+	trashed, err := repo.GetTrashedForUser("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get trash: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].DeletedAt == nil {
+		t.Fatalf("Expected 1 trashed list, got %+v", trashed)
+	}
+
+	// Deleting again is not found
+	if err := repo.Delete("list-1"); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+
+	if err := repo.Restore("list-1"); err != nil {
+		t.Fatalf("Failed to restore list: %v", err)
+	}
+
+	restored, err := repo.GetByID("list-1")
+	if err != nil {
+		t.Fatalf("Failed to get restored list: %v", err)
+	}
+	if restored.TotalItems != 1 {
+		t.Errorf("Expected restored list to keep its item, got %d items", restored.TotalItems)
+	}
+
+	// Restoring a list that is not trashed is not found
+	if err := repo.Restore("list-1"); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestListRepository_PurgeDeletedBefore(t *testing.T) {
+	itemRepo, repo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	createTestList(t, repo, "list-2", "Kept", "user-1")
+
+	if err := repo.Delete("list-1"); err != nil {
+		t.Fatalf("Failed to trash list: %v", err)
+	}
+
+	// Nothing is old enough yet
+	purged, err := repo.PurgeDeletedBefore(0)
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("Expected 0 purged lists, got %d", purged)
+	}
+
+	purged, err = repo.PurgeDeletedBefore(time.Now().Add(time.Hour).UnixMilli())
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged list, got %d", purged)
+	}
+
+	if err := repo.Restore("list-1"); err != ErrListNotFound {
+		t.Errorf("Expected purged list to be gone, got %v", err)
+	}
+	if _, err := itemRepo.GetTrashedByID("item-1"); err != ErrItemNotFound {
+		t.Errorf("Expected purged list's items to be gone, got %v", err)
+	}
+	if _, err := repo.GetByID("list-2"); err != nil {
+		t.Errorf("Expected untrashed list to remain, got %v", err)
+	}
+}
+
+func TestListRepository_Duplicate(t *testing.T) {
+	itemRepo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	price := 3.5
+	store := "Costco"
+	for i, item := range []models.Item{
+		{ID: "item-1", Name: "Milk", Quantity: 2, Price: &price, Store: &store},
+		{ID: "item-2", Name: "Bread", Quantity: 1},
+	} {
+		item.ListID = "list-1"
+		item.CategoryID = "test-cat"
+		item.SortOrder = i
+		if err := itemRepo.Create(&item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	if _, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+
+	owner := "user-1"
+	copied := &models.List{ID: "list-2", Name: "Copy", OwnerID: &owner, CreatedAt: 2000, UpdatedAt: 2000}
+	created, err := listRepo.Duplicate("list-1", copied)
+	if err != nil {
+		t.Fatalf("Failed to duplicate list: %v", err)
+	}
+	if len(created) != 2 {
+		t.Errorf("Expected 2 copies returned, got %d", len(created))
+	}
+
+	items, err := itemRepo.GetByListID("list-2")
+	if err != nil {
+		t.Fatalf("Failed to get items: %v", err)
+	}
+	if len(items) != 2 || items[0].Name != "Milk" || items[1].Name != "Bread" {
+		t.Fatalf("Expected Milk then Bread, got %+v", items)
+	}
+	if items[0].ID == "item-1" {
+		t.Error("Expected copies to get new IDs")
+	}
+	if items[0].Checked {
+		t.Error("Expected copies to start unchecked")
+	}
+	if items[0].Price == nil || *items[0].Price != price || items[0].Store == nil || *items[0].Store != store {
+		t.Error("Expected price and store to be copied")
+	}
+
+	// The source list is untouched
+	source, _ := itemRepo.GetByListID("list-1")
+	if len(source) != 2 {
+		t.Errorf("Expected source to keep 2 items, got %d", len(source))
+	}
+
+	missing := &models.List{ID: "list-3", Name: "Copy", CreatedAt: 2000, UpdatedAt: 2000}
+	if _, err := listRepo.Duplicate("missing", missing); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestListRepository_CarryOverToNew(t *testing.T) {
+	itemRepo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	for i, name := range []string{"Milk", "Bread", "Eggs"} {
+		item := &models.Item{ID: "item-" + name, ListID: "list-1", Name: name, Quantity: 1, CategoryID: "test-cat", SortOrder: i}
+		if err := itemRepo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	if _, err := itemRepo.ToggleChecked("item-Bread", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+
+	next := &models.List{ID: "list-2", Name: "Next trip", CreatedAt: 2000, UpdatedAt: 2000}
+	moved, err := listRepo.CarryOverToNew("list-1", next)
+	if err != nil {
+		t.Fatalf("Failed to carry over: %v", err)
+	}
+	if len(moved) != 2 {
+		t.Fatalf("Expected 2 moved items, got %d", len(moved))
+	}
+
+	items, _ := itemRepo.GetByListID("list-2")
+	if len(items) != 2 || items[0].Name != "Milk" || items[1].Name != "Eggs" {
+		t.Errorf("Expected Milk then Eggs in the new list, got %+v", items)
+	}
+
+	left, _ := itemRepo.GetByListID("list-1")
+	if len(left) != 1 || left[0].Name != "Bread" {
+		t.Errorf("Expected only the checked item to stay, got %+v", left)
+	}
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/units"
+)
+
+var ErrMealPlanEntryNotFound = errors.New("meal plan entry not found")
+
+// mealSlotOrder sorts entries within a day
+const mealSlotOrder = `CASE slot WHEN 'breakfast' THEN 0 WHEN 'lunch' THEN 1 WHEN 'dinner' THEN 2 ELSE 3 END`
+
+type MealPlanRepository struct {
+	db *db.DB
+}
+
+func NewMealPlanRepository(database *db.DB) *MealPlanRepository {
+	return &MealPlanRepository{db: database}
+}
+
+func (r *MealPlanRepository) withRecipe(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Recipe").
+		Preload("Recipe.Ingredients", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("sort_order ASC")
+		})
+}
+
+// GetRange returns the entries planned from one date to another, inclusive,
+// by day and slot. Dates are YYYY-MM-DD.
+func (r *MealPlanRepository) GetRange(from, to string) ([]models.MealPlanEntry, error) {
+	return getMealPlanRange(r.withRecipe(r.db.DB), from, to)
+}
+
+func getMealPlanRange(tx *gorm.DB, from, to string) ([]models.MealPlanEntry, error) {
+	entries := []models.MealPlanEntry{}
+	err := tx.Where("date >= ? AND date <= ?", from, to).
+		Order("date ASC").
+		Order(mealSlotOrder).
+		Order("created_at ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *MealPlanRepository) GetByID(id string) (*models.MealPlanEntry, error) {
+	var entry models.MealPlanEntry
+	err := r.withRecipe(r.db.DB).First(&entry, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMealPlanEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *MealPlanRepository) Create(entry *models.MealPlanEntry) error {
+	now := auth.GetCurrentTimestamp()
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	return r.db.Omit("Recipe").Create(entry).Error
+}
+
+func (r *MealPlanRepository) Update(entry *models.MealPlanEntry) error {
+	entry.UpdatedAt = auth.GetCurrentTimestamp()
+	result := r.db.Model(&models.MealPlanEntry{}).Where("id = ?", entry.ID).
+		Updates(map[string]interface{}{
+			"date":       entry.Date,
+			"slot":       entry.Slot,
+			"recipe_id":  entry.RecipeID,
+			"text":       entry.Text,
+			"servings":   entry.Servings,
+			"updated_at": entry.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMealPlanEntryNotFound
+	}
+	return nil
+}
+
+func (r *MealPlanRepository) Delete(id string) error {
+	result := r.db.Delete(&models.MealPlanEntry{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMealPlanEntryNotFound
+	}
+	return nil
+}
+
+// GenerateNew creates list with the shopping for the meals planned from one
+// date to another in one transaction
+func (r *MealPlanRepository) GenerateNew(from, to string, usePantry bool, list *models.List) ([]models.Item, error) {
+	var created []models.Item
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		items, err := r.shopping(tx, from, to, usePantry)
+		if err != nil {
+			return err
+		}
+
+		txDB := &db.DB{DB: tx}
+		if err := NewListRepository(txDB).Create(list); err != nil {
+			return err
+		}
+		created, _, err = NewItemRepository(txDB).MergeIntoList(list.ID, items)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GenerateInto merges the shopping for the meals planned from one date to
+// another into an existing list in one transaction
+func (r *MealPlanRepository) GenerateInto(from, to string, usePantry bool, listID string) (created []models.Item, merged []MergedItem, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		lists := NewListRepository(txDB)
+		if _, err := lists.GetByID(listID); err != nil {
+			return err
+		}
+
+		items, err := r.shopping(tx, from, to, usePantry)
+		if err != nil {
+			return err
+		}
+		created, merged, err = NewItemRepository(txDB).MergeIntoList(listID, items)
+		if err != nil {
+			return err
+		}
+		return lists.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, merged, nil
+}
+
+// shopping loads what is needed to build the list for a date range
+func (r *MealPlanRepository) shopping(tx *gorm.DB, from, to string, usePantry bool) ([]models.Item, error) {
+	entries, err := getMealPlanRange(r.withRecipe(tx), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var pantry []models.PantryItem
+	if usePantry {
+		if err := tx.Where("quantity > 0").Find(&pantry).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var categories []models.Category
+	if err := tx.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	categoryOrder := make(map[string]int, len(categories))
+	for _, category := range categories {
+		categoryOrder[category.ID] = category.SortOrder
+	}
+
+	return MealPlanListItems(entries, pantry, categoryOrder), nil
+}
+
+// mealPlanNeed is how much of one ingredient the planned meals call for
+type mealPlanNeed struct {
+	name       string
+	amount     float64
+	unit       string
+	categoryID string
+	covered    bool
+}
+
+// MealPlanListItems adds up the ingredients of the planned recipes, each
+// scaled to its entry's servings, and subtracts pantry stock. Ingredients of
+// the same name (ignoring case) combine when their units are compatible.
+// Anything the pantry fully covers is left out. The items come grouped by
+// category in categoryOrder, then by name.
+func MealPlanListItems(entries []models.MealPlanEntry, pantry []models.PantryItem, categoryOrder map[string]int) []models.Item {
+	var needs []*mealPlanNeed
+	byName := make(map[string][]*mealPlanNeed)
+
+	for _, entry := range entries {
+		if entry.Recipe == nil {
+			continue
+		}
+		scale := 1.0
+		if entry.Servings > 0 && entry.Recipe.Servings > 0 {
+			scale = float64(entry.Servings) / float64(entry.Recipe.Servings)
+		}
+
+		for _, ingredient := range entry.Recipe.Ingredients {
+			key := strings.ToLower(strings.TrimSpace(ingredient.Name))
+			amount, unit := ingredient.Quantity*scale, unitOf(ingredient.Unit)
+			if need := compatibleNeed(byName[key], unit); need != nil {
+				converted, _ := units.Convert(amount, unit, need.unit)
+				need.amount += converted
+				continue
+			}
+
+			need := &mealPlanNeed{name: ingredient.Name, amount: amount, unit: unit, categoryID: ingredient.CategoryID}
+			needs = append(needs, need)
+			byName[key] = append(byName[key], need)
+		}
+	}
+
+	for _, stock := range pantry {
+		key := strings.ToLower(strings.TrimSpace(stock.Name))
+		if need := compatibleNeed(byName[key], unitOf(stock.Unit)); need != nil {
+			have, _ := units.Convert(float64(stock.Quantity), unitOf(stock.Unit), need.unit)
+			need.amount -= have
+			need.covered = true
+		}
+	}
+
+	items := make([]models.Item, 0, len(needs))
+	for _, need := range needs {
+		if need.covered && need.amount <= 1e-6 {
+			continue
+		}
+		quantity, unit := units.Whole(need.amount, need.unit)
+		items = append(items, models.Item{
+			Name:       need.name,
+			Quantity:   max(quantity, 1),
+			Unit:       nilIfEmpty(unit),
+			CategoryID: need.categoryID,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		oi, oj := categoryOrder[items[i].CategoryID], categoryOrder[items[j].CategoryID]
+		if oi != oj {
+			return oi < oj
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	return items
+}
+
+func compatibleNeed(needs []*mealPlanNeed, unit string) *mealPlanNeed {
+	for _, need := range needs {
+		if units.Compatible(need.unit, unit) {
+			return need
+		}
+	}
+	return nil
+}
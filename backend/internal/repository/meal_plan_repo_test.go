@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func TestMealPlanRepository_CRUD(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	recipes := NewRecipeRepository(itemRepo.db)
+	recipe := createTestRecipe(t, recipes)
+	repo := NewMealPlanRepository(itemRepo.db)
+
+	entries := []*models.MealPlanEntry{
+		{ID: "meal-1", Date: "2024-03-02", Slot: models.MealSlotDinner, RecipeID: &recipe.ID},
+		{ID: "meal-2", Date: "2024-03-02", Slot: models.MealSlotBreakfast, Text: strPtr("Toast")},
+		{ID: "meal-3", Date: "2024-03-01", Slot: models.MealSlotSnack, Text: strPtr("Fruit")},
+		{ID: "meal-4", Date: "2024-03-09", Slot: models.MealSlotLunch, Text: strPtr("Out")},
+	}
+	for _, entry := range entries {
+		if err := repo.Create(entry); err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+
+	week, err := repo.GetRange("2024-03-01", "2024-03-07")
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	if len(week) != 3 || week[0].ID != "meal-3" || week[1].ID != "meal-2" || week[2].ID != "meal-1" {
+		t.Fatalf("Expected entries by day and slot, got %+v", week)
+	}
+	if week[2].Recipe == nil || week[2].Recipe.Name != "Pancakes" || len(week[2].Recipe.Ingredients) != 4 {
+		t.Errorf("Expected the recipe loaded with ingredients, got %+v", week[2].Recipe)
+	}
+
+	entry, _ := repo.GetByID("meal-2")
+	entry.Slot = models.MealSlotLunch
+	entry.Servings = 2
+	if err := repo.Update(entry); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+	updated, _ := repo.GetByID("meal-2")
+	if updated.Slot != models.MealSlotLunch || updated.Servings != 2 {
+		t.Errorf("Expected the entry updated, got %+v", updated)
+	}
+
+	if err := repo.Delete("meal-4"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if _, err := repo.GetByID("meal-4"); err != ErrMealPlanEntryNotFound {
+		t.Errorf("Expected ErrMealPlanEntryNotFound, got %v", err)
+	}
+
+	// Deleting a recipe removes it from the plan
+	if err := recipes.Delete(recipe.ID); err != nil {
+		t.Fatalf("Failed to delete recipe: %v", err)
+	}
+	if _, err := repo.GetByID("meal-1"); err != ErrMealPlanEntryNotFound {
+		t.Errorf("Expected the planned recipe removed, got %v", err)
+	}
+}
+
+func TestMealPlanRepository_Generate(t *testing.T) {
+	itemRepo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	recipe := createTestRecipe(t, NewRecipeRepository(itemRepo.db))
+	repo := NewMealPlanRepository(itemRepo.db)
+	pantry := NewPantryRepository(itemRepo.db)
+
+	// Pancakes for 4 on Monday and for 8 on Tuesday: 750 g flour, 1.5 l milk
+	// and 6 eggs, less the 1 kg of flour and 2 eggs at home
+	for _, entry := range []*models.MealPlanEntry{
+		{ID: "meal-1", Date: "2024-03-04", Slot: models.MealSlotBreakfast, RecipeID: &recipe.ID},
+		{ID: "meal-2", Date: "2024-03-05", Slot: models.MealSlotBreakfast, RecipeID: &recipe.ID, Servings: 8},
+		{ID: "meal-3", Date: "2024-03-11", Slot: models.MealSlotBreakfast, RecipeID: &recipe.ID},
+	} {
+		if err := repo.Create(entry); err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+	if _, err := pantry.Create(&models.PantryItem{ID: "pantry-1", Name: "flour", Quantity: 1, Unit: strPtr("kg"), CategoryID: "test-cat"}, "user-1"); err != nil {
+		t.Fatalf("Failed to create pantry item: %v", err)
+	}
+	if _, err := pantry.Create(&models.PantryItem{ID: "pantry-2", Name: "Eggs", Quantity: 2, CategoryID: "test-cat"}, "user-1"); err != nil {
+		t.Fatalf("Failed to create pantry item: %v", err)
+	}
+
+	list := &models.List{ID: "list-meals", Name: "Meals", OwnerID: strPtr("user-1"), CreatedAt: 1000, UpdatedAt: 1000}
+	created, err := repo.GenerateNew("2024-03-04", "2024-03-10", true, list)
+	if err != nil {
+		t.Fatalf("Failed to generate list: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, item := range created {
+		got[item.Name] = formatQuantity(item.Quantity, item.Unit)
+	}
+	want := map[string]string{"Milk": "1500 ml", "Eggs": "4", "Salt": "1"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for name, quantity := range want {
+		if got[name] != quantity {
+			t.Errorf("Expected %s %s, got %q", quantity, name, got[name])
+		}
+	}
+	if _, err := listRepo.GetByID("list-meals"); err != nil {
+		t.Errorf("Expected the list created, got %v", err)
+	}
+
+	// Without the pantry the flour comes back and merges into the same list
+	_, merged, err := repo.GenerateInto("2024-03-04", "2024-03-04", false, "list-meals")
+	if err != nil {
+		t.Fatalf("Failed to generate into list: %v", err)
+	}
+	if len(merged) != 3 {
+		t.Errorf("Expected milk, eggs and salt merged, got %+v", merged)
+	}
+	if _, _, err := repo.GenerateInto("2024-03-04", "2024-03-04", true, "missing"); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestMealPlanListItems(t *testing.T) {
+	ingredient := func(name string, quantity float64, unit, categoryID string) models.RecipeIngredient {
+		return models.RecipeIngredient{Name: name, Quantity: quantity, Unit: nilIfEmpty(unit), CategoryID: categoryID}
+	}
+	soup := &models.Recipe{Servings: 2, Ingredients: []models.RecipeIngredient{
+		ingredient("Onion", 1, "", "produce"),
+		ingredient("Stock", 500, "ml", "pantry"),
+		ingredient("cream", 100, "ml", "dairy"),
+	}}
+	stew := &models.Recipe{Servings: 4, Ingredients: []models.RecipeIngredient{
+		ingredient("onion", 2, "", "produce"),
+		ingredient("Stock", 1, "l", "pantry"),
+		ingredient("Beef", 1, "lb", "meat"),
+		ingredient("Carrots", 3, "bunch", "produce"),
+		ingredient("Carrots", 200, "g", "produce"),
+	}}
+	entries := []models.MealPlanEntry{
+		{Recipe: soup, Servings: 3},
+		{Recipe: stew},
+		{Text: strPtr("Leftovers")},
+	}
+	pantry := []models.PantryItem{
+		{Name: "Cream", Quantity: 1, Unit: strPtr("l")},
+		{Name: "stock", Quantity: 250, Unit: strPtr("ml")},
+	}
+	order := map[string]int{"produce": 1, "dairy": 2, "meat": 3, "pantry": 4}
+
+	items := MealPlanListItems(entries, pantry, order)
+
+	want := []string{"Carrots 3 bunch", "Carrots 200 g", "Onion 4", "Beef 1 lb", "Stock 1500 ml"}
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d items, got %+v", len(want), items)
+	}
+	for i, item := range items {
+		if got := item.Name + " " + formatQuantity(item.Quantity, item.Unit); got != want[i] {
+			t.Errorf("Item %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+}
+
+func formatQuantity(quantity int, unit *string) string {
+	s := strconv.Itoa(quantity)
+	if unit != nil {
+		s += " " + *unit
+	}
+	return s
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+var ErrPantryItemNotFound = errors.New("pantry item not found")
+
+type PantryRepository struct {
+	db *db.DB
+}
+
+func NewPantryRepository(database *db.DB) *PantryRepository {
+	return &PantryRepository{db: database}
+}
+
+// GetAll returns the pantry sorted by name, only what is kept at location
+// when it is not empty
+func (r *PantryRepository) GetAll(location string) ([]models.PantryItem, error) {
+	items := []models.PantryItem{}
+	query := r.db.Order("LOWER(name) ASC")
+	if location != "" {
+		query = query.Where("LOWER(location) = LOWER(?)", location)
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *PantryRepository) GetByID(id string) (*models.PantryItem, error) {
+	var item models.PantryItem
+	if err := r.db.First(&item, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPantryItemNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Create adds a pantry item for userID. An item that starts out low is added
+// to its restock list straight away; that shopping list item is returned, or
+// nil.
+func (r *PantryRepository) Create(item *models.PantryItem, userID string) (*models.Item, error) {
+	now := auth.GetCurrentTimestamp()
+	item.CreatedAt = now
+	item.UpdatedAt = now
+
+	var restocked *models.Item
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		var err error
+		restocked, err = restock(tx, item, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restocked, nil
+}
+
+// Update saves a pantry item for userID. When the change takes it down to its
+// low-stock threshold it is added to its restock list, and that shopping list
+// item is returned; otherwise the result is nil.
+func (r *PantryRepository) Update(item *models.PantryItem, userID string) (*models.Item, error) {
+	item.UpdatedAt = auth.GetCurrentTimestamp()
+
+	var restocked *models.Item
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var previous models.PantryItem
+		if err := tx.First(&previous, "id = ?", item.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPantryItemNotFound
+			}
+			return err
+		}
+
+		if err := tx.Save(item).Error; err != nil {
+			return err
+		}
+
+		if previous.IsLow() {
+			return nil
+		}
+		var err error
+		restocked, err = restock(tx, item, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restocked, nil
+}
+
+// GetExpiring returns pantry items in stock that expire by until, soonest
+// first
+func (r *PantryRepository) GetExpiring(until int64) ([]models.PantryItem, error) {
+	items := []models.PantryItem{}
+	err := r.db.Where("expires_at IS NOT NULL AND expires_at <= ? AND quantity > 0", until).
+		Order("expires_at ASC, LOWER(name) ASC").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *PantryRepository) Delete(id string) error {
+	result := r.db.Delete(&models.PantryItem{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPantryItemNotFound
+	}
+	return nil
+}
+
+// AddStock adds stock to the pantry item with the same name and unit
+// (ignoring case), or creates one from stock when there is none. The earlier
+// best-before date wins. It returns the pantry item as stored.
+func (r *PantryRepository) AddStock(stock models.PantryItem) (*models.PantryItem, error) {
+	now := auth.GetCurrentTimestamp()
+	var result models.PantryItem
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("LOWER(name) = LOWER(?)", stock.Name)
+		if stock.Unit == nil || *stock.Unit == "" {
+			query = query.Where("(unit IS NULL OR unit = '')")
+		} else {
+			query = query.Where("LOWER(unit) = LOWER(?)", *stock.Unit)
+		}
+		err := query.Order("created_at ASC").First(&result).Error
+
+		if err == nil {
+			result.Quantity += stock.Quantity
+			result.UpdatedAt = now
+			if stock.ExpiresAt != nil && (result.ExpiresAt == nil || *stock.ExpiresAt < *result.ExpiresAt) {
+				result.ExpiresAt = stock.ExpiresAt
+			}
+			return tx.Model(&result).Updates(map[string]interface{}{
+				"quantity":   result.Quantity,
+				"expires_at": result.ExpiresAt,
+				"updated_at": now,
+			}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		result = stock
+		result.ID = auth.GenerateID()
+		result.CreatedAt = now
+		result.UpdatedAt = now
+		return tx.Create(&result).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CheckIn checks a list item and adds its quantity to the pantry in one
+// transaction. A new pantry item is kept at location; expiresAt is the
+// best-before date of what was bought.
+func (r *PantryRepository) CheckIn(itemID, userID, userName string, location *string, expiresAt *int64) (*models.Item, *models.PantryItem, error) {
+	var item *models.Item
+	var stocked *models.PantryItem
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		var err error
+		item, err = NewItemRepository(txDB).ToggleChecked(itemID, userID, userName, nil)
+		if err != nil {
+			return err
+		}
+		if !item.Checked {
+			return nil
+		}
+		stocked, err = NewPantryRepository(txDB).AddStock(StockFromItem(item, location, expiresAt))
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return item, stocked, nil
+}
+
+// StockFromItem describes what checking a list item brings home
+func StockFromItem(item *models.Item, location *string, expiresAt *int64) models.PantryItem {
+	return models.PantryItem{
+		Name:       item.Name,
+		Quantity:   item.Quantity,
+		Unit:       item.Unit,
+		Location:   location,
+		CategoryID: item.CategoryID,
+		ExpiresAt:  expiresAt,
+	}
+}
+
+// restock adds a low pantry item to its restock list on behalf of userID,
+// unless the list is gone, userID can no longer edit it (the pantry is
+// shared, the list may not be) or it already has the item unchecked
+func restock(tx *gorm.DB, item *models.PantryItem, userID string) (*models.Item, error) {
+	if !item.IsLow() || item.RestockListID == nil {
+		return nil, nil
+	}
+	listID := *item.RestockListID
+	txDB := &db.DB{DB: tx}
+
+	role, err := NewListMemberRepository(txDB).GetRole(listID, userID)
+	if errors.Is(err, ErrListNotFound) || errors.Is(err, ErrMemberNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !role.AtLeast(models.ListRoleEditor) {
+		return nil, nil
+	}
+
+	var existing int64
+	if err := tx.Model(&models.Item{}).
+		Where("list_id = ? AND deleted_at IS NULL AND checked = ? AND LOWER(name) = LOWER(?)", listID, false, item.Name).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, nil
+	}
+
+	items := []models.Item{{
+		ID:         auth.GenerateID(),
+		Name:       item.Name,
+		Quantity:   1,
+		CategoryID: item.CategoryID,
+	}}
+	txRepo := &ItemRepository{db: txDB}
+	if err := txRepo.CreateMany(listID, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
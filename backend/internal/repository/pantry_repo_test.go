@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func setupPantryTestDB(t *testing.T) (*PantryRepository, *ItemRepository, func()) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	return NewPantryRepository(itemRepo.db), itemRepo, cleanup
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestPantryRepository_CRUD(t *testing.T) {
+	repo, _, cleanup := setupPantryTestDB(t)
+	defer cleanup()
+
+	for _, item := range []*models.PantryItem{
+		{ID: "p-1", Name: "rice", Quantity: 2, Location: strPtr("Pantry"), CategoryID: "test-cat"},
+		{ID: "p-2", Name: "Butter", Quantity: 1, Location: strPtr("Fridge"), CategoryID: "test-cat"},
+	} {
+		if _, err := repo.Create(item, "user-1"); err != nil {
+			t.Fatalf("Failed to create pantry item: %v", err)
+		}
+	}
+
+	all, err := repo.GetAll("")
+	if err != nil {
+		t.Fatalf("Failed to get pantry: %v", err)
+	}
+	if len(all) != 2 || all[0].Name != "Butter" || all[1].Name != "rice" {
+		t.Errorf("Expected items sorted by name, got %+v", all)
+	}
+
+	fridge, _ := repo.GetAll("fridge")
+	if len(fridge) != 1 || fridge[0].ID != "p-2" {
+		t.Errorf("Expected only the fridge, got %+v", fridge)
+	}
+
+	item, _ := repo.GetByID("p-1")
+	item.Quantity = 5
+	if _, err := repo.Update(item, "user-1"); err != nil {
+		t.Fatalf("Failed to update pantry item: %v", err)
+	}
+	if found, _ := repo.GetByID("p-1"); found.Quantity != 5 {
+		t.Errorf("Expected quantity 5, got %d", found.Quantity)
+	}
+
+	if err := repo.Delete("p-1"); err != nil {
+		t.Fatalf("Failed to delete pantry item: %v", err)
+	}
+	if _, err := repo.GetByID("p-1"); err != ErrPantryItemNotFound {
+		t.Errorf("Expected ErrPantryItemNotFound, got %v", err)
+	}
+	if err := repo.Delete("p-1"); err != ErrPantryItemNotFound {
+		t.Errorf("Expected ErrPantryItemNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestPantryRepository_AddStock(t *testing.T) {
+	repo, _, cleanup := setupPantryTestDB(t)
+	defer cleanup()
+
+	first, err := repo.AddStock(models.PantryItem{Name: "Milk", Quantity: 1, Unit: strPtr("l"), CategoryID: "test-cat"})
+	if err != nil {
+		t.Fatalf("Failed to add stock: %v", err)
+	}
+	second, _ := repo.AddStock(models.PantryItem{Name: "milk", Quantity: 2, Unit: strPtr("L"), CategoryID: "test-cat"})
+	if second.ID != first.ID || second.Quantity != 3 {
+		t.Errorf("Expected stock added to the same item, got %+v", second)
+	}
+
+	other, _ := repo.AddStock(models.PantryItem{Name: "Milk", Quantity: 1, CategoryID: "test-cat"})
+	if other.ID == first.ID {
+		t.Error("Expected a different unit to make a new pantry item")
+	}
+}
+
+func TestPantryRepository_Restock(t *testing.T) {
+	repo, itemRepo, cleanup := setupPantryTestDB(t)
+	defer cleanup()
+
+	item := &models.PantryItem{ID: "p-1", Name: "Eggs", Quantity: 6, CategoryID: "test-cat", LowStockThreshold: intPtr(2), RestockListID: strPtr("list-1")}
+	if restocked, err := repo.Create(item, "user-1"); err != nil || restocked != nil {
+		t.Fatalf("Expected no restock for a full item, got %v, %v", restocked, err)
+	}
+
+	item.Quantity = 2
+	restocked, err := repo.Update(item, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to update pantry item: %v", err)
+	}
+	if restocked == nil || restocked.ListID != "list-1" || restocked.Name != "Eggs" || restocked.Quantity != 1 {
+		t.Fatalf("Expected Eggs added to list-1, got %+v", restocked)
+	}
+
+	// Already low: no second item
+	item.Quantity = 1
+	if restocked, _ := repo.Update(item, "user-1"); restocked != nil {
+		t.Errorf("Expected no restock while already low, got %+v", restocked)
+	}
+
+	// Low again after restocking, but the list still has it unchecked
+	item.Quantity = 12
+	_, _ = repo.Update(item, "user-1")
+	item.Quantity = 0
+	if restocked, _ := repo.Update(item, "user-1"); restocked != nil {
+		t.Errorf("Expected no restock while the list has Eggs, got %+v", restocked)
+	}
+
+	items, _ := itemRepo.GetByListID("list-1")
+	if len(items) != 1 {
+		t.Errorf("Expected one item on the list, got %d", len(items))
+	}
+}
+
+func TestPantryRepository_RestockNeedsEditor(t *testing.T) {
+	repo, itemRepo, cleanup := setupPantryTestDB(t)
+	defer cleanup()
+
+	item := &models.PantryItem{ID: "p-1", Name: "Eggs", Quantity: 6, CategoryID: "test-cat", LowStockThreshold: intPtr(2), RestockListID: strPtr("list-1")}
+	if _, err := repo.Create(item, "user-1"); err != nil {
+		t.Fatalf("Failed to create pantry item: %v", err)
+	}
+
+	// user-2 shares the pantry but not list-1
+	item.Quantity = 1
+	restocked, err := repo.Update(item, "user-2")
+	if err != nil {
+		t.Fatalf("Failed to update pantry item: %v", err)
+	}
+	if restocked != nil {
+		t.Errorf("Expected no restock into a list the user cannot edit, got %+v", restocked)
+	}
+
+	items, _ := itemRepo.GetByListID("list-1")
+	if len(items) != 0 {
+		t.Errorf("Expected the list to be left alone, got %+v", items)
+	}
+}
+
+func TestPantryRepository_CheckIn(t *testing.T) {
+	repo, itemRepo, cleanup := setupPantryTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Flour", Quantity: 2, Unit: strPtr("kg"), CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	expiresAt := int64(5000)
+	checked, stocked, err := repo.CheckIn("item-1", "user-1", "Test User", strPtr("Pantry"), &expiresAt)
+	if err != nil {
+		t.Fatalf("Failed to check in: %v", err)
+	}
+	if !checked.Checked {
+		t.Error("Expected the item to be checked")
+	}
+	if stocked == nil || stocked.Name != "Flour" || stocked.Quantity != 2 || *stocked.Unit != "kg" || *stocked.Location != "Pantry" {
+		t.Errorf("Expected 2 kg of flour in the pantry, got %+v", stocked)
+	}
+	if checked.ExpiresAt != nil || stocked.ExpiresAt == nil || *stocked.ExpiresAt != 5000 {
+		t.Errorf("Expected the best-before date on the pantry item only, got %v and %v", checked.ExpiresAt, stocked.ExpiresAt)
+	}
+}
+
+func TestPantryRepository_GetExpiring(t *testing.T) {
+	repo, _, cleanup := setupPantryTestDB(t)
+	defer cleanup()
+
+	soon, later, past := int64(2000), int64(9000), int64(500)
+	for _, item := range []*models.PantryItem{
+		{ID: "p-1", Name: "Yogurt", Quantity: 2, ExpiresAt: &soon},
+		{ID: "p-2", Name: "Rice", Quantity: 1, ExpiresAt: &later},
+		{ID: "p-3", Name: "Milk", Quantity: 1, ExpiresAt: &past},
+		{ID: "p-4", Name: "Cream", Quantity: 0, ExpiresAt: &soon},
+		{ID: "p-5", Name: "Salt", Quantity: 1},
+	} {
+		item.CategoryID = "test-cat"
+		if _, err := repo.Create(item, "user-1"); err != nil {
+			t.Fatalf("Failed to create pantry item: %v", err)
+		}
+	}
+
+	items, err := repo.GetExpiring(5000)
+	if err != nil {
+		t.Fatalf("Failed to get expiring items: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "p-3" || items[1].ID != "p-1" {
+		t.Errorf("Expected Milk then Yogurt, got %+v", items)
+	}
+
+	// Merging keeps the earlier date
+	merged, _ := repo.AddStock(models.PantryItem{Name: "rice", Quantity: 1, CategoryID: "test-cat", ExpiresAt: &soon})
+	if merged.ID != "p-2" || *merged.ExpiresAt != soon {
+		t.Errorf("Expected rice to take the earlier date, got %+v", merged)
+	}
+}
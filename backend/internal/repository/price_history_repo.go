@@ -1,8 +1,16 @@
 package repository
 
 import (
+	"math"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/quickadd"
 )
 
 type PriceHistoryRepository struct {
@@ -14,12 +22,15 @@ func NewPriceHistoryRepository(database *db.DB) *PriceHistoryRepository {
 }
 
 func (r *PriceHistoryRepository) Create(ph *models.PriceHistory) error {
+	ph.NormalizedName = models.NormalizeItemName(ph.ItemName)
 	return r.db.Create(ph).Error
 }
 
+// GetByItemName returns the prices recorded for an item, newest first. Names
+// match ignoring case and extra whitespace.
 func (r *PriceHistoryRepository) GetByItemName(itemName string) ([]models.PriceHistory, error) {
 	var history []models.PriceHistory
-	err := r.db.Where("item_name = ?", itemName).
+	err := r.db.Where("normalized_name = ?", models.NormalizeItemName(itemName)).
 		Order("recorded_at DESC").
 		Find(&history).Error
 
@@ -36,7 +47,7 @@ func (r *PriceHistoryRepository) GetByItemName(itemName string) ([]models.PriceH
 
 func (r *PriceHistoryRepository) GetLatestByItemName(itemName string) (*models.PriceHistory, error) {
 	var ph models.PriceHistory
-	err := r.db.Where("item_name = ?", itemName).
+	err := r.db.Where("normalized_name = ?", models.NormalizeItemName(itemName)).
 		Order("recorded_at DESC").
 		First(&ph).Error
 
@@ -46,3 +57,197 @@ func (r *PriceHistoryRepository) GetLatestByItemName(itemName string) (*models.P
 
 	return &ph, nil
 }
+
+// GetStats summarizes the prices recorded for an item since a time, overall
+// and per store. Names match like GetByItemName and stores ignoring case. It
+// returns nil when nothing was recorded in the window.
+func (r *PriceHistoryRepository) GetStats(itemName string, since int64) (*models.PriceStats, error) {
+	var history []models.PriceHistory
+	err := r.db.Where("normalized_name = ? AND recorded_at >= ?", models.NormalizeItemName(itemName), since).
+		Order("recorded_at ASC").
+		Order("id ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+	for i := range history {
+		history[i].SetUnitPrice()
+	}
+
+	last := history[len(history)-1]
+	stats := &models.PriceStats{
+		ItemName:     last.ItemName,
+		Since:        since,
+		LastRecorded: last.RecordedAt,
+		Stores:       []models.StorePrice{},
+	}
+	stats.Count, stats.Min, stats.Max, stats.Average, stats.Last = summarizePrices(history)
+	stats.UnitPrice = averageUnitPrice(history)
+	if first := history[0].Price; first > 0 {
+		change := roundCents((stats.Last - first) / first * 100)
+		stats.Change = &change
+	}
+
+	var storeOrder []string
+	byStore := make(map[string][]models.PriceHistory)
+	for _, ph := range history {
+		key := ""
+		if ph.Store != nil {
+			key = strings.ToLower(strings.TrimSpace(*ph.Store))
+		}
+		if _, ok := byStore[key]; !ok {
+			storeOrder = append(storeOrder, key)
+		}
+		byStore[key] = append(byStore[key], ph)
+	}
+
+	for _, key := range storeOrder {
+		prices := byStore[key]
+		newest := prices[len(prices)-1]
+		store := models.StorePrice{LastRecorded: newest.RecordedAt}
+		if key != "" {
+			// Show the store as it was last written
+			name := strings.TrimSpace(*newest.Store)
+			store.Store = &name
+		}
+		store.Count, store.Min, store.Max, store.Average, store.Last = summarizePrices(prices)
+		store.UnitPrice = averageUnitPrice(prices)
+		stats.Stores = append(stats.Stores, store)
+	}
+
+	// Unit prices compare different package sizes, so they decide when every
+	// store has one
+	byUnitPrice := true
+	for _, store := range stats.Stores {
+		if store.UnitPrice == nil || store.UnitPrice.Per != stats.Stores[0].UnitPrice.Per {
+			byUnitPrice = false
+			break
+		}
+	}
+	sort.SliceStable(stats.Stores, func(i, j int) bool {
+		if byUnitPrice {
+			return stats.Stores[i].UnitPrice.Price < stats.Stores[j].UnitPrice.Price
+		}
+		return stats.Stores[i].Average < stats.Stores[j].Average
+	})
+	for _, store := range stats.Stores {
+		if store.Store != nil {
+			stats.CheapestStore = store.Store
+			break
+		}
+	}
+	return stats, nil
+}
+
+// PriceFromItem is the price history entry for buying item: the name, unit
+// and store trimmed and normalized, and the price rounded to cents. It
+// reports false when the item has no price.
+func PriceFromItem(item *models.Item, recordedAt int64) (models.PriceHistory, bool) {
+	if item.Price == nil || *item.Price < 0 {
+		return models.PriceHistory{}, false
+	}
+
+	name := strings.Join(strings.Fields(item.Name), " ")
+	ph := models.PriceHistory{
+		ItemName:       name,
+		NormalizedName: models.NormalizeItemName(name),
+		Price:          roundCents(*item.Price),
+		ItemID:         &item.ID,
+		RecordedAt:     recordedAt,
+	}
+	if item.Unit != nil {
+		unit := strings.TrimSpace(*item.Unit)
+		if normalized := quickadd.NormalizeUnit(unit); normalized != "" {
+			unit = normalized
+		}
+		ph.Unit = nilIfEmpty(unit)
+	}
+	if item.PackageSize != nil && *item.PackageSize > 0 {
+		ph.PackageSize = item.PackageSize
+		ph.PackageUnit = item.PackageUnit
+	}
+	if item.Store != nil {
+		ph.Store = nilIfEmpty(strings.TrimSpace(*item.Store))
+	}
+	return ph, true
+}
+
+// recordCheckedPrice adds the price of an item that was just checked off to
+// the history. Checking the same item again at the same price, unit, package
+// and store adds nothing.
+func recordCheckedPrice(tx *gorm.DB, item *models.Item, now int64) error {
+	ph, ok := PriceFromItem(item, now)
+	if !ok {
+		return nil
+	}
+
+	var previous models.PriceHistory
+	err := tx.Where("item_id = ?", item.ID).Order("recorded_at DESC").Order("id DESC").Limit(1).Find(&previous).Error
+	if err != nil {
+		return err
+	}
+	if previous.ID != "" && previous.Price == ph.Price && sameString(previous.Unit, ph.Unit) && sameString(previous.Store, ph.Store) &&
+		sameFloat(previous.PackageSize, ph.PackageSize) && sameString(previous.PackageUnit, ph.PackageUnit) {
+		return nil
+	}
+
+	ph.ID = auth.GenerateID()
+	return tx.Create(&ph).Error
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameFloat(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// averageUnitPrice averages the unit prices of prices, oldest first, that are
+// in the same reference unit as the newest one with a unit price
+func averageUnitPrice(prices []models.PriceHistory) *models.UnitPrice {
+	per := ""
+	for i := len(prices) - 1; i >= 0 && per == ""; i-- {
+		if prices[i].UnitPrice != nil {
+			per = prices[i].UnitPrice.Per
+		}
+	}
+	if per == "" {
+		return nil
+	}
+
+	sum, count := 0.0, 0
+	for _, ph := range prices {
+		if ph.UnitPrice != nil && ph.UnitPrice.Per == per {
+			sum += ph.UnitPrice.Price
+			count++
+		}
+	}
+	return &models.UnitPrice{Price: roundCents(sum / float64(count)), Per: per}
+}
+
+// summarizePrices returns the count, min, max, average and last of prices
+// sorted oldest first
+func summarizePrices(prices []models.PriceHistory) (count int, minPrice, maxPrice, average, last float64) {
+	minPrice, maxPrice = prices[0].Price, prices[0].Price
+	sum := 0.0
+	for _, ph := range prices {
+		minPrice = min(minPrice, ph.Price)
+		maxPrice = max(maxPrice, ph.Price)
+		sum += ph.Price
+	}
+	return len(prices), minPrice, maxPrice, roundCents(sum / float64(len(prices))), prices[len(prices)-1].Price
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func recordPrice(t *testing.T, repo *PriceHistoryRepository, id, name string, price float64, store *string, at int64) {
+	if err := repo.Create(&models.PriceHistory{ID: id, ItemName: name, Price: price, Store: store, RecordedAt: at}); err != nil {
+		t.Fatalf("Failed to record price: %v", err)
+	}
+}
+
+func TestPriceHistoryRepository_GetByItemName(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewPriceHistoryRepository(database)
+
+	recordPrice(t, repo, "ph-1", "Whole Milk", 3.49, nil, 1000)
+	recordPrice(t, repo, "ph-2", "  whole   MILK ", 3.79, nil, 2000)
+	recordPrice(t, repo, "ph-3", "Oat Milk", 4.99, nil, 3000)
+
+	history, err := repo.GetByItemName("whole milk")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 2 || history[0].ID != "ph-2" || history[1].ID != "ph-1" {
+		t.Errorf("Expected both spellings newest first, got %+v", history)
+	}
+
+	latest, _ := repo.GetLatestByItemName("WHOLE MILK")
+	if latest == nil || latest.ID != "ph-2" {
+		t.Errorf("Expected the latest price, got %+v", latest)
+	}
+}
+
+func TestPriceHistoryRepository_GetStats(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewPriceHistoryRepository(database)
+
+	recordPrice(t, repo, "ph-0", "Coffee", 5.00, strPtr("Aldi"), 500)
+	recordPrice(t, repo, "ph-1", "Coffee", 8.00, strPtr("Costco"), 1000)
+	recordPrice(t, repo, "ph-2", "coffee", 6.00, strPtr("aldi "), 2000)
+	recordPrice(t, repo, "ph-3", "Coffee", 10.00, strPtr("Costco"), 3000)
+	recordPrice(t, repo, "ph-4", "COFFEE", 7.00, nil, 4000)
+	recordPrice(t, repo, "ph-5", "Coffee", 6.50, strPtr("ALDI"), 5000)
+
+	stats, err := repo.GetStats("coffee", 1000)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Count != 5 || stats.Min != 6 || stats.Max != 10 || stats.Average != 7.5 || stats.Last != 6.5 {
+		t.Errorf("Unexpected summary: %+v", stats)
+	}
+	if stats.LastRecorded != 5000 || stats.ItemName != "Coffee" {
+		t.Errorf("Expected the last record's name and time, got %+v", stats)
+	}
+	if stats.Change == nil || *stats.Change != -18.75 {
+		t.Errorf("Expected -18.75%% change, got %v", stats.Change)
+	}
+
+	if len(stats.Stores) != 3 {
+		t.Fatalf("Expected 3 stores, got %+v", stats.Stores)
+	}
+	aldi := stats.Stores[0]
+	if aldi.Store == nil || *aldi.Store != "ALDI" || aldi.Count != 2 || aldi.Average != 6.25 || aldi.Last != 6.5 {
+		t.Errorf("Expected Aldi cheapest, got %+v", aldi)
+	}
+	if stats.Stores[1].Store != nil || stats.Stores[1].Average != 7 {
+		t.Errorf("Expected prices without a store second, got %+v", stats.Stores[1])
+	}
+	if costco := stats.Stores[2]; costco.Store == nil || *costco.Store != "Costco" || costco.Min != 8 || costco.Max != 10 {
+		t.Errorf("Expected Costco last, got %+v", costco)
+	}
+	if stats.CheapestStore == nil || *stats.CheapestStore != "ALDI" {
+		t.Errorf("Expected ALDI cheapest, got %v", stats.CheapestStore)
+	}
+
+	none, err := repo.GetStats("coffee", 6000)
+	if err != nil || none != nil {
+		t.Errorf("Expected no stats after the last price, got %+v, %v", none, err)
+	}
+}
+
+func TestItemRepository_ToggleCheckedRecordsPrice(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewPriceHistoryRepository(itemRepo.db)
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: " Ground  Beef", Quantity: 2, Unit: strPtr("Pounds"),
+		CategoryID: "test-cat", Price: floatPtr(5.499), Store: strPtr(" Costco ")}
+	plain := &models.Item{ID: "item-2", ListID: "list-1", Name: "Salt", Quantity: 1, CategoryID: "test-cat"}
+	for _, it := range []*models.Item{item, plain} {
+		if err := itemRepo.Create(it); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	// Check, uncheck and check again: one entry
+	for i := 0; i < 3; i++ {
+		if _, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+			t.Fatalf("Failed to toggle item: %v", err)
+		}
+	}
+	if _, err := itemRepo.ToggleChecked("item-2", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+
+	history, _ := repo.GetByItemName("ground beef")
+	if len(history) != 1 {
+		t.Fatalf("Expected 1 price recorded, got %+v", history)
+	}
+	ph := history[0]
+	if ph.ItemName != "Ground Beef" || ph.Price != 5.5 || ph.Unit == nil || *ph.Unit != "lb" ||
+		ph.Store == nil || *ph.Store != "Costco" || ph.ItemID == nil || *ph.ItemID != "item-1" {
+		t.Errorf("Expected a normalized entry, got %+v", ph)
+	}
+	var total int64
+	itemRepo.db.Model(&models.PriceHistory{}).Count(&total)
+	if total != 1 {
+		t.Errorf("Expected nothing recorded without a price, got %d entries", total)
+	}
+
+	// A new price is recorded when the item is checked again
+	_, _ = itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil)
+	itemRepo.db.Model(&models.Item{}).Where("id = ?", "item-1").Update("price", 4.99)
+	if _, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+	history, _ = repo.GetByItemName("Ground Beef")
+	if len(history) != 2 || !hasPrice(history, 4.99) {
+		t.Errorf("Expected the new price recorded, got %+v", history)
+	}
+
+	// Bulk checking records prices too
+	if _, err := itemRepo.BulkSetChecked("list-1", models.ItemSelection{Filter: models.ItemFilterAll}, false, "user-1", "Test User"); err != nil {
+		t.Fatalf("Failed to uncheck items: %v", err)
+	}
+	itemRepo.db.Model(&models.Item{}).Where("id = ?", "item-1").Update("price", 4.49)
+	if _, err := itemRepo.BulkSetChecked("list-1", models.ItemSelection{Filter: models.ItemFilterAll}, true, "user-1", "Test User"); err != nil {
+		t.Fatalf("Failed to check items: %v", err)
+	}
+	history, _ = repo.GetByItemName("ground beef")
+	if len(history) != 3 || !hasPrice(history, 4.49) {
+		t.Errorf("Expected bulk check to record the price, got %+v", history)
+	}
+}
+
+func hasPrice(history []models.PriceHistory, price float64) bool {
+	for _, ph := range history {
+		if ph.Price == price {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPriceHistoryRepository_GetStatsByUnitPrice(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewPriceHistoryRepository(database)
+
+	record := func(id string, price, size float64, unit, store string, at int64) {
+		ph := &models.PriceHistory{ID: id, ItemName: "Rice", Price: price, PackageSize: &size, PackageUnit: &unit, Store: &store, RecordedAt: at}
+		if err := repo.Create(ph); err != nil {
+			t.Fatalf("Failed to record price: %v", err)
+		}
+	}
+	// Aldi's bag is cheaper but Costco's is bigger and cheaper by the kilo
+	record("ph-1", 3.99, 500, "g", "Aldi", 1000)
+	record("ph-2", 6.49, 1, "kg", "Costco", 2000)
+	record("ph-3", 4.19, 0.5, "kg", "Aldi", 3000)
+
+	stats, err := repo.GetStats("rice", 0)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.UnitPrice == nil || stats.UnitPrice.Per != "kg" || stats.UnitPrice.Price != 7.62 {
+		t.Errorf("Expected an average of 7.62 per kg, got %+v", stats.UnitPrice)
+	}
+	if len(stats.Stores) != 2 || *stats.Stores[0].Store != "Costco" || stats.Stores[0].UnitPrice.Price != 6.49 {
+		t.Fatalf("Expected Costco cheapest by the kilo, got %+v", stats.Stores)
+	}
+	if aldi := stats.Stores[1]; aldi.UnitPrice == nil || aldi.UnitPrice.Price != 8.18 || aldi.Average != 4.09 {
+		t.Errorf("Expected Aldi at 8.18 per kg, got %+v", aldi)
+	}
+	if *stats.CheapestStore != "Costco" {
+		t.Errorf("Expected Costco cheapest, got %v", *stats.CheapestStore)
+	}
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+	"github.com/kleyson/groceries/backend/internal/units"
+)
+
+var ErrRecipeNotFound = errors.New("recipe not found")
+
+type RecipeRepository struct {
+	db *db.DB
+}
+
+func NewRecipeRepository(database *db.DB) *RecipeRepository {
+	return &RecipeRepository{db: database}
+}
+
+func (r *RecipeRepository) withDetails() *gorm.DB {
+	return r.db.
+		Preload("Ingredients", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("sort_order ASC")
+		}).
+		Preload("Steps", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("position ASC")
+		})
+}
+
+// Create saves a recipe with its ingredients and steps
+func (r *RecipeRepository) Create(recipe *models.Recipe) error {
+	now := auth.GetCurrentTimestamp()
+	recipe.CreatedAt = now
+	recipe.UpdatedAt = now
+	prepareRecipeDetails(recipe)
+	return r.db.Create(recipe).Error
+}
+
+// GetAll returns every recipe sorted by name
+func (r *RecipeRepository) GetAll() ([]models.Recipe, error) {
+	recipes := []models.Recipe{}
+	if err := r.withDetails().Order("LOWER(name) ASC").Find(&recipes).Error; err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
+
+func (r *RecipeRepository) GetByID(id string) (*models.Recipe, error) {
+	var recipe models.Recipe
+	err := r.withDetails().First(&recipe, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecipeNotFound
+		}
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// Replace overwrites a recipe, swapping in its new ingredients and steps
+func (r *RecipeRepository) Replace(recipe *models.Recipe) error {
+	recipe.UpdatedAt = auth.GetCurrentTimestamp()
+	prepareRecipeDetails(recipe)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Recipe{}).Where("id = ?", recipe.ID).
+			Updates(map[string]interface{}{
+				"name":        recipe.Name,
+				"description": recipe.Description,
+				"servings":    recipe.Servings,
+				"updated_at":  recipe.UpdatedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecipeNotFound
+		}
+
+		if err := deleteRecipeDetails(tx, recipe.ID); err != nil {
+			return err
+		}
+		if len(recipe.Ingredients) > 0 {
+			if err := tx.Create(&recipe.Ingredients).Error; err != nil {
+				return err
+			}
+		}
+		if len(recipe.Steps) > 0 {
+			if err := tx.Create(&recipe.Steps).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *RecipeRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteRecipeDetails(tx, id); err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Recipe{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecipeNotFound
+		}
+		return nil
+	})
+}
+
+// AddToList adds a recipe's ingredients, scaled from the recipe's servings to
+// servings, to a list in one transaction. Ingredients merge into unchecked
+// items of the same name and a compatible unit.
+func (r *RecipeRepository) AddToList(recipe *models.Recipe, listID string, servings int) (created []models.Item, merged []MergedItem, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		lists := NewListRepository(txDB)
+		if _, err := lists.GetByID(listID); err != nil {
+			return err
+		}
+
+		created, merged, err = NewItemRepository(txDB).MergeIntoList(listID, RecipeListItems(recipe.Ingredients, recipe.Servings, servings))
+		if err != nil {
+			return err
+		}
+		return lists.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, merged, nil
+}
+
+// RecipeListItems turns ingredients for servings into list items for
+// wanted servings. Fractional amounts move to a smaller unit where there is
+// one and round up otherwise; every item gets at least 1.
+func RecipeListItems(ingredients []models.RecipeIngredient, servings, wanted int) []models.Item {
+	scale := 1.0
+	if servings > 0 && wanted > 0 {
+		scale = float64(wanted) / float64(servings)
+	}
+
+	items := make([]models.Item, len(ingredients))
+	for i, ingredient := range ingredients {
+		quantity, unit := units.Whole(ingredient.Quantity*scale, unitOf(ingredient.Unit))
+		items[i] = models.Item{
+			Name:       ingredient.Name,
+			Quantity:   max(quantity, 1),
+			Unit:       nilIfEmpty(unit),
+			CategoryID: ingredient.CategoryID,
+		}
+	}
+	return items
+}
+
+// prepareRecipeDetails assigns IDs and order to a recipe's ingredients and
+// steps
+func prepareRecipeDetails(recipe *models.Recipe) {
+	for i := range recipe.Ingredients {
+		recipe.Ingredients[i].ID = auth.GenerateID()
+		recipe.Ingredients[i].RecipeID = recipe.ID
+		recipe.Ingredients[i].SortOrder = i
+	}
+	for i := range recipe.Steps {
+		recipe.Steps[i].ID = auth.GenerateID()
+		recipe.Steps[i].RecipeID = recipe.ID
+		recipe.Steps[i].Position = i + 1
+	}
+}
+
+func deleteRecipeDetails(tx *gorm.DB, recipeID string) error {
+	if err := tx.Where("recipe_id = ?", recipeID).Delete(&models.RecipeIngredient{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("recipe_id = ?", recipeID).Delete(&models.RecipeStep{}).Error
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func createTestRecipe(t *testing.T, repo *RecipeRepository) *models.Recipe {
+	recipe := &models.Recipe{
+		ID:       "recipe-1",
+		Name:     "Pancakes",
+		Servings: 4,
+		Ingredients: []models.RecipeIngredient{
+			{Name: "Flour", Quantity: 250, Unit: strPtr("g"), CategoryID: "test-cat"},
+			{Name: "Milk", Quantity: 0.5, Unit: strPtr("l"), CategoryID: "test-cat"},
+			{Name: "Eggs", Quantity: 2, CategoryID: "test-cat"},
+			{Name: "Salt", Quantity: 0, CategoryID: "test-cat"},
+		},
+		Steps: []models.RecipeStep{{Text: "Mix"}, {Text: "Fry"}},
+	}
+	if err := repo.Create(recipe); err != nil {
+		t.Fatalf("Failed to create recipe: %v", err)
+	}
+	return recipe
+}
+
+func TestRecipeRepository_CRUD(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewRecipeRepository(itemRepo.db)
+	createTestRecipe(t, repo)
+
+	found, err := repo.GetByID("recipe-1")
+	if err != nil {
+		t.Fatalf("Failed to get recipe: %v", err)
+	}
+	if len(found.Ingredients) != 4 || found.Ingredients[1].Name != "Milk" || found.Ingredients[1].Quantity != 0.5 {
+		t.Errorf("Expected ingredients in order, got %+v", found.Ingredients)
+	}
+	if len(found.Steps) != 2 || found.Steps[0].Text != "Mix" || found.Steps[1].Position != 2 {
+		t.Errorf("Expected steps in order, got %+v", found.Steps)
+	}
+
+	found.Name = "Crepes"
+	found.Ingredients = []models.RecipeIngredient{{Name: "Flour", Quantity: 100, Unit: strPtr("g"), CategoryID: "test-cat"}}
+	found.Steps = nil
+	if err := repo.Replace(found); err != nil {
+		t.Fatalf("Failed to replace recipe: %v", err)
+	}
+	replaced, _ := repo.GetByID("recipe-1")
+	if replaced.Name != "Crepes" || len(replaced.Ingredients) != 1 || len(replaced.Steps) != 0 {
+		t.Errorf("Expected the recipe replaced, got %+v", replaced)
+	}
+
+	all, _ := repo.GetAll()
+	if len(all) != 1 {
+		t.Errorf("Expected 1 recipe, got %d", len(all))
+	}
+
+	if err := repo.Delete("recipe-1"); err != nil {
+		t.Fatalf("Failed to delete recipe: %v", err)
+	}
+	if _, err := repo.GetByID("recipe-1"); err != ErrRecipeNotFound {
+		t.Errorf("Expected ErrRecipeNotFound, got %v", err)
+	}
+	var ingredients int64
+	itemRepo.db.Model(&models.RecipeIngredient{}).Count(&ingredients)
+	if ingredients != 0 {
+		t.Errorf("Expected ingredients deleted with the recipe, got %d", ingredients)
+	}
+}
+
+func TestRecipeRepository_AddToList(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewRecipeRepository(itemRepo.db)
+	recipe := createTestRecipe(t, repo)
+
+	// Already on the list: flour in kg, milk in ml, and checked eggs
+	for _, item := range []*models.Item{
+		{ID: "item-1", ListID: "list-1", Name: "flour", Quantity: 1, Unit: strPtr("kg"), CategoryID: "test-cat"},
+		{ID: "item-2", ListID: "list-1", Name: "Milk", Quantity: 1, Unit: strPtr("can"), CategoryID: "test-cat"},
+		{ID: "item-3", ListID: "list-1", Name: "Eggs", Quantity: 6, CategoryID: "test-cat"},
+	} {
+		if err := itemRepo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	if _, err := itemRepo.ToggleChecked("item-3", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to check item: %v", err)
+	}
+
+	// Doubled: 500 g flour, 1 l milk, 4 eggs, salt
+	created, merged, err := repo.AddToList(recipe, "list-1", 8)
+	if err != nil {
+		t.Fatalf("Failed to add recipe: %v", err)
+	}
+
+	if len(merged) != 1 || merged[0].After.ID != "item-1" || merged[0].After.Quantity != 1500 || *merged[0].After.Unit != "g" {
+		t.Errorf("Expected flour merged into 1500 g, got %+v", merged)
+	}
+	if len(created) != 3 {
+		t.Fatalf("Expected milk, eggs and salt created, got %+v", created)
+	}
+	if created[0].Name != "Milk" || created[0].Quantity != 1 || *created[0].Unit != "l" {
+		t.Errorf("Expected 1 l milk beside the cans, got %+v", created[0])
+	}
+	if created[1].Name != "Eggs" || created[1].Quantity != 4 || created[1].Unit != nil {
+		t.Errorf("Expected 4 eggs beside the checked ones, got %+v", created[1])
+	}
+	if created[2].Name != "Salt" || created[2].Quantity != 1 {
+		t.Errorf("Expected at least 1 salt, got %+v", created[2])
+	}
+
+	if _, _, err := repo.AddToList(recipe, "missing", 4); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestRecipeListItems(t *testing.T) {
+	ingredients := []models.RecipeIngredient{
+		{Name: "Butter", Quantity: 0.75, Unit: strPtr("kg")},
+		{Name: "Sugar", Quantity: 1, Unit: strPtr("cup")},
+	}
+
+	items := RecipeListItems(ingredients, 4, 2)
+	if items[0].Quantity != 375 || *items[0].Unit != "g" {
+		t.Errorf("Expected 375 g butter, got %d %v", items[0].Quantity, *items[0].Unit)
+	}
+	if items[1].Quantity != 1 || *items[1].Unit != "cup" {
+		t.Errorf("Expected half a cup rounded up to 1 cup, got %d %v", items[1].Quantity, *items[1].Unit)
+	}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+type ReportRepository struct {
+	db *db.DB
+}
+
+func NewReportRepository(database *db.DB) *ReportRepository {
+	return &ReportRepository{db: database}
+}
+
+// spendingRow is a purchase as the spending report reads it
+type spendingRow struct {
+	Price         float64
+	Quantity      int
+	CheckedAt     int64
+	CategoryID    string
+	CategoryName  *string
+	Store         *string
+	CheckedBy     *string
+	CheckedByName *string
+}
+
+// Spending totals what was spent on priced items checked from one time to
+// another, inclusive, on lists the user can see, grouped by the given
+// dimensions. Months are calendar months in loc. Items count as they were
+// when checked off: unchecking, editing, trashing or purging them later
+// doesn't undo the spending. Purchases from lists since purged count for
+// whoever checked them.
+func (r *ReportRepository) Spending(userID string, from, to int64, groupBy []string, loc *time.Location) (*models.SpendingReport, error) {
+	var rows []spendingRow
+	err := r.db.Table("purchases p").
+		Select("p.price, p.quantity, p.checked_at, p.category_id, c.name AS category_name, p.store, p.checked_by, p.checked_by_name").
+		Joins("LEFT JOIN lists l ON l.id = p.list_id").
+		Joins("LEFT JOIN list_members m ON m.list_id = p.list_id AND m.user_id = ?", userID).
+		Joins("LEFT JOIN categories c ON c.id = p.category_id").
+		Where("p.checked_at >= ? AND p.checked_at <= ?", from, to).
+		Where("m.id IS NOT NULL OR (l.id IS NULL AND p.checked_by = ?)", userID).
+		Order("p.checked_at ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SpendingReport{From: from, To: to, GroupBy: groupBy, Groups: []models.SpendingGroup{}}
+	index := make(map[string]int)
+	for _, row := range rows {
+		amount := row.Price * float64(row.Quantity)
+		report.Total += amount
+		report.Items++
+
+		var group models.SpendingGroup
+		var key []string
+		for _, dimension := range groupBy {
+			switch dimension {
+			case models.SpendingByMonth:
+				group.Month = time.UnixMilli(row.CheckedAt).In(loc).Format("2006-01")
+				key = append(key, group.Month)
+			case models.SpendingByCategory:
+				group.CategoryID = row.CategoryID
+				if row.CategoryName != nil {
+					group.Category = *row.CategoryName
+				}
+				key = append(key, group.CategoryID)
+			case models.SpendingByStore:
+				if row.Store != nil {
+					group.Store = strings.TrimSpace(*row.Store)
+				}
+				key = append(key, strings.ToLower(group.Store))
+			case models.SpendingByUser:
+				if row.CheckedBy != nil {
+					group.UserID = *row.CheckedBy
+				}
+				if row.CheckedByName != nil {
+					group.UserName = *row.CheckedByName
+				}
+				key = append(key, group.UserID)
+			}
+		}
+
+		k := strings.Join(key, "\x00")
+		i, ok := index[k]
+		if !ok {
+			i = len(report.Groups)
+			index[k] = i
+			report.Groups = append(report.Groups, group)
+		}
+		// Names are shown as they were last written
+		total, items := report.Groups[i].Total, report.Groups[i].Items
+		report.Groups[i] = group
+		report.Groups[i].Total = total + amount
+		report.Groups[i].Items = items + 1
+	}
+
+	report.Total = roundCents(report.Total)
+	for i := range report.Groups {
+		report.Groups[i].Total = roundCents(report.Groups[i].Total)
+	}
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Month != report.Groups[j].Month {
+			return report.Groups[i].Month < report.Groups[j].Month
+		}
+		return report.Groups[i].Total > report.Groups[j].Total
+	})
+	return report, nil
+}
+
+// recordPurchase adds an item that userID just checked off to the purchases
+// spending is counted from, when it has a price
+func recordPurchase(tx *gorm.DB, item *models.Item, userID, userName string, now int64) error {
+	if item.Price == nil || *item.Price < 0 {
+		return nil
+	}
+	purchase := models.Purchase{
+		ID:            auth.GenerateID(),
+		ItemID:        &item.ID,
+		ListID:        item.ListID,
+		Name:          item.Name,
+		Quantity:      item.Quantity,
+		Unit:          item.Unit,
+		Price:         *item.Price,
+		CategoryID:    item.CategoryID,
+		Store:         item.Store,
+		CheckedBy:     &userID,
+		CheckedByName: &userName,
+		CheckedAt:     now,
+	}
+	return tx.Create(&purchase).Error
+}
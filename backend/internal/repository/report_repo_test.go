@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func TestReportRepository_Spending(t *testing.T) {
+	itemRepo, listRepo, catRepo, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewReportRepository(itemRepo.db)
+
+	createTestCategory(t, catRepo, "dairy", "Dairy")
+	createTestUser(t, userRepo, "user-2", "other", "Other User")
+	private := &models.List{ID: "list-private", Name: "Private", OwnerID: strPtr("user-2"), CreatedAt: 1000, UpdatedAt: 1000}
+	if err := listRepo.Create(private); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	march := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC).UnixMilli()
+	april := time.Date(2024, 4, 2, 12, 0, 0, 0, time.UTC).UnixMilli()
+	buy := func(id, listID, name string, quantity int, price *float64, categoryID, store string, checkedAt int64) {
+		item := &models.Item{ID: id, ListID: listID, Name: name, Quantity: quantity, Price: price, CategoryID: categoryID, Store: nilIfEmpty(store)}
+		if err := itemRepo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+		if _, err := itemRepo.ToggleChecked(id, "user-1", "Test User", nil); err != nil {
+			t.Fatalf("Failed to check item: %v", err)
+		}
+		itemRepo.db.Model(&models.Purchase{}).Where("item_id = ?", id).UpdateColumn("checked_at", checkedAt)
+	}
+	buy("item-1", "list-1", "Milk", 2, floatPtr(1.25), "dairy", "Aldi", march)
+	buy("item-2", "list-1", "Bread", 1, floatPtr(3), "test-cat", "aldi", march)
+	buy("item-3", "list-1", "Cheese", 1, floatPtr(6.5), "dairy", "Costco", april)
+	buy("item-4", "list-1", "Salt", 1, nil, "test-cat", "", april)
+	buy("item-5", "list-private", "Caviar", 1, floatPtr(90), "test-cat", "", april)
+
+	// Trashed items still count; unchecked ones don't
+	if err := itemRepo.Delete("item-2"); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	unchecked := &models.Item{ID: "item-6", ListID: "list-1", Name: "Eggs", Quantity: 1, Price: floatPtr(4), CategoryID: "dairy"}
+	if err := itemRepo.Create(unchecked); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	to := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+	report, err := repo.Spending("user-1", from, to, []string{models.SpendingByMonth, models.SpendingByCategory}, time.UTC)
+	if err != nil {
+		t.Fatalf("Failed to get report: %v", err)
+	}
+	if report.Total != 12 || report.Items != 3 {
+		t.Errorf("Expected 12 over 3 items, got %v over %d", report.Total, report.Items)
+	}
+	want := []models.SpendingGroup{
+		{Month: "2024-03", CategoryID: "test-cat", Category: "Test Category", Total: 3, Items: 1},
+		{Month: "2024-03", CategoryID: "dairy", Category: "Dairy", Total: 2.5, Items: 1},
+		{Month: "2024-04", CategoryID: "dairy", Category: "Dairy", Total: 6.5, Items: 1},
+	}
+	if len(report.Groups) != len(want) {
+		t.Fatalf("Expected %d groups, got %+v", len(want), report.Groups)
+	}
+	for i := range want {
+		if report.Groups[i] != want[i] {
+			t.Errorf("Group %d: expected %+v, got %+v", i, want[i], report.Groups[i])
+		}
+	}
+
+	byStore, _ := repo.Spending("user-1", from, to, []string{models.SpendingByStore, models.SpendingByUser}, time.UTC)
+	if len(byStore.Groups) != 2 {
+		t.Fatalf("Expected stores to group ignoring case, got %+v", byStore.Groups)
+	}
+	if g := byStore.Groups[0]; g.Store != "Costco" || g.Total != 6.5 || g.UserID != "user-1" || g.UserName != "Test User" {
+		t.Errorf("Expected Costco first, got %+v", g)
+	}
+	if g := byStore.Groups[1]; g.Store != "aldi" || g.Total != 5.5 || g.Items != 2 {
+		t.Errorf("Expected Aldi as last written, got %+v", g)
+	}
+
+	// The other user only sees their own list
+	theirs, _ := repo.Spending("user-2", from, to, []string{models.SpendingByMonth}, time.UTC)
+	if theirs.Total != 90 {
+		t.Errorf("Expected 90 for the other user, got %v", theirs.Total)
+	}
+
+	// What was bought stays bought when the items are reused, repriced or purged
+	if _, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to uncheck item: %v", err)
+	}
+	cheese, _ := itemRepo.GetByID("item-3")
+	cheese.Price = floatPtr(99)
+	if err := itemRepo.Update(cheese); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	if _, err := itemRepo.PurgeDeletedBefore(time.Now().Add(time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("Failed to purge items: %v", err)
+	}
+	later, _ := repo.Spending("user-1", from, to, []string{models.SpendingByMonth}, time.UTC)
+	if later.Total != 12 || later.Items != 3 {
+		t.Errorf("Expected still 12 over 3 items, got %v over %d", later.Total, later.Items)
+	}
+
+	// Once a list is purged its purchases count for whoever checked them
+	if err := listRepo.Delete("list-private"); err != nil {
+		t.Fatalf("Failed to delete list: %v", err)
+	}
+	if _, err := listRepo.PurgeDeletedBefore(time.Now().Add(time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("Failed to purge lists: %v", err)
+	}
+	mine, _ := repo.Spending("user-1", from, to, []string{models.SpendingByMonth}, time.UTC)
+	theirs, _ = repo.Spending("user-2", from, to, []string{models.SpendingByMonth}, time.UTC)
+	if mine.Total != 102 || theirs.Total != 0 {
+		t.Errorf("Expected 102 for the checker and 0 for the owner, got %v and %v", mine.Total, theirs.Total)
+	}
+}
+
+func TestItemRepository_UndoCheckRemovesPurchase(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, Price: floatPtr(2), CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	before, _ := itemRepo.GetByID("item-1")
+	if _, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to check item: %v", err)
+	}
+
+	var count int64
+	itemRepo.db.Model(&models.Purchase{}).Where("item_id = ?", "item-1").Count(&count)
+	if count != 1 {
+		t.Fatalf("Expected a purchase recorded, got %d", count)
+	}
+
+	if _, err := itemRepo.RestoreSnapshot(before); err != nil {
+		t.Fatalf("Failed to restore item: %v", err)
+	}
+	itemRepo.db.Model(&models.Purchase{}).Where("item_id = ?", "item-1").Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the purchase taken back, got %d", count)
+	}
+}
+
+func TestItemRepository_ToggleCheckedRecordsTime(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	checked, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil)
+	if err != nil {
+		t.Fatalf("Failed to check item: %v", err)
+	}
+	stored, _ := itemRepo.GetByID("item-1")
+	if checked.CheckedAt == nil || stored.CheckedAt == nil || *stored.CheckedAt != *checked.CheckedAt {
+		t.Errorf("Expected the checked time recorded, got %v and %v", checked.CheckedAt, stored.CheckedAt)
+	}
+
+	if _, err := itemRepo.ToggleChecked("item-1", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to uncheck item: %v", err)
+	}
+	stored, _ = itemRepo.GetByID("item-1")
+	if stored.CheckedAt != nil {
+		t.Errorf("Expected the checked time cleared, got %v", *stored.CheckedAt)
+	}
+}
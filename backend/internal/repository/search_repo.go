@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+// Markers FTS5 puts around matches, replaced with <mark> once the text has
+// been escaped
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+type SearchRepository struct {
+	db *db.DB
+}
+
+func NewSearchRepository(database *db.DB) *SearchRepository {
+	return &SearchRepository{db: database}
+}
+
+// Search finds lists and items by name across the lists the user can see and
+// groups the results by list, most recently updated first. Checked items,
+// trashed lists and trashed items are left out unless opts asks for them.
+// A list matches when its name matches or it has a matching item.
+func (r *SearchRepository) Search(userID, query string, opts models.SearchOptions) (*models.SearchPage, error) {
+	page := &models.SearchPage{Groups: []models.SearchGroup{}}
+	match := ftsPrefixQuery(query)
+	if match == "" {
+		return page, nil
+	}
+
+	itemConditions := ""
+	if !opts.IncludeChecked {
+		itemConditions += " AND i.checked = 0"
+	}
+	listConditions := ""
+	if !opts.IncludeTrashed {
+		itemConditions += " AND i.deleted_at IS NULL"
+		listConditions += " AND l.deleted_at IS NULL"
+	}
+
+	// Fetch one extra list to know whether there is another page
+	var hits []struct {
+		ID   string
+		Role models.ListRole
+	}
+	err := r.db.Raw(`
+		SELECT l.id, m.role AS role
+		FROM lists l
+		LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = ?
+		WHERE m.id IS NOT NULL`+listConditions+`
+			AND (l.id IN (SELECT id FROM lists_fts WHERE lists_fts MATCH ?)
+				OR l.id IN (
+					SELECT i.list_id FROM items_fts
+					JOIN items i ON i.id = items_fts.id
+					WHERE items_fts MATCH ?`+itemConditions+`))
+		ORDER BY l.updated_at DESC, l.id
+		LIMIT ? OFFSET ?`,
+		userID, match, match, opts.Limit+1, opts.Offset).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+		page.NextOffset = opts.Offset + opts.Limit
+	}
+	if len(hits) == 0 {
+		return page, nil
+	}
+
+	listIDs := make([]string, len(hits))
+	for i, hit := range hits {
+		listIDs[i] = hit.ID
+	}
+
+	var lists []models.List
+	if err := r.db.Where("id IN ?", listIDs).Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	listsByID := make(map[string]models.List, len(lists))
+	for _, list := range lists {
+		listsByID[list.ID] = list
+	}
+
+	var listHighlights []struct {
+		ID        string
+		Highlight string
+	}
+	err = r.db.Raw(`
+		SELECT l.id, highlight(lists_fts, 1, ?, ?) AS highlight
+		FROM lists_fts
+		JOIN lists l ON l.id = lists_fts.id
+		WHERE lists_fts MATCH ? AND l.id IN ?`,
+		matchStart, matchEnd, match, listIDs).
+		Scan(&listHighlights).Error
+	if err != nil {
+		return nil, err
+	}
+	highlightsByID := make(map[string]string, len(listHighlights))
+	for _, h := range listHighlights {
+		highlightsByID[h.ID] = h.Highlight
+	}
+
+	var items []models.SearchItem
+	err = r.db.Raw(`
+		SELECT i.*, highlight(items_fts, 1, ?, ?) AS highlight
+		FROM items_fts
+		JOIN items i ON i.id = items_fts.id
+		WHERE items_fts MATCH ? AND i.list_id IN ?`+itemConditions+`
+		ORDER BY i.sort_order ASC`,
+		matchStart, matchEnd, match, listIDs).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	itemsByList := make(map[string][]models.SearchItem)
+	for _, item := range items {
+		item.Highlight = markMatches(item.Highlight)
+		itemsByList[item.ListID] = append(itemsByList[item.ListID], item)
+	}
+
+	for _, hit := range hits {
+		list := listsByID[hit.ID]
+		group := models.SearchGroup{
+			List:      list,
+			Role:      hit.Role,
+			Highlight: html.EscapeString(list.Name),
+			Items:     itemsByList[hit.ID],
+		}
+		if highlight, ok := highlightsByID[hit.ID]; ok {
+			group.Matched = true
+			group.Highlight = markMatches(highlight)
+		}
+		if group.Items == nil {
+			group.Items = []models.SearchItem{}
+		}
+		page.Groups = append(page.Groups, group)
+	}
+
+	return page, nil
+}
+
+// markMatches escapes text for HTML and turns the FTS5 match markers into
+// <mark> tags
+func markMatches(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, matchStart, "<mark>")
+	return strings.ReplaceAll(text, matchEnd, "</mark>")
+}
+
+// ftsPrefixQuery turns user input into an FTS5 query matching every word as
+// a prefix, so "oat mi" finds "Oat milk". Punctuation is dropped rather than
+// passed to FTS5 as syntax. It returns "" when nothing is left to search for.
+func ftsPrefixQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func TestSearchRepository_Search(t *testing.T) {
+	itemRepo, listRepo, _, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewSearchRepository(itemRepo.db)
+
+	createTestUser(t, userRepo, "user-2", "other", "Other User")
+	owner := "user-2"
+	if err := listRepo.Create(&models.List{ID: "private", Name: "Milk run", OwnerID: &owner, CreatedAt: 1000, UpdatedAt: 1000}); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	createTestList(t, listRepo, "list-2", "Dairy & milk", "user-1")
+	createTestList(t, listRepo, "list-3", "Old", "user-1")
+
+	for _, item := range []*models.Item{
+		{ID: "item-1", ListID: "list-1", Name: "Oat milk", SortOrder: 2},
+		{ID: "item-2", ListID: "list-1", Name: "Milk <b>chocolate</b>", SortOrder: 1},
+		{ID: "item-3", ListID: "list-1", Name: "Bread"},
+		{ID: "item-4", ListID: "list-1", Name: "Whole milk", Checked: true},
+		{ID: "item-5", ListID: "list-3", Name: "Buttermilk"},
+		{ID: "item-6", ListID: "list-3", Name: "Milkshake"},
+		{ID: "item-7", ListID: "private", Name: "Milk"},
+	} {
+		item.Quantity = 1
+		item.CategoryID = "test-cat"
+		if err := itemRepo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	itemRepo.db.Exec("UPDATE items SET checked = 1 WHERE id = ?", "item-4")
+	itemRepo.db.Exec("UPDATE lists SET updated_at = 3000 WHERE id = ?", "list-1")
+	itemRepo.db.Exec("UPDATE lists SET updated_at = 2000 WHERE id = ?", "list-2")
+	itemRepo.db.Exec("UPDATE lists SET deleted_at = 5000 WHERE id = ?", "list-3")
+
+	page, err := repo.Search("user-1", "milk", models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(page.Groups) != 2 || page.NextOffset != 0 {
+		t.Fatalf("Expected list-1 and list-2, got %+v", page)
+	}
+
+	group := page.Groups[0]
+	if group.List.ID != "list-1" || group.Matched || group.Role != models.ListRoleOwner {
+		t.Errorf("Expected list-1 matched through its items, got %+v", group)
+	}
+	if len(group.Items) != 2 || group.Items[0].ID != "item-2" || group.Items[1].ID != "item-1" {
+		t.Fatalf("Expected unchecked matches in list order, got %+v", group.Items)
+	}
+	if group.Items[0].Highlight != "<mark>Milk</mark> &lt;b&gt;chocolate&lt;/b&gt;" {
+		t.Errorf("Expected escaped highlight, got %q", group.Items[0].Highlight)
+	}
+
+	group = page.Groups[1]
+	if group.List.ID != "list-2" || !group.Matched || group.Highlight != "Dairy &amp; <mark>milk</mark>" || len(group.Items) != 0 {
+		t.Errorf("Expected list-2 matched by name, got %+v", group)
+	}
+
+	// Checked items and the trash when asked for
+	page, _ = repo.Search("user-1", "milk", models.SearchOptions{Limit: 10, IncludeChecked: true, IncludeTrashed: true})
+	if len(page.Groups) != 3 || len(page.Groups[0].Items) != 3 || page.Groups[2].List.ID != "list-3" {
+		t.Fatalf("Expected checked and trashed results, got %+v", page)
+	}
+	// "milk" is a prefix of "Milkshake" but not of "Buttermilk"
+	if items := page.Groups[2].Items; len(items) != 1 || items[0].ID != "item-6" {
+		t.Errorf("Expected only Milkshake in the trashed list, got %+v", items)
+	}
+
+	// Paging by list
+	page, _ = repo.Search("user-1", "milk", models.SearchOptions{Limit: 1})
+	if len(page.Groups) != 1 || page.Groups[0].List.ID != "list-1" || page.NextOffset != 1 {
+		t.Fatalf("Expected first page with a next offset, got %+v", page)
+	}
+	page, _ = repo.Search("user-1", "milk", models.SearchOptions{Limit: 1, Offset: 1})
+	if len(page.Groups) != 1 || page.Groups[0].List.ID != "list-2" || page.NextOffset != 0 {
+		t.Errorf("Expected last page, got %+v", page)
+	}
+
+	// Renamed lists are indexed
+	if err := listRepo.db.Model(&models.List{}).Where("id = ?", "list-2").Update("name", "Cheese").Error; err != nil {
+		t.Fatalf("Failed to rename list: %v", err)
+	}
+	page, _ = repo.Search("user-1", "chee", models.SearchOptions{Limit: 10})
+	if len(page.Groups) != 1 || page.Groups[0].List.ID != "list-2" {
+		t.Errorf("Expected the renamed list, got %+v", page)
+	}
+}
+
+func TestSearchRepository_SurvivesVacuum(t *testing.T) {
+	itemRepo, _, _, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+	repo := NewSearchRepository(itemRepo.db)
+
+	for _, item := range []*models.Item{
+		{ID: "item-1", ListID: "list-1", Name: "Bread"},
+		{ID: "item-2", ListID: "list-1", Name: "Butter"},
+		{ID: "item-3", ListID: "list-1", Name: "Milk"},
+	} {
+		item.Quantity = 1
+		item.CategoryID = "test-cat"
+		if err := itemRepo.Create(item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	// Purging leaves a gap VACUUM may close by renumbering rowids
+	if err := itemRepo.db.Exec("DELETE FROM items WHERE id IN ?", []string{"item-1", "item-2"}).Error; err != nil {
+		t.Fatalf("Failed to purge items: %v", err)
+	}
+	if err := itemRepo.db.Exec("VACUUM").Error; err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if err := itemRepo.db.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate again: %v", err)
+	}
+
+	page, err := repo.Search("user-1", "milk", models.SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(page.Groups) != 1 || len(page.Groups[0].Items) != 1 || page.Groups[0].Items[0].ID != "item-3" {
+		t.Fatalf("Expected Milk to be found after vacuum, got %+v", page)
+	}
+
+	page, _ = repo.Search("user-1", "butter", models.SearchOptions{Limit: 10})
+	if len(page.Groups) != 0 {
+		t.Errorf("Expected purged items to be gone from the index, got %+v", page)
+	}
+}
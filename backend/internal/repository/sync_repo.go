@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+var (
+	ErrSyncOperationNotFound = errors.New("sync operation not found")
+	// ErrAlreadyExists means a client-chosen ID is taken by an entity the
+	// user can see
+	ErrAlreadyExists = errors.New("entity already exists")
+	// ErrIDConflict means a client-chosen ID is taken by an entity the user
+	// cannot see, which must not be given away
+	ErrIDConflict = errors.New("entity ID is taken")
+)
+
+// TxRepositories groups repositories that share a single database transaction
+type TxRepositories struct {
+	Lists      *ListRepository
+	Members    *ListMemberRepository
+	Items      *ItemRepository
+	Categories *CategoryRepository
+	Pantry     *PantryRepository
+	Activities *ActivityRepository
+	Sync       *SyncRepository
+}
+
+type SyncRepository struct {
+	db *db.DB
+}
+
+func NewSyncRepository(database *db.DB) *SyncRepository {
+	return &SyncRepository{db: database}
+}
+
+// Transaction runs fn with repositories bound to one transaction. The
+// transaction is rolled back if fn returns an error.
+func (r *SyncRepository) Transaction(fn func(tx *TxRepositories) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		return fn(&TxRepositories{
+			Lists:      NewListRepository(txDB),
+			Members:    NewListMemberRepository(txDB),
+			Items:      NewItemRepository(txDB),
+			Categories: NewCategoryRepository(txDB),
+			Pantry:     NewPantryRepository(txDB),
+			Activities: NewActivityRepository(txDB),
+			Sync:       NewSyncRepository(txDB),
+		})
+	})
+}
+
+func (r *SyncRepository) CreateOperation(op *models.SyncOperation) error {
+	return r.db.Create(op).Error
+}
+
+func (r *SyncRepository) GetOperation(userID, clientOpID string) (*models.SyncOperation, error) {
+	var op models.SyncOperation
+	err := r.db.First(&op, "user_id = ? AND client_op_id = ?", userID, clientOpID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSyncOperationNotFound
+		}
+		return nil, err
+	}
+	return &op, nil
+}
+
+// CheckNewListID reports whether id is free for a list userID creates
+// offline. Trashed lists still hold their ID.
+func (r *SyncRepository) CheckNewListID(id, userID string) error {
+	var listIDs []string
+	if err := r.db.Model(&models.List{}).Where("id = ?", id).Pluck("id", &listIDs).Error; err != nil {
+		return err
+	}
+	return r.idTakenIn(listIDs, userID)
+}
+
+// CheckNewItemID reports whether id is free for an item userID creates
+// offline. Trashed items still hold their ID.
+func (r *SyncRepository) CheckNewItemID(id, userID string) error {
+	var listIDs []string
+	if err := r.db.Model(&models.Item{}).Where("id = ?", id).Pluck("list_id", &listIDs).Error; err != nil {
+		return err
+	}
+	return r.idTakenIn(listIDs, userID)
+}
+
+// idTakenIn returns nil when listIDs is empty, that is nothing holds the ID,
+// and otherwise tells apart whether userID can see the list that does
+func (r *SyncRepository) idTakenIn(listIDs []string, userID string) error {
+	if len(listIDs) == 0 {
+		return nil
+	}
+	var members int64
+	if err := r.db.Model(&models.ListMember{}).
+		Where("list_id = ? AND user_id = ?", listIDs[0], userID).
+		Count(&members).Error; err != nil {
+		return err
+	}
+	if members > 0 {
+		return ErrAlreadyExists
+	}
+	return ErrIDConflict
+}
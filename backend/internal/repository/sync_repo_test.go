@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func TestSyncRepository_Operations(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSyncRepository(database)
+	createTestUser(t, NewUserRepository(database), "user-1", "alice", "Alice")
+
+	_, err := repo.GetOperation("user-1", "op-1")
+	if err != ErrSyncOperationNotFound {
+		t.Errorf("Expected ErrSyncOperationNotFound, got %v", err)
+	}
+
+	op := &models.SyncOperation{ID: "sync-1", UserID: "user-1", ClientOpID: "op-1", Result: `{"status":"applied"}`, CreatedAt: 1000}
+	if err := repo.CreateOperation(op); err != nil {
+		t.Fatalf("Failed to record operation: %v", err)
+	}
+
+	found, err := repo.GetOperation("user-1", "op-1")
+	if err != nil {
+		t.Fatalf("Failed to get operation: %v", err)
+	}
+	if found.Result != op.Result {
+		t.Errorf("Expected result %s, got %s", op.Result, found.Result)
+	}
+
+	// Client op IDs are scoped per user
+	_, err = repo.GetOperation("user-2", "op-1")
+	if err != ErrSyncOperationNotFound {
+		t.Errorf("Expected ErrSyncOperationNotFound for other user, got %v", err)
+	}
+}
+
+func TestSyncRepository_TransactionRollsBack(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSyncRepository(database)
+	listRepo := NewListRepository(database)
+
+	errAbort := errors.New("abort")
+	err := repo.Transaction(func(tx *TxRepositories) error {
+		list := &models.List{ID: "list-1", Name: "Offline", CreatedAt: 1000, UpdatedAt: 1000}
+		if err := tx.Lists.Create(list); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Expected errAbort, got %v", err)
+	}
+
+	if _, err := listRepo.GetByID("list-1"); err != ErrListNotFound {
+		t.Errorf("Expected list to be rolled back, got %v", err)
+	}
+
+	err = repo.Transaction(func(tx *TxRepositories) error {
+		list := &models.List{ID: "list-2", Name: "Committed", CreatedAt: 1000, UpdatedAt: 1000}
+		return tx.Lists.Create(list)
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if _, err := listRepo.GetByID("list-2"); err != nil {
+		t.Errorf("Expected committed list, got %v", err)
+	}
+}
+
+func TestSyncRepository_CheckNewIDCountsTrash(t *testing.T) {
+	itemRepo, listRepo, _, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	repo := NewSyncRepository(itemRepo.db)
+	createTestUser(t, userRepo, "user-2", "bob", "Bob")
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if err := itemRepo.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to trash item: %v", err)
+	}
+	if err := listRepo.Delete("list-1"); err != nil {
+		t.Fatalf("Failed to trash list: %v", err)
+	}
+
+	// A queued batch replaying creates with the trashed IDs
+	err := repo.Transaction(func(tx *TxRepositories) error {
+		if err := tx.Sync.CheckNewListID("list-1", "user-1"); err != ErrAlreadyExists {
+			t.Errorf("Expected ErrAlreadyExists for the owner's trashed list, got %v", err)
+		}
+		if err := tx.Sync.CheckNewItemID("item-1", "user-1"); err != ErrAlreadyExists {
+			t.Errorf("Expected ErrAlreadyExists for the owner's trashed item, got %v", err)
+		}
+		if err := tx.Sync.CheckNewListID("list-1", "user-2"); err != ErrIDConflict {
+			t.Errorf("Expected ErrIDConflict for someone else's list, got %v", err)
+		}
+		if err := tx.Sync.CheckNewItemID("item-1", "user-2"); err != ErrIDConflict {
+			t.Errorf("Expected ErrIDConflict for someone else's item, got %v", err)
+		}
+		if err := tx.Sync.CheckNewItemID("item-2", "user-2"); err != nil {
+			t.Errorf("Expected an unused ID to be free, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
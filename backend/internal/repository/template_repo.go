@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+type TemplateRepository struct {
+	db *db.DB
+}
+
+func NewTemplateRepository(database *db.DB) *TemplateRepository {
+	return &TemplateRepository{db: database}
+}
+
+// CreateFromList saves a template holding a copy of the list's items that
+// are not in the trash
+func (r *TemplateRepository) CreateFromList(template *models.Template, listID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var items []models.Item
+		if err := tx.Where("list_id = ? AND deleted_at IS NULL", listID).Order("sort_order ASC").Find(&items).Error; err != nil {
+			return err
+		}
+
+		template.Items = make([]models.TemplateItem, len(items))
+		for i, item := range items {
+			template.Items[i] = models.TemplateItem{
+				ID:          auth.GenerateID(),
+				TemplateID:  template.ID,
+				Name:        item.Name,
+				Quantity:    item.Quantity,
+				Unit:        item.Unit,
+				CategoryID:  item.CategoryID,
+				Price:       item.Price,
+				PackageSize: item.PackageSize,
+				PackageUnit: item.PackageUnit,
+				Store:       item.Store,
+				SortOrder:   i,
+			}
+		}
+
+		return tx.Create(template).Error
+	})
+}
+
+func (r *TemplateRepository) withItems() *gorm.DB {
+	return r.db.Preload("Items", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("sort_order ASC")
+	})
+}
+
+// GetAllForUser returns the user's templates sorted by name
+func (r *TemplateRepository) GetAllForUser(userID string) ([]models.Template, error) {
+	var templates []models.Template
+	err := r.withItems().Where("owner_id = ?", userID).Order("name ASC").Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *TemplateRepository) GetByID(id string) (*models.Template, error) {
+	var template models.Template
+	err := r.withItems().First(&template, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *TemplateRepository) Rename(id, name string, updatedAt int64) error {
+	result := r.db.Model(&models.Template{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "updated_at": updatedAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// SetSchedule stores a template's weekly schedule. A nil weekday removes it.
+func (r *TemplateRepository) SetSchedule(template *models.Template, updatedAt int64) error {
+	result := r.db.Model(&models.Template{}).Where("id = ?", template.ID).
+		Updates(map[string]interface{}{
+			"schedule_weekday": template.ScheduleWeekday,
+			"schedule_hour":    template.ScheduleHour,
+			"schedule_list_id": template.ScheduleListID,
+			"next_run_at":      template.NextRunAt,
+			"updated_at":       updatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+func (r *TemplateRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", id).Delete(&models.TemplateItem{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Template{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTemplateNotFound
+		}
+		return nil
+	})
+}
+
+// GetDue returns scheduled templates whose next run is at or before now
+func (r *TemplateRepository) GetDue(now int64) ([]models.Template, error) {
+	var templates []models.Template
+	err := r.withItems().
+		Where("schedule_weekday IS NOT NULL AND next_run_at IS NOT NULL AND next_run_at <= ?", now).
+		Order("next_run_at ASC").
+		Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// MarkRun records that a scheduled template ran and when it runs next
+func (r *TemplateRepository) MarkRun(id string, ranAt int64, nextRunAt *int64) error {
+	return r.db.Model(&models.Template{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_run_at": ranAt, "next_run_at": nextRunAt}).Error
+}
+
+// InstantiateNew creates list and fills it with the template's items in one
+// transaction. The items go in as the template lists them; there is nothing
+// to merge into yet.
+func (r *TemplateRepository) InstantiateNew(template *models.Template, list *models.List) ([]models.Item, error) {
+	created := templateListItems(template)
+	for i := range created {
+		created[i].ID = auth.GenerateID()
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		if err := NewListRepository(txDB).Create(list); err != nil {
+			return err
+		}
+		return NewItemRepository(txDB).CreateMany(list.ID, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// InstantiateInto merges the template's items into an existing list in one
+// transaction, adding quantities to matching items, and bumps the list
+// version once
+func (r *TemplateRepository) InstantiateInto(template *models.Template, listID string) (created []models.Item, merged []MergedItem, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		txDB := &db.DB{DB: tx}
+		lists := NewListRepository(txDB)
+		if _, err := lists.GetByID(listID); err != nil {
+			return err
+		}
+
+		created, merged, err = NewItemRepository(txDB).MergeIntoList(listID, templateListItems(template))
+		if err != nil {
+			return err
+		}
+		return lists.TouchUpdatedAt(listID, auth.GetCurrentTimestamp())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, merged, nil
+}
+
+// templateListItems converts a template's items into list items
+func templateListItems(template *models.Template) []models.Item {
+	items := make([]models.Item, len(template.Items))
+	for i, item := range template.Items {
+		items[i] = models.Item{
+			Name:        item.Name,
+			Quantity:    item.Quantity,
+			Unit:        item.Unit,
+			CategoryID:  item.CategoryID,
+			Price:       item.Price,
+			PackageSize: item.PackageSize,
+			PackageUnit: item.PackageUnit,
+			Store:       item.Store,
+		}
+	}
+	return items
+}
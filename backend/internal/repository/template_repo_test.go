@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func setupTemplateTestDB(t *testing.T) (*TemplateRepository, *ItemRepository, *ListRepository, func()) {
+	itemRepo, listRepo, _, _, cleanup := setupItemTestDB(t)
+	templateRepo := NewTemplateRepository(itemRepo.db)
+
+	unit := "kg"
+	litre := "l"
+	for i, item := range []models.Item{
+		{ID: "item-1", Name: "Milk", Quantity: 2, PackageSize: floatPtr(1), PackageUnit: &litre},
+		{ID: "item-2", Name: "Chicken", Quantity: 1, Unit: &unit},
+	} {
+		item.ListID = "list-1"
+		item.CategoryID = "test-cat"
+		item.SortOrder = i
+		if err := itemRepo.Create(&item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	return templateRepo, itemRepo, listRepo, cleanup
+}
+
+func TestTemplateRepository_CreateFromList(t *testing.T) {
+	templateRepo, itemRepo, _, cleanup := setupTemplateTestDB(t)
+	defer cleanup()
+
+	// Trashed items are left out of the template
+	if err := itemRepo.Delete("item-2"); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	template := &models.Template{ID: "tpl-1", Name: "Weekly shop", OwnerID: "user-1", CreatedAt: 1000, UpdatedAt: 1000}
+	if err := templateRepo.CreateFromList(template, "list-1"); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	found, err := templateRepo.GetByID("tpl-1")
+	if err != nil {
+		t.Fatalf("Failed to get template: %v", err)
+	}
+	if len(found.Items) != 1 || found.Items[0].Name != "Milk" || found.Items[0].Quantity != 2 {
+		t.Errorf("Expected template with Milk x2, got %+v", found.Items)
+	}
+
+	templates, err := templateRepo.GetAllForUser("user-2")
+	if err != nil {
+		t.Fatalf("Failed to get templates: %v", err)
+	}
+	if len(templates) != 0 {
+		t.Errorf("Expected no templates for another user, got %d", len(templates))
+	}
+}
+
+func TestTemplateRepository_InstantiateNew(t *testing.T) {
+	templateRepo, itemRepo, _, cleanup := setupTemplateTestDB(t)
+	defer cleanup()
+
+	template := &models.Template{ID: "tpl-1", Name: "Weekly shop", OwnerID: "user-1", CreatedAt: 1000, UpdatedAt: 1000}
+	if err := templateRepo.CreateFromList(template, "list-1"); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	owner := "user-1"
+	list := &models.List{ID: "list-2", Name: "Weekly shop", OwnerID: &owner, CreatedAt: 2000, UpdatedAt: 2000}
+	created, err := templateRepo.InstantiateNew(template, list)
+	if err != nil {
+		t.Fatalf("Failed to instantiate template: %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("Expected 2 created items, got %d", len(created))
+	}
+
+	items, err := itemRepo.GetByListID("list-2")
+	if err != nil {
+		t.Fatalf("Failed to get items: %v", err)
+	}
+	if len(items) != 2 || items[0].Name != "Milk" || items[1].Name != "Chicken" {
+		t.Errorf("Expected Milk then Chicken, got %+v", items)
+	}
+	if items[1].Unit == nil || *items[1].Unit != "kg" {
+		t.Error("Expected unit to be copied")
+	}
+	if items[0].PackageSize == nil || *items[0].PackageSize != 1 || items[0].PackageUnit == nil || *items[0].PackageUnit != "l" {
+		t.Error("Expected package size to be copied")
+	}
+}
+
+func TestTemplateRepository_InstantiateNewKeepsRepeatedItems(t *testing.T) {
+	templateRepo, itemRepo, _, cleanup := setupTemplateTestDB(t)
+	defer cleanup()
+
+	template := &models.Template{ID: "tpl-1", Name: "Weekly shop", OwnerID: "user-1", CreatedAt: 1000, UpdatedAt: 1000}
+	if err := templateRepo.CreateFromList(template, "list-1"); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	template.Items = append(template.Items, models.TemplateItem{Name: "Milk", Quantity: 1, CategoryID: "test-cat"})
+
+	owner := "user-1"
+	list := &models.List{ID: "list-2", Name: "Weekly shop", OwnerID: &owner, CreatedAt: 2000, UpdatedAt: 2000}
+	if _, err := templateRepo.InstantiateNew(template, list); err != nil {
+		t.Fatalf("Failed to instantiate template: %v", err)
+	}
+
+	items, _ := itemRepo.GetByListID("list-2")
+	if len(items) != 3 {
+		t.Fatalf("Expected each template line as its own item, got %+v", items)
+	}
+	if items[0].Quantity != 2 || items[2].Name != "Milk" || items[2].Quantity != 1 {
+		t.Errorf("Expected repeated Milk lines to stay apart, got %+v", items)
+	}
+}
+
+func TestTemplateRepository_InstantiateIntoMergesQuantities(t *testing.T) {
+	templateRepo, itemRepo, listRepo, cleanup := setupTemplateTestDB(t)
+	defer cleanup()
+
+	template := &models.Template{ID: "tpl-1", Name: "Weekly shop", OwnerID: "user-1", CreatedAt: 1000, UpdatedAt: 1000}
+	if err := templateRepo.CreateFromList(template, "list-1"); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	// Checked items are not merged into
+	if _, err := itemRepo.ToggleChecked("item-2", "user-1", "Test User", nil); err != nil {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+
+	before, _ := listRepo.GetByID("list-1")
+
+	created, merged, err := templateRepo.InstantiateInto(template, "list-1")
+	if err != nil {
+		t.Fatalf("Failed to instantiate template: %v", err)
+	}
+	if len(merged) != 1 || merged[0].After.ID != "item-1" || merged[0].After.Quantity != 4 || merged[0].Before.Quantity != 2 {
+		t.Errorf("Expected Milk merged to quantity 4, got %+v", merged)
+	}
+	if len(created) != 1 || created[0].Name != "Chicken" {
+		t.Errorf("Expected a new Chicken item, got %+v", created)
+	}
+
+	after, _ := listRepo.GetByID("list-1")
+	if after.Version != before.Version+1 {
+		t.Errorf("Expected list version to bump once, got %d -> %d", before.Version, after.Version)
+	}
+
+	if _, _, err := templateRepo.InstantiateInto(template, "missing"); err != ErrListNotFound {
+		t.Errorf("Expected ErrListNotFound, got %v", err)
+	}
+}
+
+func TestTemplateRepository_Schedule(t *testing.T) {
+	templateRepo, _, _, cleanup := setupTemplateTestDB(t)
+	defer cleanup()
+
+	template := &models.Template{ID: "tpl-1", Name: "Weekly shop", OwnerID: "user-1", CreatedAt: 1000, UpdatedAt: 1000}
+	if err := templateRepo.CreateFromList(template, "list-1"); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	// Wednesday 2024-01-03 10:00 UTC; Saturday 09:00 is three days later
+	now := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	saturday := int(time.Saturday)
+	template.ScheduleWeekday = &saturday
+	template.ScheduleHour = 9
+	template.NextRunAt = template.NextRunAfter(now)
+
+	want := time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC).UnixMilli()
+	if template.NextRunAt == nil || *template.NextRunAt != want {
+		t.Fatalf("Expected next run %d, got %v", want, template.NextRunAt)
+	}
+
+	if err := templateRepo.SetSchedule(template, 2000); err != nil {
+		t.Fatalf("Failed to set schedule: %v", err)
+	}
+
+	due, err := templateRepo.GetDue(now.UnixMilli())
+	if err != nil {
+		t.Fatalf("Failed to get due templates: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("Expected nothing due yet, got %d", len(due))
+	}
+
+	due, _ = templateRepo.GetDue(want)
+	if len(due) != 1 || len(due[0].Items) != 2 {
+		t.Fatalf("Expected the template with its items to be due, got %+v", due)
+	}
+
+	// Running at the scheduled time moves the next run a week ahead
+	ranAt := time.UnixMilli(want).UTC()
+	if err := templateRepo.MarkRun("tpl-1", want, template.NextRunAfter(ranAt)); err != nil {
+		t.Fatalf("Failed to mark run: %v", err)
+	}
+	found, _ := templateRepo.GetByID("tpl-1")
+	nextWeek := time.Date(2024, 1, 13, 9, 0, 0, 0, time.UTC).UnixMilli()
+	if found.NextRunAt == nil || *found.NextRunAt != nextWeek {
+		t.Errorf("Expected next run %d, got %v", nextWeek, found.NextRunAt)
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/kleyson/groceries/backend/internal/auth"
+	"github.com/kleyson/groceries/backend/internal/db"
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+type TombstoneRepository struct {
+	db *db.DB
+}
+
+func NewTombstoneRepository(database *db.DB) *TombstoneRepository {
+	return &TombstoneRepository{db: database}
+}
+
+// GetSinceForUser returns deletions at or after since. Item and list
+// deletions are limited to lists the user can still see, category deletions
+// go to everyone, and deletions recorded for the user alone are always
+// returned.
+func (r *TombstoneRepository) GetSinceForUser(userID string, since int64) ([]models.Tombstone, error) {
+	var tombstones []models.Tombstone
+	err := r.db.Table("tombstones t").
+		Select("t.*").
+		Joins("LEFT JOIN lists l ON l.id = t.list_id").
+		Joins("LEFT JOIN list_members m ON m.list_id = l.id AND m.user_id = ?", userID).
+		Where("t.deleted_at >= ?", since).
+		Where("t.user_id = ? OR (t.user_id IS NULL AND (t.entity_type = ? OR m.id IS NOT NULL))", userID, models.EntityCategory).
+		Order("t.deleted_at ASC").
+		Scan(&tombstones).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tombstones == nil {
+		tombstones = []models.Tombstone{}
+	}
+
+	return tombstones, nil
+}
+
+// recordTombstone stores a deletion marker using the caller's transaction
+func recordTombstone(tx *gorm.DB, entityType, entityID string, listID *string) error {
+	return tx.Create(&models.Tombstone{
+		ID:         auth.GenerateID(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		ListID:     listID,
+		DeletedAt:  auth.GetCurrentTimestamp(),
+	}).Error
+}
+
+// recordUserTombstone stores a deletion marker only userID gets, using the
+// caller's transaction
+func recordUserTombstone(tx *gorm.DB, entityType, entityID string, listID *string, userID string) error {
+	return tx.Create(&models.Tombstone{
+		ID:         auth.GenerateID(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		ListID:     listID,
+		DeletedAt:  auth.GetCurrentTimestamp(),
+		UserID:     &userID,
+	}).Error
+}
+
+// recordMoveTombstones tells the members of fromListID who cannot see
+// toListID that the items moved between them are gone, and forgets earlier
+// such deletions for those who can see them again, using the caller's
+// transaction
+func recordMoveTombstones(tx *gorm.DB, itemIDs []string, fromListID, toListID string) error {
+	targetMembers := tx.Model(&models.ListMember{}).Select("user_id").Where("list_id = ?", toListID)
+	if err := tx.Where("entity_type = ? AND entity_id IN ? AND user_id IN (?)", models.EntityItem, itemIDs, targetMembers).
+		Delete(&models.Tombstone{}).Error; err != nil {
+		return err
+	}
+
+	var userIDs []string
+	if err := tx.Model(&models.ListMember{}).
+		Where("list_id = ? AND user_id NOT IN (?)", fromListID, tx.Model(&models.ListMember{}).Select("user_id").Where("list_id = ?", toListID)).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		for _, itemID := range itemIDs {
+			if err := recordUserTombstone(tx, models.EntityItem, itemID, &fromListID, userID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// clearTombstones forgets earlier deletions of an entity that has been
+// restored, using the caller's transaction. Deletions recorded for a single
+// user stay, since the restore does not give them access back.
+func clearTombstones(tx *gorm.DB, entityType, entityID string) error {
+	return tx.Where("entity_type = ? AND entity_id = ? AND user_id IS NULL", entityType, entityID).Delete(&models.Tombstone{}).Error
+}
+
+// clearUserTombstones forgets the deletions recorded for userID alone of a
+// list and the items now on it, once they can see the list again, using the
+// caller's transaction
+func clearUserTombstones(tx *gorm.DB, listID, userID string) error {
+	items := tx.Model(&models.Item{}).Select("id").Where("list_id = ?", listID)
+	return tx.Where("user_id = ?", userID).
+		Where("(entity_type = ? AND entity_id = ?) OR (entity_type = ? AND entity_id IN (?))", models.EntityList, listID, models.EntityItem, items).
+		Delete(&models.Tombstone{}).Error
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/kleyson/groceries/backend/internal/models"
+)
+
+func TestTombstoneRepository_RecordsDeletes(t *testing.T) {
+	itemRepo, listRepo, catRepo, _, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	repo := NewTombstoneRepository(itemRepo.db)
+
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := itemRepo.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	createTestCategory(t, catRepo, "temp-cat", "Temporary")
+	if err := catRepo.Delete("temp-cat"); err != nil {
+		t.Fatalf("Failed to delete category: %v", err)
+	}
+	if err := listRepo.Delete("list-1"); err != nil {
+		t.Fatalf("Failed to delete list: %v", err)
+	}
+
+	tombstones, err := repo.GetSinceForUser("user-1", 0)
+	if err != nil {
+		t.Fatalf("Failed to get tombstones: %v", err)
+	}
+	if len(tombstones) != 3 {
+		t.Fatalf("Expected 3 tombstones, got %d", len(tombstones))
+	}
+
+	types := map[string]string{}
+	for _, ts := range tombstones {
+		types[ts.EntityType] = ts.EntityID
+	}
+	if types[models.EntityItem] != "item-1" || types[models.EntityCategory] != "temp-cat" || types[models.EntityList] != "list-1" {
+		t.Errorf("Unexpected tombstones: %+v", tombstones)
+	}
+
+	// Nothing after the last deletion
+	tombstones, err = repo.GetSinceForUser("user-1", tombstones[len(tombstones)-1].DeletedAt+1)
+	if err != nil {
+		t.Fatalf("Failed to get tombstones: %v", err)
+	}
+	if len(tombstones) != 0 {
+		t.Errorf("Expected no tombstones, got %d", len(tombstones))
+	}
+}
+
+func TestTombstoneRepository_HidesItemsInPrivateLists(t *testing.T) {
+	itemRepo, listRepo, _, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	repo := NewTombstoneRepository(itemRepo.db)
+	createTestUser(t, userRepo, "user-2", "bob", "Bob")
+
+	owner := "user-2"
+	private := &models.List{ID: "list-2", Name: "Bob's", OwnerID: &owner, CreatedAt: 1000, UpdatedAt: 1000}
+	if err := listRepo.Create(private); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	item := &models.Item{ID: "item-1", ListID: "list-2", Name: "Secret", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if err := itemRepo.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	tombstones, err := repo.GetSinceForUser("user-1", 0)
+	if err != nil {
+		t.Fatalf("Failed to get tombstones: %v", err)
+	}
+	if len(tombstones) != 0 {
+		t.Errorf("Expected no visible tombstones, got %d", len(tombstones))
+	}
+
+	tombstones, err = repo.GetSinceForUser("user-2", 0)
+	if err != nil {
+		t.Fatalf("Failed to get tombstones: %v", err)
+	}
+	if len(tombstones) != 1 {
+		t.Errorf("Expected 1 tombstone for the owner, got %d", len(tombstones))
+	}
+}
+
+func TestTombstoneRepository_PerUserDeletions(t *testing.T) {
+	itemRepo, listRepo, _, userRepo, cleanup := setupItemTestDB(t)
+	defer cleanup()
+
+	repo := NewTombstoneRepository(itemRepo.db)
+	memberRepo := NewListMemberRepository(itemRepo.db)
+	createTestUser(t, userRepo, "user-2", "bob", "Bob")
+	createTestList(t, listRepo, "list-2", "Alice's", "user-1")
+
+	member := &models.ListMember{ID: "member-1", ListID: "list-1", UserID: "user-2", Role: models.ListRoleEditor, CreatedAt: 1000}
+	if err := memberRepo.Create(member); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	// Moving items to a list Bob cannot see takes them away from him only
+	item := &models.Item{ID: "item-1", ListID: "list-1", Name: "Milk", Quantity: 1, CategoryID: "test-cat"}
+	if err := itemRepo.Create(item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if _, err := itemRepo.BulkMove("list-1", models.ItemSelection{ItemIDs: []string{"item-1"}}, "list-2"); err != nil {
+		t.Fatalf("Failed to move item: %v", err)
+	}
+
+	tombstones, _ := repo.GetSinceForUser("user-2", 0)
+	if len(tombstones) != 1 || tombstones[0].EntityType != models.EntityItem || tombstones[0].EntityID != "item-1" {
+		t.Errorf("Expected Bob to lose the moved item, got %+v", tombstones)
+	}
+	tombstones, _ = repo.GetSinceForUser("user-1", 0)
+	if len(tombstones) != 0 {
+		t.Errorf("Expected nothing deleted for Alice, who can see both lists, got %+v", tombstones)
+	}
+
+	// Leaving a list takes it away from the member who left
+	if err := memberRepo.Delete("list-1", "user-2"); err != nil {
+		t.Fatalf("Failed to remove member: %v", err)
+	}
+	tombstones, _ = repo.GetSinceForUser("user-2", 0)
+	lost := map[string]string{}
+	for _, ts := range tombstones {
+		lost[ts.EntityType] = ts.EntityID
+	}
+	if len(tombstones) != 2 || lost[models.EntityList] != "list-1" {
+		t.Errorf("Expected Bob to lose list-1, got %+v", tombstones)
+	}
+	tombstones, _ = repo.GetSinceForUser("user-1", 0)
+	if len(tombstones) != 0 {
+		t.Errorf("Expected Bob's deletions to stay his, got %+v", tombstones)
+	}
+
+	// Trashing a list is only news to its members
+	if err := listRepo.Delete("list-2"); err != nil {
+		t.Fatalf("Failed to delete list: %v", err)
+	}
+	tombstones, _ = repo.GetSinceForUser("user-2", 0)
+	if len(tombstones) != 2 {
+		t.Errorf("Expected no tombstone for a list Bob never saw, got %+v", tombstones)
+	}
+
+	// Rejoining brings the list back
+	member.ID = "member-2"
+	if err := memberRepo.Create(member); err != nil {
+		t.Fatalf("Failed to add member again: %v", err)
+	}
+	tombstones, _ = repo.GetSinceForUser("user-2", 0)
+	if len(tombstones) != 1 || tombstones[0].EntityType != models.EntityItem {
+		t.Errorf("Expected only the moved item to stay deleted for Bob, got %+v", tombstones)
+	}
+}
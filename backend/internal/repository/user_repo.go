@@ -5,6 +5,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/kleyson/groceries/backend/internal/auth"
 	"github.com/kleyson/groceries/backend/internal/db"
 	"github.com/kleyson/groceries/backend/internal/models"
 )
@@ -64,15 +65,34 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
+// Delete removes a user. Each list the user owned passes to its highest
+// ranking remaining member; lists nobody else is a member of go to the
+// trash.
 func (r *UserRepository) Delete(id string) error {
-	result := r.db.Delete(&models.User{}, "id = ?", id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return ErrUserNotFound
-	}
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var owned []models.List
+		if err := tx.Select("id", "deleted_at").Where("owner_id = ?", id).Find(&owned).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.ListMember{}, "user_id = ?", id).Error; err != nil {
+			return err
+		}
+		now := auth.GetCurrentTimestamp()
+		for _, list := range owned {
+			if err := handOverList(tx, list, now); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Delete(&models.User{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
 }
 
 func (r *UserRepository) Count() (int64, error) {
@@ -106,3 +126,32 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// handOverList makes the list's highest ranking member its owner, or trashes
+// the list when it has no members left
+func handOverList(tx *gorm.DB, list models.List, now int64) error {
+	var heir models.ListMember
+	err := tx.Where("list_id = ?", list.ID).
+		Order(db.MemberRankOrder).
+		First(&heir).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if list.DeletedAt != nil {
+			return nil
+		}
+		return NewListRepository(&db.DB{DB: tx}).Delete(list.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.ListMember{}).Where("id = ?", heir.ID).
+		Update("role", models.ListRoleOwner).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.List{}).Where("id = ?", list.ID).
+		Updates(map[string]interface{}{
+			"owner_id":   heir.UserID,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": now,
+		}).Error
+}
@@ -230,6 +230,95 @@ func TestUserRepository_Delete(t *testing.T) {
 	}
 }
 
+func TestUserRepository_DeleteHandsOverLists(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewUserRepository(database)
+	listRepo := NewListRepository(database)
+	memberRepo := NewListMemberRepository(database)
+	createTestUser(t, repo, "user-1", "alice", "Alice")
+	createTestUser(t, repo, "user-2", "bob", "Bob")
+	createTestUser(t, repo, "user-3", "carol", "Carol")
+	createTestList(t, listRepo, "shared", "Shared", "user-1")
+	createTestList(t, listRepo, "private", "Private", "user-1")
+
+	for _, member := range []*models.ListMember{
+		{ID: "member-1", ListID: "shared", UserID: "user-2", Role: models.ListRoleViewer, CreatedAt: 1000},
+		{ID: "member-2", ListID: "shared", UserID: "user-3", Role: models.ListRoleEditor, CreatedAt: 2000},
+	} {
+		if err := memberRepo.Create(member); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+
+	if err := repo.Delete("user-1"); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	// The editor outranks the earlier viewer
+	shared, err := listRepo.GetByID("shared")
+	if err != nil || shared.OwnerID == nil || *shared.OwnerID != "user-3" {
+		t.Fatalf("Expected carol to own the shared list, got %+v (%v)", shared, err)
+	}
+	if role, _ := memberRepo.GetRole("shared", "user-3"); role != models.ListRoleOwner {
+		t.Errorf("Expected carol's role raised to owner, got %s", role)
+	}
+	if role, _ := memberRepo.GetRole("shared", "user-2"); role != models.ListRoleViewer {
+		t.Errorf("Expected bob to stay a viewer, got %s", role)
+	}
+
+	// Nobody else could see the private list, so it goes to the trash
+	if _, err := listRepo.GetByID("private"); err != ErrListNotFound {
+		t.Errorf("Expected the private list trashed, got %v", err)
+	}
+	if _, err := memberRepo.GetRoleIncludingTrashed("private", "user-2"); err != ErrMemberNotFound {
+		t.Errorf("Expected the private list to stay private, got %v", err)
+	}
+}
+
+func TestMigrateAssignsListOwners(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewUserRepository(database)
+	listRepo := NewListRepository(database)
+	memberRepo := NewListMemberRepository(database)
+	createTestUser(t, repo, "user-1", "alice", "Alice")
+	admin := &models.User{ID: "user-2", Username: "bob", Name: "Bob", PasswordHash: "hash", IsAdmin: true, CreatedAt: 2000}
+	if err := repo.Create(admin); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// A list from before sharing, and one left by a deleted owner
+	createTestList(t, listRepo, "legacy", "Household", "")
+	createTestList(t, listRepo, "orphan", "Orphan", "")
+	member := &models.ListMember{ID: "member-1", ListID: "orphan", UserID: "user-1", Role: models.ListRoleViewer, CreatedAt: 1000}
+	if err := memberRepo.Create(member); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	legacy, _ := listRepo.GetByID("legacy")
+	if legacy.OwnerID == nil || *legacy.OwnerID != "user-2" {
+		t.Errorf("Expected the admin to own the legacy list, got %v", legacy.OwnerID)
+	}
+	if role, _ := memberRepo.GetRole("legacy", "user-1"); role != models.ListRoleEditor {
+		t.Errorf("Expected alice to keep editing the legacy list, got %s", role)
+	}
+
+	orphan, _ := listRepo.GetByID("orphan")
+	if orphan.OwnerID == nil || *orphan.OwnerID != "user-1" {
+		t.Errorf("Expected the remaining member to own the orphan, got %v", orphan.OwnerID)
+	}
+	if role, _ := memberRepo.GetRole("orphan", "user-2"); role != "" {
+		t.Errorf("Expected the orphan to stay private, got %s", role)
+	}
+}
+
 func TestUserRepository_Count(t *testing.T) {
 	database, cleanup := setupTestDB(t)
 	defer cleanup()
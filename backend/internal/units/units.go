@@ -0,0 +1,165 @@
+// Package units converts between the units stored on items. Units are the
+// normalized spellings produced by quickadd.NormalizeUnit; anything else is
+// only compatible with itself.
+package units
+
+import (
+	"math"
+	"strings"
+)
+
+// Dimension is what a unit measures
+type Dimension int
+
+const (
+	// Other covers units such as "can" or "bag" that convert to nothing else
+	Other Dimension = iota
+	Mass
+	Volume
+	Count
+)
+
+type unit struct {
+	dimension Dimension
+	// factor converts to the dimension's base unit: grams, millilitres or pieces
+	factor float64
+}
+
+var known = map[string]unit{
+	"mg": {Mass, 0.001},
+	"g":  {Mass, 1},
+	"kg": {Mass, 1000},
+	"oz": {Mass, 28.349523125},
+	"lb": {Mass, 453.59237},
+
+	"ml":    {Volume, 1},
+	"cl":    {Volume, 10},
+	"l":     {Volume, 1000},
+	"tsp":   {Volume, 4.92892159375},
+	"tbsp":  {Volume, 14.78676478125},
+	"fl oz": {Volume, 29.5735295625},
+	"cup":   {Volume, 236.5882365},
+	"pt":    {Volume, 473.176473},
+	"qt":    {Volume, 946.352946},
+	"gal":   {Volume, 3785.411784},
+
+	"":      {Count, 1},
+	"pc":    {Count, 1},
+	"dozen": {Count, 12},
+}
+
+// smaller is the unit a fractional amount is expressed in to make it whole
+var smaller = map[string]string{
+	"kg":    "g",
+	"l":     "ml",
+	"cl":    "ml",
+	"lb":    "oz",
+	"gal":   "qt",
+	"dozen": "pc",
+}
+
+func lookup(name string) (unit, bool) {
+	u, ok := known[strings.ToLower(strings.TrimSpace(name))]
+	return u, ok
+}
+
+// DimensionOf returns what a unit measures, Other when it is not known
+func DimensionOf(name string) Dimension {
+	if u, ok := lookup(name); ok {
+		return u.dimension
+	}
+	return Other
+}
+
+// Compatible reports whether amounts in a and b can be added together
+func Compatible(a, b string) bool {
+	ua, okA := lookup(a)
+	ub, okB := lookup(b)
+	if !okA || !okB {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return ua.dimension == ub.dimension
+}
+
+// Convert expresses amount of from in to. It reports false when the units
+// are not compatible.
+func Convert(amount float64, from, to string) (float64, bool) {
+	if !Compatible(from, to) {
+		return 0, false
+	}
+	uf, okF := lookup(from)
+	ut, okT := lookup(to)
+	if !okF || !okT {
+		return amount, true
+	}
+	return amount * uf.factor / ut.factor, true
+}
+
+// Whole turns an amount into a whole quantity, moving to a smaller unit for
+// fractions when there is one (1.5 kg becomes 1500 g) and rounding up
+// otherwise
+func Whole(amount float64, unit string) (int, string) {
+	if isWhole(amount) {
+		return int(math.Round(amount)), unit
+	}
+	if to, ok := smaller[unit]; ok {
+		converted, _ := Convert(amount, unit, to)
+		if isWhole(converted) {
+			return int(math.Round(converted)), to
+		}
+		return int(math.Ceil(converted)), to
+	}
+	return int(math.Ceil(amount)), unit
+}
+
+// Add sums two compatible amounts. The result stays in aUnit when it comes
+// out whole there and uses the smaller of the two units otherwise. It
+// reports false when the units are not compatible.
+func Add(a float64, aUnit string, b float64, bUnit string) (int, string, bool) {
+	inA, ok := Convert(b, bUnit, aUnit)
+	if !ok {
+		return 0, "", false
+	}
+	if sum := a + inA; isWhole(sum) {
+		return int(math.Round(sum)), aUnit, true
+	}
+
+	target := aUnit
+	if ua, okA := lookup(aUnit); okA {
+		if ub, okB := lookup(bUnit); okB && ub.factor < ua.factor {
+			target = bUnit
+		}
+	}
+	inTarget, _ := Convert(a, aUnit, target)
+	other, _ := Convert(b, bUnit, target)
+	quantity, unit := Whole(inTarget+other, target)
+	return quantity, unit, true
+}
+
+// isWhole allows for the rounding error of converting through the base unit
+func isWhole(amount float64) bool {
+	return math.Abs(amount-math.Round(amount)) < 1e-6
+}
+
+// reference is the unit prices are compared in for each dimension
+var reference = map[Dimension]string{
+	Mass:   "kg",
+	Volume: "l",
+	Count:  "pc",
+}
+
+// UnitPrice is what one kg, l or piece costs when price buys amount of unit,
+// so that packages of different sizes can be compared. It reports false for
+// units such as "can" that measure nothing comparable and for amounts that
+// are not positive.
+func UnitPrice(price, amount float64, unit string) (float64, string, bool) {
+	if amount <= 0 {
+		return 0, "", false
+	}
+	per, ok := reference[DimensionOf(unit)]
+	if !ok {
+		return 0, "", false
+	}
+	inReference, _ := Convert(amount, unit, per)
+	return price / inReference, per, true
+}
@@ -0,0 +1,136 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompatible(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"g", "kg", true},
+		{"oz", "lb", true},
+		{"cup", "ml", true},
+		{"fl oz", "l", true},
+		{"", "dozen", true},
+		{"pc", "", true},
+		{"g", "ml", false},
+		{"kg", "", false},
+		{"can", "can", true},
+		{"Can", "can", true},
+		{"can", "jar", false},
+		{"can", "", false},
+	}
+	for _, tt := range tests {
+		if got := Compatible(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compatible(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		from, to string
+		want     float64
+	}{
+		{1.5, "kg", "g", 1500},
+		{16, "oz", "lb", 1},
+		{1, "l", "ml", 1000},
+		{2, "dozen", "", 24},
+		{1, "cup", "tbsp", 16},
+		{3, "can", "can", 3},
+	}
+	for _, tt := range tests {
+		got, ok := Convert(tt.amount, tt.from, tt.to)
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %q, %q) = %v, %v, want %v", tt.amount, tt.from, tt.to, got, ok, tt.want)
+		}
+	}
+	if _, ok := Convert(1, "g", "ml"); ok {
+		t.Error("Expected grams and millilitres not to convert")
+	}
+}
+
+func TestWhole(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		unit     string
+		quantity int
+		wantUnit string
+	}{
+		{2, "kg", 2, "kg"},
+		{1.5, "kg", 1500, "g"},
+		{0.5, "lb", 8, "oz"},
+		{1.5, "dozen", 18, "pc"},
+		{1.5, "cup", 2, "cup"},
+		{2.2, "", 3, ""},
+	}
+	for _, tt := range tests {
+		quantity, unit := Whole(tt.amount, tt.unit)
+		if quantity != tt.quantity || unit != tt.wantUnit {
+			t.Errorf("Whole(%v, %q) = %d %q, want %d %q", tt.amount, tt.unit, quantity, unit, tt.quantity, tt.wantUnit)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		a        float64
+		aUnit    string
+		b        float64
+		bUnit    string
+		quantity int
+		unit     string
+	}{
+		{1, "kg", 1000, "g", 2, "kg"},
+		{1, "kg", 500, "g", 1500, "g"},
+		{500, "g", 1, "kg", 1500, "g"},
+		{2, "", 1, "dozen", 14, ""},
+		{1, "l", 250, "ml", 1250, "ml"},
+		{2, "can", 1, "can", 3, "can"},
+	}
+	for _, tt := range tests {
+		quantity, unit, ok := Add(tt.a, tt.aUnit, tt.b, tt.bUnit)
+		if !ok || quantity != tt.quantity || unit != tt.unit {
+			t.Errorf("Add(%v %q, %v %q) = %d %q %v, want %d %q", tt.a, tt.aUnit, tt.b, tt.bUnit, quantity, unit, ok, tt.quantity, tt.unit)
+		}
+	}
+	if _, _, ok := Add(1, "kg", 1, "l"); ok {
+		t.Error("Expected kilograms and litres not to add")
+	}
+}
+
+func TestUnitPrice(t *testing.T) {
+	tests := []struct {
+		price  float64
+		amount float64
+		unit   string
+		want   float64
+		per    string
+	}{
+		{3.99, 500, "g", 7.98, "kg"},
+		{6.49, 1, "kg", 6.49, "kg"},
+		{4.54, 1, "lb", 10.009, "kg"},
+		{2.50, 16, "oz", 5.512, "kg"},
+		{1.80, 750, "ml", 2.4, "l"},
+		{3.00, 64, "fl oz", 1.585, "l"},
+		{4.20, 1, "dozen", 0.35, "pc"},
+		{5.00, 4, "", 1.25, "pc"},
+	}
+	for _, tt := range tests {
+		got, per, ok := UnitPrice(tt.price, tt.amount, tt.unit)
+		if !ok || per != tt.per || math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("UnitPrice(%v, %v %q) = %v per %q %v, want %v per %q", tt.price, tt.amount, tt.unit, got, per, ok, tt.want, tt.per)
+		}
+	}
+
+	if _, _, ok := UnitPrice(2, 1, "can"); ok {
+		t.Error("Expected no unit price for cans")
+	}
+	if _, _, ok := UnitPrice(2, 0, "g"); ok {
+		t.Error("Expected no unit price for an empty package")
+	}
+}
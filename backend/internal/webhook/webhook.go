@@ -0,0 +1,56 @@
+// Package webhook posts JSON notifications to a URL configured by the
+// operator.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Payload is the body of every webhook request
+type Payload struct {
+	Event  string      `json:"event"`
+	SentAt int64       `json:"sentAt"`
+	Data   interface{} `json:"data"`
+}
+
+// Webhook sends events to one URL. The zero Client uses a 10 second timeout.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// New returns a webhook for url, or nil when url is empty so callers can
+// treat an unconfigured webhook as switched off
+func New(url string) *Webhook {
+	if url == "" {
+		return nil
+	}
+	return &Webhook{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send posts event with data and fails on any non-2xx response
+func (w *Webhook) Send(event string, data interface{}) error {
+	body, err := json.Marshal(Payload{Event: event, SentAt: time.Now().UnixMilli(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", event, resp.Status)
+	}
+	return nil
+}
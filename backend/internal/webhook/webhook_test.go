@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	var got Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON, got %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := New(server.URL).Send("test.event", map[string]int{"count": 2}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if got.Event != "test.event" || got.SentAt == 0 {
+		t.Errorf("Unexpected payload: %+v", got)
+	}
+	if data, ok := got.Data.(map[string]interface{}); !ok || data["count"] != float64(2) {
+		t.Errorf("Expected data to round-trip, got %#v", got.Data)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := New(server.URL).Send("test.event", nil); err == nil {
+		t.Error("Expected an error for a 500 response")
+	}
+}
+
+func TestNewWithoutURL(t *testing.T) {
+	if New("") != nil {
+		t.Error("Expected no webhook without a URL")
+	}
+}